@@ -0,0 +1,89 @@
+﻿using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using Microsoft.EntityFrameworkCore;
+using static Auth.Application.AppUsers.AddPasskey;
+
+namespace Auth.Application.Tests.UnitTests
+{
+    public class AddPasskeyTests
+    {
+        private readonly DataContext _context;
+
+        public AddPasskeyTests()
+        {
+            var options = new DbContextOptionsBuilder<DataContext>()
+            .UseInMemoryDatabase(databaseName: Guid.NewGuid().ToString())
+            .Options;
+
+            _context = new DataContext(options);
+        }
+
+        private static AddPasskeyCommand NewCommand(string email, byte[] credentialId) =>
+            new(email, credentialId, [1, 2, 3], "none", ["internal", "hybrid"], new byte[16], 0, true, false);
+
+        [Fact]
+        public async Task Should_Store_Passkey_For_User()
+        {
+            // Arrange
+            var user = new AppUser { Email = "test@example.com", Password = "hash" };
+            _context.AppUsers.Add(user);
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = NewCommand("test@example.com", [42]) };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            Assert.True(result.IsSuccess);
+
+            var passkey = await _context.AppUserPasskeys.SingleAsync();
+            Assert.Equal(user.Id, passkey.AppUserId);
+            Assert.Equal(new byte[] { 42 }, passkey.CredentialId);
+            Assert.Equal("internal,hybrid", passkey.Transports);
+            Assert.True(passkey.BackupEligible);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_Credential_Is_Already_Registered()
+        {
+            // Arrange
+            var user = new AppUser { Email = "test@example.com", Password = "hash" };
+            _context.AppUserPasskeys.Add(new AppUserPasskey
+            {
+                CredentialId = [42],
+                PublicKey = [1, 2, 3],
+                AttestationType = "none",
+                Transports = "",
+                Aaguid = new byte[16],
+                AppUser = user,
+            });
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = NewCommand("test@example.com", [42]) };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.AlreadyExists, exception.StatusCode);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_User_Not_Found()
+        {
+            // Arrange
+            var command = new Command { Request = NewCommand("nonexistent@example.com", [42]) };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.Unauthenticated, exception.StatusCode);
+            Assert.Empty(_context.AppUserPasskeys);
+        }
+    }
+}
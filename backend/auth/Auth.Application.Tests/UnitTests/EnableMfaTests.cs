@@ -0,0 +1,79 @@
+﻿using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using Microsoft.EntityFrameworkCore;
+using static Auth.Application.AppUsers.EnableMfa;
+
+namespace Auth.Application.Tests.UnitTests
+{
+    public class EnableMfaTests
+    {
+        private readonly DataContext _context;
+
+        public EnableMfaTests()
+        {
+            var options = new DbContextOptionsBuilder<DataContext>()
+            .UseInMemoryDatabase(databaseName: Guid.NewGuid().ToString())
+            .Options;
+
+            _context = new DataContext(options);
+        }
+
+        [Fact]
+        public async Task Should_Store_Secret_And_Recovery_Codes_For_User()
+        {
+            // Arrange
+            var user = new AppUser { Email = "test@example.com", Password = "hash" };
+            _context.AppUsers.Add(user);
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = new EnableMfaCommand("test@example.com", "SECRET", ["hash1", "hash2"]) };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            Assert.True(result.IsSuccess);
+
+            var mfa = await _context.AppUserMfas.SingleAsync();
+            Assert.Equal(user.Id, mfa.AppUserId);
+            Assert.Equal("SECRET", mfa.Secret);
+            Assert.Equal(new[] { "hash1", "hash2" }, _context.AppUserRecoveryCodes.Select(code => code.CodeHash).OrderBy(hash => hash));
+        }
+
+        [Fact]
+        public async Task Should_Replace_Previous_Secret_And_Recovery_Codes()
+        {
+            // Arrange
+            var user = new AppUser { Email = "test@example.com", Password = "hash" };
+            _context.AppUserMfas.Add(new AppUserMfa { Secret = "OLD", AppUser = user });
+            _context.AppUserRecoveryCodes.Add(new AppUserRecoveryCode { CodeHash = "old", AppUser = user });
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = new EnableMfaCommand("test@example.com", "NEW", ["new"]) };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            Assert.True(result.IsSuccess);
+            Assert.Equal("NEW", (await _context.AppUserMfas.SingleAsync()).Secret);
+            Assert.Equal("new", (await _context.AppUserRecoveryCodes.SingleAsync()).CodeHash);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_User_Not_Found()
+        {
+            // Arrange
+            var command = new Command { Request = new EnableMfaCommand("nonexistent@example.com", "SECRET", ["hash1"]) };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.Unauthenticated, exception.StatusCode);
+            Assert.Empty(_context.AppUserMfas);
+        }
+    }
+}
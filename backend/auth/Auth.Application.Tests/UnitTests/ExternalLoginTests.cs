@@ -0,0 +1,109 @@
+﻿using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Auth.Infrastructure.Redis;
+using Auth.Infrastructure.Services;
+using Grpc.Core;
+using Microsoft.EntityFrameworkCore;
+using Moq;
+using static Auth.Application.AppUsers.ExternalLogin;
+
+namespace Auth.Application.Tests.UnitTests
+{
+    public class ExternalLoginTests
+    {
+        private readonly DataContext _context;
+
+        private readonly Mock<ITokenService> _tokenServiceMock;
+        private readonly Mock<ITokenRepository> _tokenCashRepositoryMock;
+        public ExternalLoginTests()
+        {
+            var options = new DbContextOptionsBuilder<DataContext>()
+            .UseInMemoryDatabase(databaseName: Guid.NewGuid().ToString())
+            .Options;
+
+            _context = new DataContext(options);
+
+            _tokenServiceMock = new Mock<ITokenService>();
+            _tokenServiceMock.Setup(ts => ts.CreateAccessToken(It.IsAny<AppUser>())).ReturnsAsync("accessToken");
+            _tokenServiceMock.Setup(ts => ts.CreateRefreshToken(It.IsAny<AppUser>())).ReturnsAsync("refreshToken");
+
+            _tokenCashRepositoryMock = new Mock<ITokenRepository>();
+            _tokenCashRepositoryMock.Setup(tr => tr.StoreToken(It.IsAny<string>(), It.IsAny<string>())).ReturnsAsync(true);
+        }
+
+        [Fact]
+        public async Task Should_Create_User_And_Link_Login_When_Email_Is_New()
+        {
+            // Arrange
+            var command = new Command { Request = new ExternalLoginCommand("google", "google-user-1", "new@example.com", true) };
+
+            // Act
+            var result = await new Handler(_context, _tokenServiceMock.Object, _tokenCashRepositoryMock.Object).Handle(command);
+
+            // Assert
+            Assert.True(result.IsSuccess);
+            Assert.Equal("accessToken", result.Value.AccessToken);
+            Assert.Equal("refreshToken", result.Value.RefreshToken);
+
+            var login = await _context.AppUserLogins.Include(l => l.AppUser).SingleAsync();
+            Assert.Equal("google", login.Provider);
+            Assert.Equal("google-user-1", login.ProviderKey);
+            Assert.Equal("new@example.com", login.AppUser.Email);
+        }
+
+        [Fact]
+        public async Task Should_Link_Existing_User_With_Same_Verified_Email()
+        {
+            // Arrange
+            var user = new AppUser { Email = "existing@example.com", Password = BCrypt.Net.BCrypt.HashPassword("TestPassw0rd") };
+            _context.AppUsers.Add(user);
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = new ExternalLoginCommand("github", "42", "existing@example.com", true) };
+
+            // Act
+            var result = await new Handler(_context, _tokenServiceMock.Object, _tokenCashRepositoryMock.Object).Handle(command);
+
+            // Assert
+            Assert.True(result.IsSuccess);
+            Assert.Equal(1, await _context.AppUsers.CountAsync());
+            Assert.Equal(user.Id, (await _context.AppUserLogins.SingleAsync()).AppUserId);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_Email_Is_Not_Verified()
+        {
+            // Arrange
+            _context.AppUsers.Add(new AppUser { Email = "victim@example.com", Password = BCrypt.Net.BCrypt.HashPassword("TestPassw0rd") });
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = new ExternalLoginCommand("github", "42", "victim@example.com", false) };
+
+            // Act
+            var result = await new Handler(_context, _tokenServiceMock.Object, _tokenCashRepositoryMock.Object).Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.PermissionDenied, exception.StatusCode);
+            Assert.Empty(_context.AppUserLogins);
+        }
+
+        [Fact]
+        public async Task Should_Login_Linked_User_Without_Verified_Email()
+        {
+            // Arrange
+            var user = new AppUser { Email = "linked@example.com", Password = "hash" };
+            _context.AppUserLogins.Add(new AppUserLogin { Provider = "github", ProviderKey = "42", AppUser = user });
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = new ExternalLoginCommand("github", "42", "", false) };
+
+            // Act
+            var result = await new Handler(_context, _tokenServiceMock.Object, _tokenCashRepositoryMock.Object).Handle(command);
+
+            // Assert
+            Assert.True(result.IsSuccess);
+            _tokenCashRepositoryMock.Verify(tr => tr.StoreToken(user.Id.ToString(), "refreshToken"), Times.Once);
+        }
+    }
+}
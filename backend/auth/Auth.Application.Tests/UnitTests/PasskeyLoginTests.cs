@@ -0,0 +1,98 @@
+﻿using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Auth.Infrastructure.Redis;
+using Auth.Infrastructure.Services;
+using Grpc.Core;
+using Microsoft.EntityFrameworkCore;
+using Moq;
+using static Auth.Application.AppUsers.PasskeyLogin;
+
+namespace Auth.Application.Tests.UnitTests
+{
+    public class PasskeyLoginTests
+    {
+        private readonly DataContext _context;
+
+        private readonly Mock<ITokenService> _tokenServiceMock;
+        private readonly Mock<ITokenRepository> _tokenCashRepositoryMock;
+        private readonly AppUser _user;
+        public PasskeyLoginTests()
+        {
+            var options = new DbContextOptionsBuilder<DataContext>()
+            .UseInMemoryDatabase(databaseName: Guid.NewGuid().ToString())
+            .Options;
+
+            _context = new DataContext(options);
+
+            _tokenServiceMock = new Mock<ITokenService>();
+            _tokenServiceMock.Setup(ts => ts.CreateAccessToken(It.IsAny<AppUser>())).ReturnsAsync("accessToken");
+            _tokenServiceMock.Setup(ts => ts.CreateRefreshToken(It.IsAny<AppUser>())).ReturnsAsync("refreshToken");
+
+            _tokenCashRepositoryMock = new Mock<ITokenRepository>();
+            _tokenCashRepositoryMock.Setup(tr => tr.StoreToken(It.IsAny<string>(), It.IsAny<string>())).ReturnsAsync(true);
+
+            _user = new AppUser { Email = "test@example.com", Password = "hash" };
+            _context.AppUserPasskeys.Add(new AppUserPasskey
+            {
+                CredentialId = [42],
+                PublicKey = [1, 2, 3],
+                AttestationType = "none",
+                Transports = "",
+                Aaguid = new byte[16],
+                SignCount = 1,
+                AppUser = _user,
+            });
+            _context.SaveChanges();
+        }
+
+        [Fact]
+        public async Task Should_Return_Tokens_And_Update_Sign_Count()
+        {
+            // Arrange
+            var command = new Command { Request = new PasskeyLoginCommand("test@example.com", [42], 2, true) };
+
+            // Act
+            var result = await new Handler(_context, _tokenServiceMock.Object, _tokenCashRepositoryMock.Object).Handle(command);
+
+            // Assert
+            Assert.True(result.IsSuccess);
+            Assert.Equal("accessToken", result.Value.AccessToken);
+            Assert.Equal("refreshToken", result.Value.RefreshToken);
+
+            var passkey = await _context.AppUserPasskeys.SingleAsync();
+            Assert.Equal(2, passkey.SignCount);
+            Assert.True(passkey.BackupState);
+            _tokenCashRepositoryMock.Verify(tr => tr.StoreToken(_user.Id.ToString(), "refreshToken"), Times.Once);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_Passkey_Belongs_To_Another_User()
+        {
+            // Arrange
+            var command = new Command { Request = new PasskeyLoginCommand("other@example.com", [42], 2, false) };
+
+            // Act
+            var result = await new Handler(_context, _tokenServiceMock.Object, _tokenCashRepositoryMock.Object).Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.Unauthenticated, exception.StatusCode);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_User_Already_Logged_In()
+        {
+            // Arrange
+            _tokenCashRepositoryMock.Setup(tr => tr.IsExist(_user.Id.ToString())).ReturnsAsync(true);
+
+            var command = new Command { Request = new PasskeyLoginCommand("test@example.com", [42], 2, false) };
+
+            // Act
+            var result = await new Handler(_context, _tokenServiceMock.Object, _tokenCashRepositoryMock.Object).Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.AlreadyExists, exception.StatusCode);
+        }
+    }
+}
@@ -0,0 +1,83 @@
+﻿using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Auth.Infrastructure.Redis;
+using Grpc.Core;
+using Microsoft.EntityFrameworkCore;
+using Moq;
+using System.Security.Cryptography;
+using System.Text;
+using static Auth.Application.AppUsers.RevokeRefreshToken;
+
+namespace Auth.Application.Tests.UnitTests
+{
+    public class RevokeRefreshTokenTests
+    {
+        private readonly DataContext _context;
+        private readonly Mock<ITokenRepository> _tokenRepositoryMock;
+        private readonly Handler _handler;
+
+        private readonly AppUser _user = new AppUser { Id = Guid.NewGuid(), Email = "test@example.com", Password = "hash" };
+
+        public RevokeRefreshTokenTests()
+        {
+            var options = new DbContextOptionsBuilder<DataContext>()
+            .UseInMemoryDatabase(databaseName: Guid.NewGuid().ToString())
+            .Options;
+
+            _context = new DataContext(options);
+            _context.AppUsers.Add(_user);
+            _context.SaveChanges();
+
+            _tokenRepositoryMock = new Mock<ITokenRepository>();
+            _tokenRepositoryMock.Setup(r => r.GetToken(_user.Id.ToString())).ReturnsAsync("refreshToken");
+            _tokenRepositoryMock.Setup(r => r.RemoveToken(_user.Id.ToString())).ReturnsAsync(true);
+
+            _handler = new Handler(_context, _tokenRepositoryMock.Object);
+        }
+
+        private static string Hash(string token) =>
+            Convert.ToHexString(SHA256.HashData(Encoding.UTF8.GetBytes(token))).ToLowerInvariant();
+
+        [Fact]
+        public async Task Should_Remove_Matching_Refresh_Token()
+        {
+            // Arrange
+            var command = new Command { Request = new RevokeRefreshTokenCommand(_user.Email, Hash("refreshToken")) };
+
+            // Act
+            var result = await _handler.Handle(command);
+
+            // Assert
+            Assert.True(result.Value.IsRevoked);
+            _tokenRepositoryMock.Verify(r => r.RemoveToken(_user.Id.ToString()), Times.Once);
+        }
+
+        [Fact]
+        public async Task Should_Keep_Refresh_Token_Of_Another_Login()
+        {
+            // Arrange
+            var command = new Command { Request = new RevokeRefreshTokenCommand(_user.Email, Hash("previousRefreshToken")) };
+
+            // Act
+            var result = await _handler.Handle(command);
+
+            // Assert
+            Assert.False(result.Value.IsRevoked);
+            _tokenRepositoryMock.Verify(r => r.RemoveToken(It.IsAny<string>()), Times.Never);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_User_Not_Found()
+        {
+            // Arrange
+            var command = new Command { Request = new RevokeRefreshTokenCommand("unknown@example.com", Hash("refreshToken")) };
+
+            // Act
+            var result = await _handler.Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.NotFound, exception.StatusCode);
+        }
+    }
+}
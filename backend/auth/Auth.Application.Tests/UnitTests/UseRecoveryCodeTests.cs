@@ -0,0 +1,75 @@
+﻿using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using Microsoft.EntityFrameworkCore;
+using static Auth.Application.AppUsers.UseRecoveryCode;
+
+namespace Auth.Application.Tests.UnitTests
+{
+    public class UseRecoveryCodeTests
+    {
+        private readonly DataContext _context;
+
+        public UseRecoveryCodeTests()
+        {
+            var options = new DbContextOptionsBuilder<DataContext>()
+            .UseInMemoryDatabase(databaseName: Guid.NewGuid().ToString())
+            .Options;
+
+            _context = new DataContext(options);
+        }
+
+        [Fact]
+        public async Task Should_Consume_Recovery_Code_Once()
+        {
+            // Arrange
+            var user = new AppUser { Email = "test@example.com", Password = "hash" };
+            _context.AppUserRecoveryCodes.Add(new AppUserRecoveryCode { CodeHash = "hash1", AppUser = user });
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = new UseRecoveryCodeCommand("test@example.com", "hash1") };
+
+            // Act
+            var first = await new Handler(_context).Handle(command);
+            var second = await new Handler(_context).Handle(command);
+
+            // Assert
+            Assert.True(first.Value.IsValid);
+            Assert.False(second.Value.IsValid);
+            Assert.Empty(_context.AppUserRecoveryCodes);
+        }
+
+        [Fact]
+        public async Task Should_Not_Accept_Another_Users_Recovery_Code()
+        {
+            // Arrange
+            var owner = new AppUser { Email = "owner@example.com", Password = "hash" };
+            _context.AppUsers.Add(new AppUser { Email = "test@example.com", Password = "hash" });
+            _context.AppUserRecoveryCodes.Add(new AppUserRecoveryCode { CodeHash = "hash1", AppUser = owner });
+            await _context.SaveChangesAsync();
+
+            var command = new Command { Request = new UseRecoveryCodeCommand("test@example.com", "hash1") };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            Assert.False(result.Value.IsValid);
+            Assert.Single(_context.AppUserRecoveryCodes);
+        }
+
+        [Fact]
+        public async Task Should_Return_Failure_When_User_Not_Found()
+        {
+            // Arrange
+            var command = new Command { Request = new UseRecoveryCodeCommand("nonexistent@example.com", "hash1") };
+
+            // Act
+            var result = await new Handler(_context).Handle(command);
+
+            // Assert
+            var exception = Assert.IsType<RpcException>(result.Error);
+            Assert.Equal(StatusCode.Unauthenticated, exception.StatusCode);
+        }
+    }
+}
@@ -0,0 +1,71 @@
+﻿using Auth.Application.Core;
+using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+
+namespace Auth.Application.AppUsers
+{
+    public class AddPasskey
+    {
+        public record AddPasskeyCommand(
+            string Email,
+            byte[] CredentialId,
+            byte[] PublicKey,
+            string AttestationType,
+            IEnumerable<string> Transports,
+            byte[] Aaguid,
+            uint SignCount,
+            bool BackupEligible,
+            bool BackupState);
+        public record AddPasskeyResult(bool IsSuccess);
+        public class Command : IRequest<Result<AddPasskeyResult>>
+        {
+            public AddPasskeyCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<AddPasskeyResult>>
+        {
+            private readonly DataContext _context;
+
+            public Handler(DataContext context)
+            {
+                _context = context;
+            }
+            public async Task<Result<AddPasskeyResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var request = command.Request;
+
+                if (request.CredentialId == null || request.CredentialId.Length == 0 || request.PublicKey == null || request.PublicKey.Length == 0)
+                    return Result<AddPasskeyResult>.Failure(new RpcException(new Status(StatusCode.InvalidArgument, "Credential id and public key are required")));
+
+                var user = await _context.AppUsers.FirstOrDefaultAsync(user => user.Email == request.Email);
+
+                if (user == null)
+                    return Result<AddPasskeyResult>.Failure(new RpcException(new Status(StatusCode.Unauthenticated, "User not found")));
+
+                bool isRegistered = await _context.AppUserPasskeys.AnyAsync(passkey => passkey.CredentialId.SequenceEqual(request.CredentialId));
+
+                if (isRegistered)
+                    return Result<AddPasskeyResult>.Failure(new RpcException(new Status(StatusCode.AlreadyExists, "Passkey already registered")));
+
+                _context.AppUserPasskeys.Add(new AppUserPasskey
+                {
+                    CredentialId = request.CredentialId,
+                    PublicKey = request.PublicKey,
+                    AttestationType = request.AttestationType ?? "",
+                    Transports = string.Join(",", request.Transports ?? []),
+                    Aaguid = request.Aaguid ?? [],
+                    SignCount = request.SignCount,
+                    BackupEligible = request.BackupEligible,
+                    BackupState = request.BackupState,
+                    AppUser = user,
+                });
+                await _context.SaveChangesAsync();
+
+                return Result<AddPasskeyResult>.Success(new AddPasskeyResult(true));
+            }
+        }
+    }
+}
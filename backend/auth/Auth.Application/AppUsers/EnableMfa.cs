@@ -0,0 +1,62 @@
+﻿using Auth.Application.Core;
+using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+
+namespace Auth.Application.AppUsers
+{
+    public class EnableMfa
+    {
+        public record EnableMfaCommand(string Email, string Secret, IEnumerable<string> RecoveryCodeHashes);
+        public record EnableMfaResult(bool IsSuccess);
+        public class Command : IRequest<Result<EnableMfaResult>>
+        {
+            public EnableMfaCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<EnableMfaResult>>
+        {
+            private readonly DataContext _context;
+
+            public Handler(DataContext context)
+            {
+                _context = context;
+            }
+            public async Task<Result<EnableMfaResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var request = command.Request;
+
+                if (string.IsNullOrEmpty(request.Secret))
+                    return Result<EnableMfaResult>.Failure(new RpcException(new Status(StatusCode.InvalidArgument, "Secret is required")));
+
+                var user = await _context.AppUsers.FirstOrDefaultAsync(user => user.Email == request.Email);
+
+                if (user == null)
+                    return Result<EnableMfaResult>.Failure(new RpcException(new Status(StatusCode.Unauthenticated, "User not found")));
+
+                // Enabling again replaces the secret and every previous recovery code.
+                _context.AppUserMfas.RemoveRange(_context.AppUserMfas.Where(mfa => mfa.AppUserId == user.Id));
+                _context.AppUserRecoveryCodes.RemoveRange(_context.AppUserRecoveryCodes.Where(code => code.AppUserId == user.Id));
+
+                _context.AppUserMfas.Add(new AppUserMfa
+                {
+                    Secret = request.Secret,
+                    AppUser = user,
+                });
+                foreach (var codeHash in (request.RecoveryCodeHashes ?? []).Distinct())
+                {
+                    _context.AppUserRecoveryCodes.Add(new AppUserRecoveryCode
+                    {
+                        CodeHash = codeHash,
+                        AppUser = user,
+                    });
+                }
+                await _context.SaveChangesAsync();
+
+                return Result<EnableMfaResult>.Success(new EnableMfaResult(true));
+            }
+        }
+    }
+}
@@ -0,0 +1,90 @@
+﻿using Auth.Application.Core;
+using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Auth.Infrastructure.Redis;
+using Auth.Infrastructure.Services;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+
+namespace Auth.Application.AppUsers
+{
+    public class ExternalLogin
+    {
+        public record ExternalLoginCommand(string Provider, string Subject, string Email, bool EmailVerified);
+        public record ExternalLoginResult(string AccessToken, string RefreshToken);
+        public class Command : IRequest<Result<ExternalLoginResult>>
+        {
+            public ExternalLoginCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<ExternalLoginResult>>
+        {
+
+            private readonly DataContext _context;
+            private readonly ITokenService _tokenService;
+            private readonly ITokenRepository _tokenCashRepository;
+
+            public Handler(DataContext context, ITokenService tokenService, ITokenRepository tokenCashRepository)
+            {
+                _context = context;
+                _tokenService = tokenService;
+                _tokenCashRepository = tokenCashRepository;
+            }
+            public async Task<Result<ExternalLoginResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var request = command.Request;
+
+                if (string.IsNullOrEmpty(request.Provider) || string.IsNullOrEmpty(request.Subject))
+                    return Result<ExternalLoginResult>.Failure(new RpcException(new Status(StatusCode.InvalidArgument, "Provider and subject are required")));
+
+                var login = await _context.AppUserLogins
+                    .Include(login => login.AppUser)
+                    .FirstOrDefaultAsync(login => login.Provider == request.Provider && login.ProviderKey == request.Subject);
+
+                var user = login?.AppUser;
+
+                if (user == null)
+                {
+                    // An external account is linked by email only when the provider vouches for it,
+                    // otherwise anyone could take over a password account by registering its email elsewhere.
+                    if (string.IsNullOrEmpty(request.Email) || !request.EmailVerified)
+                        return Result<ExternalLoginResult>.Failure(new RpcException(new Status(StatusCode.PermissionDenied, "Email is not verified by provider")));
+
+                    user = await _context.AppUsers.FirstOrDefaultAsync(user => user.Email == request.Email);
+
+                    if (user == null)
+                    {
+                        user = new AppUser
+                        {
+                            Email = request.Email,
+                            // Social-only accounts get a random password nobody knows.
+                            Password = BCrypt.Net.BCrypt.HashPassword(Guid.NewGuid().ToString()),
+                        };
+                        _context.AppUsers.Add(user);
+                    }
+
+                    _context.AppUserLogins.Add(new AppUserLogin
+                    {
+                        Provider = request.Provider,
+                        ProviderKey = request.Subject,
+                        AppUser = user,
+                    });
+                    await _context.SaveChangesAsync();
+                }
+
+                bool isLoggedIn = await _tokenCashRepository.IsExist(user.Id.ToString());
+
+                if (isLoggedIn)
+                    return Result<ExternalLoginResult>.Failure(new RpcException(new Status(StatusCode.AlreadyExists, "User have already logged in")));
+
+                string accessToken = await _tokenService.CreateAccessToken(user);
+
+                string refreshToken = await _tokenService.CreateRefreshToken(user);
+                await _tokenCashRepository.StoreToken(user.Id.ToString(), refreshToken);
+
+                return Result<ExternalLoginResult>.Success(new ExternalLoginResult(accessToken, refreshToken));
+            }
+        }
+    }
+}
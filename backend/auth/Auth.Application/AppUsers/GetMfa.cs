@@ -0,0 +1,40 @@
+﻿using Auth.Application.Core;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+
+namespace Auth.Application.AppUsers
+{
+    public class GetMfa
+    {
+        public record GetMfaCommand(string Email);
+        public record GetMfaResult(string Secret);
+        public class Command : IRequest<Result<GetMfaResult>>
+        {
+            public GetMfaCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<GetMfaResult>>
+        {
+            private readonly DataContext _context;
+
+            public Handler(DataContext context)
+            {
+                _context = context;
+            }
+            public async Task<Result<GetMfaResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var user = await _context.AppUsers.FirstOrDefaultAsync(user => user.Email == command.Request.Email);
+
+                if (user == null)
+                    return Result<GetMfaResult>.Failure(new RpcException(new Status(StatusCode.Unauthenticated, "User not found")));
+
+                var mfa = await _context.AppUserMfas.FirstOrDefaultAsync(mfa => mfa.AppUserId == user.Id);
+
+                // An empty secret means 2FA is not enabled.
+                return Result<GetMfaResult>.Success(new GetMfaResult(mfa?.Secret ?? ""));
+            }
+        }
+    }
+}
@@ -0,0 +1,42 @@
+﻿using Auth.Application.Core;
+using Auth.Domain;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+
+namespace Auth.Application.AppUsers
+{
+    public class ListPasskeys
+    {
+        public record ListPasskeysCommand(string Email);
+        public record ListPasskeysResult(List<AppUserPasskey> Passkeys);
+        public class Command : IRequest<Result<ListPasskeysResult>>
+        {
+            public ListPasskeysCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<ListPasskeysResult>>
+        {
+            private readonly DataContext _context;
+
+            public Handler(DataContext context)
+            {
+                _context = context;
+            }
+            public async Task<Result<ListPasskeysResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var user = await _context.AppUsers.FirstOrDefaultAsync(user => user.Email == command.Request.Email);
+
+                if (user == null)
+                    return Result<ListPasskeysResult>.Failure(new RpcException(new Status(StatusCode.Unauthenticated, "User not found")));
+
+                var passkeys = await _context.AppUserPasskeys
+                    .Where(passkey => passkey.AppUserId == user.Id)
+                    .ToListAsync();
+
+                return Result<ListPasskeysResult>.Success(new ListPasskeysResult(passkeys));
+            }
+        }
+    }
+}
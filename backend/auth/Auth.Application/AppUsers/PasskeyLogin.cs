@@ -0,0 +1,66 @@
+﻿using Auth.Application.Core;
+using Auth.Infrastructure.Persistance;
+using Auth.Infrastructure.Redis;
+using Auth.Infrastructure.Services;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+
+namespace Auth.Application.AppUsers
+{
+    public class PasskeyLogin
+    {
+        public record PasskeyLoginCommand(string Email, byte[] CredentialId, uint SignCount, bool BackupState);
+        public record PasskeyLoginResult(string AccessToken, string RefreshToken);
+        public class Command : IRequest<Result<PasskeyLoginResult>>
+        {
+            public PasskeyLoginCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<PasskeyLoginResult>>
+        {
+
+            private readonly DataContext _context;
+            private readonly ITokenService _tokenService;
+            private readonly ITokenRepository _tokenCashRepository;
+
+            public Handler(DataContext context, ITokenService tokenService, ITokenRepository tokenCashRepository)
+            {
+                _context = context;
+                _tokenService = tokenService;
+                _tokenCashRepository = tokenCashRepository;
+            }
+            public async Task<Result<PasskeyLoginResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var request = command.Request;
+
+                // The gateway has verified the assertion against the public key,
+                // here the passkey only has to belong to the user logging in.
+                var passkey = await _context.AppUserPasskeys
+                    .Include(passkey => passkey.AppUser)
+                    .FirstOrDefaultAsync(passkey => passkey.CredentialId.SequenceEqual(request.CredentialId) && passkey.AppUser.Email == request.Email);
+
+                if (passkey == null)
+                    return Result<PasskeyLoginResult>.Failure(new RpcException(new Status(StatusCode.Unauthenticated, "Passkey not found")));
+
+                var user = passkey.AppUser;
+
+                bool isLoggedIn = await _tokenCashRepository.IsExist(user.Id.ToString());
+
+                if (isLoggedIn)
+                    return Result<PasskeyLoginResult>.Failure(new RpcException(new Status(StatusCode.AlreadyExists, "User have already logged in")));
+
+                passkey.SignCount = request.SignCount;
+                passkey.BackupState = request.BackupState;
+                await _context.SaveChangesAsync();
+
+                string accessToken = await _tokenService.CreateAccessToken(user);
+
+                string refreshToken = await _tokenService.CreateRefreshToken(user);
+                await _tokenCashRepository.StoreToken(user.Id.ToString(), refreshToken);
+
+                return Result<PasskeyLoginResult>.Success(new PasskeyLoginResult(accessToken, refreshToken));
+            }
+        }
+    }
+}
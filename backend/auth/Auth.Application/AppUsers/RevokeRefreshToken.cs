@@ -0,0 +1,58 @@
+﻿using Auth.Application.Core;
+using Auth.Infrastructure.Persistance;
+using Auth.Infrastructure.Redis;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+using System.Security.Cryptography;
+using System.Text;
+
+namespace Auth.Application.AppUsers
+{
+    public class RevokeRefreshToken
+    {
+        public record RevokeRefreshTokenCommand(string Email, string RefreshTokenHash);
+        public record RevokeRefreshTokenResult(bool IsRevoked);
+        public class Command : IRequest<Result<RevokeRefreshTokenResult>>
+        {
+            public RevokeRefreshTokenCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<RevokeRefreshTokenResult>>
+        {
+            private readonly DataContext _context;
+            private readonly ITokenRepository _tokenRepository;
+
+            public Handler(DataContext context, ITokenRepository tokenRepository)
+            {
+                _context = context;
+                _tokenRepository = tokenRepository;
+            }
+            public async Task<Result<RevokeRefreshTokenResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var request = command.Request;
+
+                var user = await _context.AppUsers.FirstOrDefaultAsync(user => user.Email == request.Email);
+
+                if (user == null)
+                    return Result<RevokeRefreshTokenResult>.Failure(new RpcException(new Status(StatusCode.NotFound, "User not found")));
+
+                // The gateway only knows the SHA-256 of the refresh token a
+                // session was issued with. A user keeps a single refresh token,
+                // so one issued by a later login is left alone.
+                var userId = user.Id.ToString();
+                var token = await _tokenRepository.GetToken(userId);
+                if (string.IsNullOrEmpty(token))
+                    return Result<RevokeRefreshTokenResult>.Success(new RevokeRefreshTokenResult(false));
+
+                var hash = Convert.ToHexString(SHA256.HashData(Encoding.UTF8.GetBytes(token))).ToLowerInvariant();
+                if (!CryptographicOperations.FixedTimeEquals(Encoding.UTF8.GetBytes(hash), Encoding.UTF8.GetBytes(request.RefreshTokenHash)))
+                    return Result<RevokeRefreshTokenResult>.Success(new RevokeRefreshTokenResult(false));
+
+                var isRevoked = await _tokenRepository.RemoveToken(userId);
+
+                return Result<RevokeRefreshTokenResult>.Success(new RevokeRefreshTokenResult(isRevoked));
+            }
+        }
+    }
+}
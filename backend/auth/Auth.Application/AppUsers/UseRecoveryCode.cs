@@ -0,0 +1,55 @@
+﻿using Auth.Application.Core;
+using Auth.Infrastructure.Persistance;
+using Grpc.Core;
+using MediatR;
+using Microsoft.EntityFrameworkCore;
+
+namespace Auth.Application.AppUsers
+{
+    public class UseRecoveryCode
+    {
+        public record UseRecoveryCodeCommand(string Email, string CodeHash);
+        public record UseRecoveryCodeResult(bool IsValid);
+        public class Command : IRequest<Result<UseRecoveryCodeResult>>
+        {
+            public UseRecoveryCodeCommand Request { get; set; }
+        }
+
+        public class Handler : IRequestHandler<Command, Result<UseRecoveryCodeResult>>
+        {
+            private readonly DataContext _context;
+
+            public Handler(DataContext context)
+            {
+                _context = context;
+            }
+            public async Task<Result<UseRecoveryCodeResult>> Handle(Command command, CancellationToken cancellationToken = default)
+            {
+                var request = command.Request;
+
+                var user = await _context.AppUsers.FirstOrDefaultAsync(user => user.Email == request.Email);
+
+                if (user == null)
+                    return Result<UseRecoveryCodeResult>.Failure(new RpcException(new Status(StatusCode.Unauthenticated, "User not found")));
+
+                var code = await _context.AppUserRecoveryCodes.FirstOrDefaultAsync(code => code.AppUserId == user.Id && code.CodeHash == request.CodeHash);
+
+                if (code == null)
+                    return Result<UseRecoveryCodeResult>.Success(new UseRecoveryCodeResult(false));
+
+                _context.AppUserRecoveryCodes.Remove(code);
+                try
+                {
+                    await _context.SaveChangesAsync();
+                }
+                catch (DbUpdateConcurrencyException)
+                {
+                    // A concurrent request consumed the code first.
+                    return Result<UseRecoveryCodeResult>.Success(new UseRecoveryCodeResult(false));
+                }
+
+                return Result<UseRecoveryCodeResult>.Success(new UseRecoveryCodeResult(true));
+            }
+        }
+    }
+}
@@ -0,0 +1,11 @@
+﻿namespace Auth.Domain
+{
+    public class AppUserLogin
+    {
+        public Guid Id { get; set; }
+        public string Provider { get; set; }
+        public string ProviderKey { get; set; }
+        public Guid AppUserId { get; set; }
+        public AppUser AppUser { get; set; }
+    }
+}
@@ -0,0 +1,10 @@
+﻿namespace Auth.Domain
+{
+    public class AppUserMfa
+    {
+        public Guid Id { get; set; }
+        public string Secret { get; set; }
+        public Guid AppUserId { get; set; }
+        public AppUser AppUser { get; set; }
+    }
+}
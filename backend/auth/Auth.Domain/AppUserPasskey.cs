@@ -0,0 +1,17 @@
+﻿namespace Auth.Domain
+{
+    public class AppUserPasskey
+    {
+        public Guid Id { get; set; }
+        public byte[] CredentialId { get; set; }
+        public byte[] PublicKey { get; set; }
+        public string AttestationType { get; set; }
+        public string Transports { get; set; }
+        public byte[] Aaguid { get; set; }
+        public long SignCount { get; set; }
+        public bool BackupEligible { get; set; }
+        public bool BackupState { get; set; }
+        public Guid AppUserId { get; set; }
+        public AppUser AppUser { get; set; }
+    }
+}
@@ -0,0 +1,10 @@
+﻿namespace Auth.Domain
+{
+    public class AppUserRecoveryCode
+    {
+        public Guid Id { get; set; }
+        public string CodeHash { get; set; }
+        public Guid AppUserId { get; set; }
+        public AppUser AppUser { get; set; }
+    }
+}
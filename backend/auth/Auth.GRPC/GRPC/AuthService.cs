@@ -1,4 +1,5 @@
 ﻿using Auth.Application.AppUsers;
+using Google.Protobuf;
 using Grpc.Core;
 using MediatR;
 
@@ -75,5 +76,169 @@ namespace Auth.GRPC.Controllers
                 IsSuccess = result.IsSuccess,
             };
         }
+
+        public override async Task<LoginReply> ExternalLogin(ExternalLoginRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Logging user with {Provider} account: {Subject}", request.Provider, request.Subject);
+            var command = new ExternalLogin.Command { Request = new ExternalLogin.ExternalLoginCommand(request.Provider, request.Subject, request.Email, request.EmailVerified) };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            return new LoginReply
+            {
+                AccessToken = result.Value.AccessToken,
+                RefreshToken = result.Value.RefreshToken
+            };
+        }
+
+        public override async Task<AddPasskeyReply> AddPasskey(AddPasskeyRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Adding passkey for user with email: {Email}", request.Email);
+            var passkey = request.Passkey ?? new Passkey();
+            var command = new AddPasskey.Command
+            {
+                Request = new AddPasskey.AddPasskeyCommand(
+                    request.Email,
+                    passkey.CredentialId.ToByteArray(),
+                    passkey.PublicKey.ToByteArray(),
+                    passkey.AttestationType,
+                    passkey.Transports,
+                    passkey.Aaguid.ToByteArray(),
+                    passkey.SignCount,
+                    passkey.BackupEligible,
+                    passkey.BackupState)
+            };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            return new AddPasskeyReply
+            {
+                IsSuccess = result.Value.IsSuccess,
+            };
+        }
+
+        public override async Task<ListPasskeysReply> ListPasskeys(ListPasskeysRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Listing passkeys for user with email: {Email}", request.Email);
+            var command = new ListPasskeys.Command { Request = new ListPasskeys.ListPasskeysCommand(request.Email) };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            var reply = new ListPasskeysReply();
+            foreach (var passkey in result.Value.Passkeys)
+            {
+                reply.Passkeys.Add(new Passkey
+                {
+                    CredentialId = ByteString.CopyFrom(passkey.CredentialId),
+                    PublicKey = ByteString.CopyFrom(passkey.PublicKey),
+                    AttestationType = passkey.AttestationType,
+                    Transports = { passkey.Transports.Split(',', StringSplitOptions.RemoveEmptyEntries) },
+                    Aaguid = ByteString.CopyFrom(passkey.Aaguid),
+                    SignCount = (uint)passkey.SignCount,
+                    BackupEligible = passkey.BackupEligible,
+                    BackupState = passkey.BackupState,
+                });
+            }
+
+            return reply;
+        }
+
+        public override async Task<LoginReply> PasskeyLogin(PasskeyLoginRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Logging user with passkey, email: {Email}", request.Email);
+            var command = new PasskeyLogin.Command { Request = new PasskeyLogin.PasskeyLoginCommand(request.Email, request.CredentialId.ToByteArray(), request.SignCount, request.BackupState) };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            return new LoginReply
+            {
+                AccessToken = result.Value.AccessToken,
+                RefreshToken = result.Value.RefreshToken
+            };
+        }
+
+        public override async Task<EnableMfaReply> EnableMfa(EnableMfaRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Enabling 2FA for user with email: {Email}", request.Email);
+            var command = new EnableMfa.Command { Request = new EnableMfa.EnableMfaCommand(request.Email, request.Secret, request.RecoveryCodeHashes) };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            return new EnableMfaReply
+            {
+                IsSuccess = result.Value.IsSuccess,
+            };
+        }
+
+        public override async Task<GetMfaReply> GetMfa(GetMfaRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Getting 2FA for user with email: {Email}", request.Email);
+            var command = new GetMfa.Command { Request = new GetMfa.GetMfaCommand(request.Email) };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            return new GetMfaReply
+            {
+                Secret = result.Value.Secret,
+            };
+        }
+
+        public override async Task<UseRecoveryCodeReply> UseRecoveryCode(UseRecoveryCodeRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Using 2FA recovery code for user with email: {Email}", request.Email);
+            var command = new UseRecoveryCode.Command { Request = new UseRecoveryCode.UseRecoveryCodeCommand(request.Email, request.CodeHash) };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            return new UseRecoveryCodeReply
+            {
+                IsValid = result.Value.IsValid,
+            };
+        }
+
+        public override async Task<RevokeRefreshTokenReply> RevokeRefreshToken(RevokeRefreshTokenRequest request, ServerCallContext context)
+        {
+            logger.LogInformation("Revoking refresh token for user with email: {Email}", request.Email);
+            var command = new RevokeRefreshToken.Command { Request = new RevokeRefreshToken.RevokeRefreshTokenCommand(request.Email, request.RefreshTokenHash) };
+            var result = await mediator.Send(command);
+
+            if (!result.IsSuccess)
+            {
+                throw result.Error;
+            }
+
+            return new RevokeRefreshTokenReply
+            {
+                IsRevoked = result.Value.IsRevoked,
+            };
+        }
     }
 }
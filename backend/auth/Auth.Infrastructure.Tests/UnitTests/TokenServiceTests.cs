@@ -37,6 +37,28 @@ namespace Auth.Infrastructure.Tests.UnitTests
             Assert.False(string.IsNullOrEmpty(token));
         }
 
+        [Fact]
+        public async Task Should_Create_Access_Tokens_With_Unique_Jti()
+        {
+            // Act
+            var first = new JwtSecurityTokenHandler().ReadJwtToken(await _tokenService.CreateAccessToken(_user));
+            var second = new JwtSecurityTokenHandler().ReadJwtToken(await _tokenService.CreateAccessToken(_user));
+
+            //Assert
+            Assert.False(string.IsNullOrEmpty(first.Id));
+            Assert.NotEqual(first.Id, second.Id);
+        }
+
+        [Fact]
+        public async Task Should_Create_Access_Token_With_User_Id_Subject()
+        {
+            // Act
+            var token = new JwtSecurityTokenHandler().ReadJwtToken(await _tokenService.CreateAccessToken(_user));
+
+            //Assert
+            Assert.Equal(_user.Id.ToString(), token.Subject);
+        }
+
         [Fact]
         public async Task Should_Succesfuly_Create_Refresh_Token()
         {
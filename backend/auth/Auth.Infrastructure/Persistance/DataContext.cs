@@ -10,5 +10,30 @@ namespace Auth.Infrastructure.Persistance
         }
 
         public DbSet<AppUser> AppUsers { get; set; }
+        public DbSet<AppUserLogin> AppUserLogins { get; set; }
+        public DbSet<AppUserPasskey> AppUserPasskeys { get; set; }
+        public DbSet<AppUserMfa> AppUserMfas { get; set; }
+        public DbSet<AppUserRecoveryCode> AppUserRecoveryCodes { get; set; }
+
+        protected override void OnModelCreating(ModelBuilder modelBuilder)
+        {
+            base.OnModelCreating(modelBuilder);
+
+            modelBuilder.Entity<AppUserLogin>()
+                .HasIndex(login => new { login.Provider, login.ProviderKey })
+                .IsUnique();
+
+            modelBuilder.Entity<AppUserPasskey>()
+                .HasIndex(passkey => passkey.CredentialId)
+                .IsUnique();
+
+            modelBuilder.Entity<AppUserMfa>()
+                .HasIndex(mfa => mfa.AppUserId)
+                .IsUnique();
+
+            modelBuilder.Entity<AppUserRecoveryCode>()
+                .HasIndex(code => new { code.AppUserId, code.CodeHash })
+                .IsUnique();
+        }
     }
 }
@@ -14,7 +14,11 @@ namespace Auth.Infrastructure.Services
         {
             var claims = new List<Claim>
             {
-                new Claim(ClaimTypes.Email, user.Email)
+                new Claim(ClaimTypes.Email, user.Email),
+                // The gateway reports the user ID from sub, as in token introspection.
+                new Claim(JwtRegisteredClaimNames.Sub, user.Id.ToString()),
+                // The gateway can key its token store by jti (TOKEN_STORE_KEY=jti).
+                new Claim(JwtRegisteredClaimNames.Jti, Guid.NewGuid().ToString())
             };
 
             var key = new SymmetricSecurityKey(Encoding.UTF8.GetBytes(config["JWT_ACCESS_SECRET_KEY"]!));
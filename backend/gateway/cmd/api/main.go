@@ -2,29 +2,44 @@ package main
 
 import (
 	"context"
+	"go.uber.org/zap"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 	"workmap/gateway/config"
 	"workmap/gateway/logger"
 )
 
 func main() {
-	log := logger.New()
+	level := zap.NewAtomicLevel()
+	log := logger.New(level)
 	cfg := config.New(log)
-	services := cfg.NewServices(log)
-	server := services.Server
+	services := cfg.NewServices(log, level)
 
-	server.Run()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// Restore the default handling, so that a second signal kills the
+		// process instead of waiting for the shutdown.
+		<-ctx.Done()
+		stop()
+	}()
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	services.WatchConfigFile()
 
-	<-quit
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Info("received SIGHUP, reloading config")
+			services.Reload()
+		}
+	}()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	server.ShutDown(ctx)
+	if err := services.Run(ctx); err != nil {
+		log.Error("gateway stopped with errors", zap.Error(err))
+		_ = log.Sync()
+		os.Exit(1)
+	}
+	log.Info("gateway stopped")
 }
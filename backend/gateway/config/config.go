@@ -1,102 +1,858 @@
 package config
 
 import (
-	"github.com/spf13/viper"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/spf13/pflag"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"workmap/gateway/docs"
+	"workmap/gateway/internal/crash"
 	"workmap/gateway/internal/gapi"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/grpcserver"
 	"workmap/gateway/internal/handlers"
+	"workmap/gateway/internal/introspection"
+	"workmap/gateway/internal/lifecycle"
+	_ "workmap/gateway/internal/memstore" // registers the memory token store
+	"workmap/gateway/internal/metricsserver"
 	"workmap/gateway/internal/middlewares"
+	"workmap/gateway/internal/oauth"
+	_ "workmap/gateway/internal/pgstore" // registers the postgres token store
 	"workmap/gateway/internal/redis"
 	"workmap/gateway/internal/routes"
+	"workmap/gateway/internal/rpcproxy"
 	"workmap/gateway/internal/server"
+	"workmap/gateway/internal/tenant"
+	"workmap/gateway/internal/tokencache"
+	"workmap/gateway/pkg/identity"
 )
 
 type (
+	// Config is loaded by Load. The mapstructure tag of a field is its key in
+	// every layer: the environment, the config file and, lowercased with
+	// dashes, the command-line flag. Fields tagged secret are redacted by
+	// Print and can be read from the file named by <KEY>_FILE. Fields tagged
+	// restart are not applied by Services.Reload.
 	Config struct {
-		Port        string      `mapstructure:"PORT"`
-		AuthService AuthService `mapstructure:",squash"`
-		Redis       Redis       `mapstructure:",squash"`
+		Port            string        `mapstructure:"PORT" restart:"true"`
+		ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+		ShutdownDrain   time.Duration `mapstructure:"SHUTDOWN_DRAIN_PERIOD"`
+		LogLevel        zapcore.Level `mapstructure:"LOG_LEVEL"`
+		Environment     string        `mapstructure:"ENVIRONMENT"`
+		AuthService     AuthService   `mapstructure:",squash"`
+		Redis           Redis         `mapstructure:",squash"`
+		TokenStore      TokenStore    `mapstructure:",squash"`
+		TokenCache      TokenCache    `mapstructure:",squash"`
+		Tenant          Tenant        `mapstructure:",squash"`
+		HTTP            HTTP          `mapstructure:",squash"`
+		Compression     Compression   `mapstructure:",squash"`
+		OpenAPI         OpenAPI       `mapstructure:",squash"`
+		API             API           `mapstructure:",squash"`
+		RPC             RPC           `mapstructure:",squash"`
+		GRPC            GRPC          `mapstructure:",squash"`
+		Metrics         Metrics       `mapstructure:",squash"`
+		Identity        Identity      `mapstructure:",squash"`
+		Crash           Crash         `mapstructure:",squash"`
+		CORS            CORS          `mapstructure:",squash"`
+		OAuth           OAuth         `mapstructure:",squash"`
+		WebAuthn        WebAuthn      `mapstructure:",squash"`
+		CSRF            CSRF          `mapstructure:",squash"`
+
+		args []string
+		file string
 	}
 
 	AuthService struct {
-		Host string `mapstructure:"AUTH_SERVICE_HOST"`
-		Port string `mapstructure:"AUTH_SERVICE_PORT"`
+		Host string `mapstructure:"AUTH_SERVICE_HOST" restart:"true"`
+		Port string `mapstructure:"AUTH_SERVICE_PORT" restart:"true"`
+		// AccessSecret is the key the Auth service signs access tokens with,
+		// used only by REDIS_FALLBACK=verify.
+		AccessSecret string `mapstructure:"JWT_ACCESS_SECRET_KEY" secret:"true"`
 	}
 
+	// Redis is used by every store, and by the token store unless
+	// TOKEN_STORE selects another backend. Host and port are used in
+	// standalone mode, addrs in sentinel and cluster mode. Fallback is one
+	// of the RedisFallback constants.
 	Redis struct {
-		Host     string `mapstructure:"REDIS_HOST"`
-		Port     string `mapstructure:"REDIS_PORT"`
-		Password string `mapstructure:"REDIS_PASSWORD"`
+		Mode           string        `mapstructure:"REDIS_MODE" restart:"true"`
+		Host           string        `mapstructure:"REDIS_HOST" restart:"true"`
+		Port           string        `mapstructure:"REDIS_PORT" restart:"true"`
+		Password       string        `mapstructure:"REDIS_PASSWORD" secret:"true" restart:"true"`
+		Addrs          []string      `mapstructure:"REDIS_ADDRS" restart:"true"`
+		SentinelMaster string        `mapstructure:"REDIS_SENTINEL_MASTER" restart:"true"`
+		DB             int           `mapstructure:"REDIS_DB" restart:"true"`
+		KeyPrefix      string        `mapstructure:"REDIS_KEY_PREFIX" restart:"true"`
+		Fallback       string        `mapstructure:"REDIS_FALLBACK" restart:"true"`
+		HealthInterval time.Duration `mapstructure:"REDIS_HEALTH_INTERVAL" restart:"true"`
+	}
+
+	// Tenant scopes access tokens and sessions in Redis. Hosts are
+	// host=tenant pairs, checked before the claim of the access token.
+	Tenant struct {
+		Hosts []string `mapstructure:"TENANT_HOSTS"`
+		Claim string   `mapstructure:"TENANT_CLAIM"`
+	}
+
+	// TokenStore selects where access tokens are kept, see store.TokenBackends.
+	// Every backend keys them by HMAC or jti, see store.TokenKeyConfig.
+	TokenStore struct {
+		Backend      string `mapstructure:"TOKEN_STORE" restart:"true"`
+		MemoryShards int    `mapstructure:"TOKEN_STORE_MEMORY_SHARDS" restart:"true"`
+		PostgresDSN  string `mapstructure:"TOKEN_STORE_POSTGRES_DSN" secret:"true" restart:"true"`
+		Key          string `mapstructure:"TOKEN_STORE_KEY" restart:"true"`
+		Pepper       string `mapstructure:"TOKEN_STORE_PEPPER" secret:"true" restart:"true"`
+		Migration    bool   `mapstructure:"TOKEN_STORE_MIGRATION" restart:"true"`
+	}
+
+	// TokenCache keeps the recent access token lookups in memory, disabled
+	// when the size is 0. Deletions reach the other gateways through Redis.
+	TokenCache struct {
+		Size        int           `mapstructure:"TOKEN_CACHE_SIZE" restart:"true"`
+		MaxAge      time.Duration `mapstructure:"TOKEN_CACHE_MAX_AGE" restart:"true"`
+		NegativeAge time.Duration `mapstructure:"TOKEN_CACHE_NEGATIVE_AGE" restart:"true"`
+	}
+
+	// HTTP limits the connections and requests. The route overrides are
+	// pattern=value pairs like "POST /user/login=5s", 0 for no limit. The
+	// rate limits are class=requests/window pairs like "auth=20/1m". H2C is
+	// only for a trusted proxy in front of the gateway.
+	HTTP struct {
+		ReadHeaderTimeout time.Duration `mapstructure:"HTTP_READ_HEADER_TIMEOUT" restart:"true"`
+		ReadTimeout       time.Duration `mapstructure:"HTTP_READ_TIMEOUT" restart:"true"`
+		WriteTimeout      time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT" restart:"true"`
+		IdleTimeout       time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT" restart:"true"`
+		MaxHeaderBytes    int           `mapstructure:"HTTP_MAX_HEADER_BYTES" restart:"true"`
+		H2C               bool          `mapstructure:"HTTP_H2C" restart:"true"`
+		MaxBodyBytes      int64         `mapstructure:"HTTP_MAX_BODY_BYTES"`
+		HandlerTimeout    time.Duration `mapstructure:"HTTP_HANDLER_TIMEOUT"`
+		RouteMaxBodyBytes []string      `mapstructure:"HTTP_ROUTE_MAX_BODY_BYTES"`
+		RouteTimeouts     []string      `mapstructure:"HTTP_ROUTE_TIMEOUTS"`
+		RateLimits        []string      `mapstructure:"HTTP_RATE_LIMITS"`
+	}
+	// Compression applies to the content types listed, a type ending with a
+	// slash standing for all its subtypes.
+	Compression struct {
+		Encodings    []string `mapstructure:"COMPRESSION_ENCODINGS"`
+		MinSize      int      `mapstructure:"COMPRESSION_MIN_SIZE"`
+		ContentTypes []string `mapstructure:"COMPRESSION_CONTENT_TYPES"`
+	}
+	// OpenAPI checks the requests, and the responses in development, against
+	// docs/swagger.yaml. ValidateResponses is one of the
+	// middlewares.ResponseValidation constants, empty to disable it; failing
+	// responses is only allowed outside of production.
+	OpenAPI struct {
+		ValidateRequests  bool   `mapstructure:"OPENAPI_VALIDATE_REQUESTS"`
+		ValidateResponses string `mapstructure:"OPENAPI_VALIDATE_RESPONSES"`
+	}
+	// API deprecates versions like v1, the unversioned routes of v1 with
+	// routes.Unversioned, or routes as served like "GET /user/profile".
+	// Deprecations and sunsets are route=date pairs like v1=2026-10-01.
+	API struct {
+		Deprecations    []string `mapstructure:"API_DEPRECATIONS"`
+		Sunsets         []string `mapstructure:"API_SUNSETS"`
+		DeprecationLink string   `mapstructure:"API_DEPRECATION_LINK"`
+	}
+	// RPC serves procedures of the Auth service, like
+	// "/auth.AuthService/Login", to the clients generated from
+	// backend/proto, over Connect, gRPC-Web and gRPC. The calls skip the
+	// checks of the REST handlers, like 2FA, so none is served by default,
+	// and only those of rpcproxy.BrowserSafe may be.
+	RPC struct {
+		Procedures []string `mapstructure:"RPC_PROCEDURES"`
+	}
+	// GRPC serves the TokenIntrospection service of backend/proto to the
+	// internal services, on its own port, none if empty. The client token is
+	// required with the port, as any caller reaching it could revoke tokens.
+	GRPC struct {
+		Port        string `mapstructure:"GRPC_PORT" restart:"true"`
+		ClientToken string `mapstructure:"GRPC_CLIENT_TOKEN" secret:"true" restart:"true"`
+	}
+	// Metrics serves GET /metrics on its own port, none if empty, so that the
+	// metrics are not public along the API.
+	Metrics struct {
+		Port string `mapstructure:"METRICS_PORT" restart:"true"`
+	}
+	// Identity signs the identity tokens sent to the upstream services along
+	// the calls made for an authenticated caller, none if the key is empty.
+	// The key is the base64 of an Ed25519 seed, its public key is logged at
+	// startup for the services to verify with.
+	Identity struct {
+		SigningKey string        `mapstructure:"IDENTITY_SIGNING_KEY" secret:"true" restart:"true"`
+		TokenTTL   time.Duration `mapstructure:"IDENTITY_TOKEN_TTL" restart:"true"`
+	}
+	// Crash sends a report of each panic of the handlers to a directory, an
+	// HTTP endpoint taking JSON POST requests, both, or neither when empty.
+	Crash struct {
+		ReportsDir     string        `mapstructure:"CRASH_REPORTS_DIR"`
+		ReportsURL     string        `mapstructure:"CRASH_REPORTS_URL" secret:"true"`
+		ReportsTimeout time.Duration `mapstructure:"CRASH_REPORTS_TIMEOUT"`
+	}
+	CORS struct {
+		AllowedOrigins []string `mapstructure:"CORS_ALLOWED_ORIGINS"`
+	}
+
+	// OAuth configures social login. A provider is enabled when its client ID
+	// is set. The callbacks redirect the browser to FrontendURL.
+	OAuth struct {
+		RedirectBaseURL      string `mapstructure:"OAUTH_REDIRECT_BASE_URL"`
+		FrontendURL          string `mapstructure:"OAUTH_FRONTEND_URL"`
+		GoogleClientID       string `mapstructure:"OAUTH_GOOGLE_CLIENT_ID"`
+		GoogleClientSecret   string `mapstructure:"OAUTH_GOOGLE_CLIENT_SECRET" secret:"true"`
+		GitHubClientID       string `mapstructure:"OAUTH_GITHUB_CLIENT_ID"`
+		GitHubClientSecret   string `mapstructure:"OAUTH_GITHUB_CLIENT_SECRET" secret:"true"`
+		LinkedInClientID     string `mapstructure:"OAUTH_LINKEDIN_CLIENT_ID"`
+		LinkedInClientSecret string `mapstructure:"OAUTH_LINKEDIN_CLIENT_SECRET" secret:"true"`
+		// Clients are the id=secret pairs of the resource servers calling
+		// /oauth/introspect and /oauth/revoke, none if empty. Only the
+		// RevokeClients, by ID, may revoke tokens.
+		Clients       []string `mapstructure:"OAUTH_CLIENTS" secret:"true"`
+		RevokeClients []string `mapstructure:"OAUTH_REVOKE_CLIENTS"`
 	}
+
+	// WebAuthn configures passkeys. They are enabled when the relying party ID is set.
+	WebAuthn struct {
+		RPID          string   `mapstructure:"WEBAUTHN_RP_ID"`
+		RPDisplayName string   `mapstructure:"WEBAUTHN_RP_DISPLAY_NAME"`
+		RPOrigins     []string `mapstructure:"WEBAUTHN_RP_ORIGINS"`
+	}
+
+	// CSRF configures the protection of cookie-authenticated routes.
+	CSRF struct {
+		Secret         string   `mapstructure:"CSRF_SECRET" secret:"true"`
+		TrustedOrigins []string `mapstructure:"CSRF_TRUSTED_ORIGINS"`
+		ExemptPaths    []string `mapstructure:"CSRF_EXEMPT_PATHS"`
+	}
+)
+
+// What the gateway is deployed for, in ENVIRONMENT.
+const (
+	EnvironmentProduction  = "production"
+	EnvironmentDevelopment = "development"
+	EnvironmentTest        = "test"
+)
+
+// What the gateway does while Redis is unavailable.
+const (
+	// RedisFallbackNone does not start without Redis, and refuses the
+	// requests needing it.
+	RedisFallbackNone = "none"
+	// RedisFallbackReconnect starts without Redis and reconnects in the
+	// background, refusing the requests needing it meanwhile.
+	RedisFallbackReconnect = "reconnect"
+	// RedisFallbackVerify is RedisFallbackReconnect, but accepts for reads
+	// the access tokens whose signature and expiry verify locally.
+	RedisFallbackVerify = "verify"
 )
 
+// defaults are the built-in values, overridden by the config file, the
+// environment and the command-line flags, in that order.
+var defaults = map[string]any{
+	"PORT":                      "4001",
+	"SHUTDOWN_TIMEOUT":          5 * time.Second,
+	"SHUTDOWN_DRAIN_PERIOD":     time.Duration(0),
+	"LOG_LEVEL":                 "debug",
+	"ENVIRONMENT":               EnvironmentProduction,
+	"AUTH_SERVICE_PORT":         "8080",
+	"REDIS_MODE":                store.RedisModeStandalone,
+	"REDIS_PORT":                "6379",
+	"REDIS_FALLBACK":            RedisFallbackNone,
+	"REDIS_HEALTH_INTERVAL":     time.Second,
+	"TOKEN_STORE":               "redis",
+	"TOKEN_STORE_MEMORY_SHARDS": 32,
+	"TOKEN_STORE_KEY":           store.TokenKeyHMAC,
+	"TOKEN_CACHE_SIZE":          10000,
+	"TOKEN_CACHE_MAX_AGE":       30 * time.Second,
+	"TOKEN_CACHE_NEGATIVE_AGE":  5 * time.Second,
+	"HTTP_READ_HEADER_TIMEOUT":  5 * time.Second,
+	"HTTP_READ_TIMEOUT":         15 * time.Second,
+	"HTTP_WRITE_TIMEOUT":        15 * time.Second,
+	"HTTP_IDLE_TIMEOUT":         time.Minute,
+	"HTTP_MAX_HEADER_BYTES":     64 << 10,
+	"HTTP_MAX_BODY_BYTES":       64 << 10,
+	"HTTP_HANDLER_TIMEOUT":      10 * time.Second,
+	"HTTP_RATE_LIMITS":          "auth=20/1m",
+	"HTTP_H2C":                  false,
+	"COMPRESSION_ENCODINGS":     "br,zstd,gzip",
+	"COMPRESSION_MIN_SIZE":      1024,
+	"COMPRESSION_CONTENT_TYPES": "application/json,application/msgpack,application/x-protobuf,text/",
+	"GRPC_PORT":                 "",
+	"METRICS_PORT":              "9090",
+	"IDENTITY_TOKEN_TTL":        time.Minute,
+	"CRASH_REPORTS_TIMEOUT":     5 * time.Second,
+	"CORS_ALLOWED_ORIGINS":      "*",
+	"WEBAUTHN_RP_DISPLAY_NAME":  "Work Map",
+}
+
+// New loads the config from the command-line arguments and the environment.
+// With --print-config it prints the effective config and exits.
 func New(logger *zap.Logger) *Config {
-	var cfg Config
+	cfg, printConfig, err := Load(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logger.Fatal("failed to load config", zap.Error(err))
+	}
+
+	if printConfig {
+		cfg.Print(os.Stdout)
+	}
 
-	v := viper.New()
-	v.SetConfigType("env")
-	v.AddConfigPath(".")    // path for local development
-	v.AddConfigPath("/app") // path for container
-	v.SetConfigName(".env")
-	v.AutomaticEnv()
-	if err := v.ReadInConfig(); err != nil {
-		logger.Fatal("failed to read config", zap.Error(err))
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal("invalid config", zap.Error(err))
 	}
 
-	if err := v.Unmarshal(&cfg); err != nil {
-		logger.Fatal("failed to unmarshal into config struct", zap.Error(err))
+	if printConfig {
+		os.Exit(0)
 	}
 
-	return &cfg
+	return cfg
 }
 
+// Services are the running gateway. The connections to the Auth service,
+// Redis and the token store live until Run returns, everything built from
+// the rest of the config is replaced by Reload.
 type Services struct {
 	Server *server.Server
+	// GRPCServer is nil without GRPC_PORT.
+	GRPCServer *grpcserver.Server
+	// MetricsServer is nil without METRICS_PORT.
+	MetricsServer *metricsserver.Server
+
+	logger *zap.Logger
+	level  zap.AtomicLevel
+	auth   pb.AuthServiceClient
+	// upstream is the connection of auth, for the proxied procedures.
+	upstream grpc.ClientConnInterface
+	redis    store.RedisStore
+	tokens   store.TokenStore
+	// sessions is tokens if it can list sessions.
+	sessions store.SessionStore
+	// introspection looks tokens up for the gRPC server and the OAuth
+	// introspection endpoint.
+	introspection *introspection.Service
+	// lifecycle starts and stops the connections and the server.
+	lifecycle *lifecycle.Manager
+
+	mu         sync.Mutex
+	cfg        *Config
+	csrfSecret []byte
 }
 
-func (cfg *Config) NewServices(logger *zap.Logger) *Services {
+func (cfg *Config) NewServices(logger *zap.Logger, level zap.AtomicLevel) *Services {
 	auth, err := gapi.NewAuthService(&gapi.AuthConfig{
-		Host: cfg.AuthService.Host,
-		Port: cfg.AuthService.Port,
+		Host:     cfg.AuthService.Host,
+		Port:     cfg.AuthService.Port,
+		Identity: cfg.newIdentitySigner(logger),
 	})
 	if err != nil { // TODO delete this
 		logger.Fatal("auth service err", zap.Error(err))
 	}
 
+	// Components stop in the reverse order: the server first, Redis last.
+	lc := lifecycle.New(&lifecycle.Config{Logger: logger})
+
 	redis, err := store.NewRedis(&store.RedisConfig{
-		Host:     cfg.Redis.Host,
-		Port:     cfg.Redis.Port,
-		Password: cfg.Redis.Password,
+		Mode:       cfg.Redis.Mode,
+		Host:       cfg.Redis.Host,
+		Port:       cfg.Redis.Port,
+		Password:   cfg.Redis.Password,
+		Addrs:      cfg.Redis.Addrs,
+		MasterName: cfg.Redis.SentinelMaster,
+		DB:         cfg.Redis.DB,
+		KeyPrefix:  cfg.Redis.KeyPrefix,
+
+		AllowUnavailable: cfg.Redis.Fallback != RedisFallbackNone,
 	})
 	if err != nil {
 		logger.Fatal("failed connection to redis", zap.Error(err))
 	}
+	if !redis.Up() {
+		logger.Error("redis is unavailable, starting degraded", zap.String("fallback", cfg.Redis.Fallback))
+	}
+	lc.Add(lifecycle.Component{
+		Name: "redis",
+		Stop: func(context.Context) error { return redis.Close() },
+	})
+	lc.Add(lifecycle.Component{
+		Name: "auth service",
+		Stop: func(context.Context) error { return auth.Close() },
+	})
+
+	tokens, err := store.OpenTokenStore(cfg.TokenStore.Backend, &store.TokenBackendConfig{
+		TokenKeys: store.TokenKeyConfig{
+			Mode:    cfg.TokenStore.Key,
+			Pepper:  cfg.newTokenPepper(logger),
+			Migrate: cfg.TokenStore.Migration,
+		},
+		Redis:        &redis,
+		MemoryShards: cfg.TokenStore.MemoryShards,
+		PostgresDSN:  cfg.TokenStore.PostgresDSN,
+	})
+	if err != nil {
+		logger.Fatal("failed to open token store", zap.String("backend", cfg.TokenStore.Backend), zap.Error(err))
+	}
+	// The redis backend shares the Redis connection, closed on its own.
+	if c, ok := tokens.(io.Closer); ok && cfg.TokenStore.Backend != "redis" {
+		lc.Add(lifecycle.Component{
+			Name: "token store",
+			Stop: func(context.Context) error { return c.Close() },
+		})
+	}
+
+	lc.Add(lifecycle.Component{
+		Name: "redis health",
+		Start: func(ctx context.Context) error {
+			redis.Watch(ctx, cfg.Redis.HealthInterval, func(up bool, err error) {
+				if up {
+					logger.Info("redis is available again")
+					return
+				}
+				logger.Error("redis is unavailable", zap.String("fallback", cfg.Redis.Fallback), zap.Error(err))
+			})
+			return nil
+		},
+	})
+
+	if cfg.TokenCache.Size > 0 {
+		cache := tokencache.New(&tokencache.Config{
+			Store:       tokens,
+			Logger:      logger,
+			Invalidator: &redis,
+			Size:        cfg.TokenCache.Size,
+			MaxAge:      cfg.TokenCache.MaxAge,
+			NegativeAge: cfg.TokenCache.NegativeAge,
+		})
+		lc.Add(lifecycle.Component{
+			Name: "token invalidations",
+			Start: func(ctx context.Context) error {
+				err := cache.Subscribe(ctx)
+				if err != nil && cfg.Redis.Fallback != RedisFallbackNone {
+					logger.Error("failed to subscribe to token invalidations, retrying in the background", zap.Error(err))
+					return nil
+				}
+				return err
+			},
+		})
+		tokens = cache
+	}
+
+	sessions, _ := tokens.(store.SessionStore)
+
+	level.SetLevel(cfg.LogLevel)
+
+	s := &Services{
+		lifecycle: lc,
+		logger:    logger,
+		level:     level,
+		auth:      auth,
+		upstream:  auth.Conn(),
+		redis:     redis,
+		tokens:    tokens,
+		sessions:  sessions,
+		introspection: introspection.New(&introspection.Config{
+			Logger:            logger,
+			Tokens:            tokens,
+			APIKeys:           &redis,
+			DegradedJWTSecret: cfg.degradedJWTSecret(),
+			Tenants:           cfg.newTenantResolver(),
+		}),
+		cfg:        cfg,
+		csrfSecret: cfg.newCSRFSecret(logger),
+	}
+
+	s.Server = server.New(&server.Config{
+		Port:              cfg.Port,
+		Logger:            logger,
+		Router:            s.newRouter(cfg),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
+		H2C:               cfg.HTTP.H2C,
+	})
+	lc.Add(lifecycle.Component{
+		Name:  "http server",
+		Start: s.Server.Start,
+		Stop:  s.Server.Stop,
+		Err:   s.Server.Err(),
+	})
+
+	if cfg.GRPC.Port != "" {
+		s.GRPCServer = grpcserver.New(&grpcserver.Config{
+			Port:          cfg.GRPC.Port,
+			Logger:        logger,
+			Introspection: s.introspection,
+			ClientToken:   cfg.GRPC.ClientToken,
+		})
+		lc.Add(lifecycle.Component{
+			Name:  "grpc server",
+			Start: s.GRPCServer.Start,
+			Stop:  s.GRPCServer.Stop,
+			Err:   s.GRPCServer.Err(),
+		})
+	}
+
+	if cfg.Metrics.Port != "" {
+		s.MetricsServer = metricsserver.New(&metricsserver.Config{
+			Port:   cfg.Metrics.Port,
+			Logger: logger,
+		})
+		lc.Add(lifecycle.Component{
+			Name:  "metrics server",
+			Start: s.MetricsServer.Start,
+			Stop:  s.MetricsServer.Stop,
+			Err:   s.MetricsServer.Err(),
+		})
+	}
+
+	return s
+}
+
+// Run starts the gateway and serves until ctx is done or a component fails.
+// It then drains for SHUTDOWN_DRAIN_PERIOD and stops every component within
+// SHUTDOWN_TIMEOUT, returning the errors of all three steps.
+func (s *Services) Run(ctx context.Context) error {
+	if err := s.lifecycle.Start(ctx); err != nil {
+		return err
+	}
+
+	var failure error
+	select {
+	case <-ctx.Done():
+		s.logger.Info("shutting down")
+	case failure = <-s.lifecycle.Failed():
+		s.logger.Error("component failed, shutting down", zap.Error(failure))
+	}
+
+	s.mu.Lock()
+	drain, timeout := s.cfg.ShutdownDrain, s.cfg.ShutdownTimeout
+	s.mu.Unlock()
+
+	return errors.Join(failure, s.lifecycle.Shutdown(drain, timeout))
+}
+
+// newRouter builds the route table and everything behind it from cfg.
+func (s *Services) newRouter(cfg *Config) *routes.Router {
+	tenants := cfg.newTenantResolver()
 
 	h := handlers.New(&handlers.Config{
-		Logger:     logger,
-		Auth:       auth,
-		TokenStore: &redis,
+		Logger:               s.logger,
+		Auth:                 s.auth,
+		TokenStore:           s.tokens,
+		SessionStore:         s.sessions,
+		Tenants:              tenants,
+		OAuthStateStore:      &s.redis,
+		OAuthProviders:       cfg.newOAuthProviders(s.logger),
+		OAuthFrontendURL:     cfg.OAuth.FrontendURL,
+		MFAStore:             &s.redis,
+		WebAuthn:             cfg.newWebAuthn(s.logger),
+		WebAuthnSessionStore: &s.redis,
+		APIKeyStore:          &s.redis,
+		CSRFSecret:           s.csrfSecret,
+		RedisHealth:          &s.redis,
+		DegradedReads:        cfg.Redis.Fallback == RedisFallbackVerify,
+		Lifecycle:            s.lifecycle,
+		Introspection:        s.introspection,
 	})
 
 	m := middlewares.New(&middlewares.Config{
-		Logger: logger,
-		Auth:   auth,
-		Redis:  &redis,
+		Logger:             s.logger,
+		Auth:               s.auth,
+		Redis:              s.tokens,
+		APIKeys:            &s.redis,
+		CORSAllowedOrigins: cfg.CORS.AllowedOrigins,
+		CSRFSecret:         s.csrfSecret,
+		CSRFTrustedOrigins: cfg.CSRF.TrustedOrigins,
+		CSRFExemptPaths:    cfg.CSRF.ExemptPaths,
+		DegradedJWTSecret:  cfg.degradedJWTSecret(),
+		Tenants:            tenants,
+
+		CompressionEncodings:    cfg.Compression.Encodings,
+		CompressionMinSize:      cfg.Compression.MinSize,
+		CompressionContentTypes: cfg.Compression.ContentTypes,
+
+		OpenAPI:          cfg.newOpenAPIRouter(s.logger),
+		OpenAPIRequests:  cfg.OpenAPI.ValidateRequests,
+		OpenAPIResponses: cfg.OpenAPI.ValidateResponses,
+
+		OAuthClients:      cfg.oauthClients(),
+		OAuthClientScopes: cfg.oauthClientScopes(),
+
+		CrashSinks: cfg.newCrashSinks(s.logger),
+	})
+
+	return routes.New(&routes.Config{
+		Logger:       s.logger,
+		Handler:      h,
+		Middleware:   m,
+		RPC:          cfg.newRPCProxy(s.logger, s.upstream),
+		Limits:       cfg.routeLimits(),
+		Deprecations: cfg.deprecations(),
 	})
+}
+
+func (cfg *Config) newOAuthProviders(logger *zap.Logger) map[string]oauth.Provider {
+	o := cfg.OAuth
+	configs := []*oauth.ProviderConfig{
+		{
+			Name:         "google",
+			Kind:         oauth.KindOIDC,
+			Issuer:       "https://accounts.google.com",
+			ClientID:     o.GoogleClientID,
+			ClientSecret: o.GoogleClientSecret,
+		},
+		{
+			Name:         "github",
+			Kind:         oauth.KindGitHub,
+			ClientID:     o.GitHubClientID,
+			ClientSecret: o.GitHubClientSecret,
+		},
+		{
+			Name:         "linkedin",
+			Kind:         oauth.KindOIDC,
+			Issuer:       "https://www.linkedin.com/oauth",
+			ClientID:     o.LinkedInClientID,
+			ClientSecret: o.LinkedInClientSecret,
+		},
+	}
+
+	providers := make(map[string]oauth.Provider)
+	for _, pc := range configs {
+		if pc.ClientID == "" {
+			continue
+		}
+		pc.RedirectURL = fmt.Sprintf("%s/auth/%s/callback", strings.TrimSuffix(o.RedirectBaseURL, "/"), pc.Name)
+
+		p, err := oauth.NewProvider(context.Background(), pc)
+		if err != nil {
+			logger.Error("failed to init oauth provider, social login disabled", zap.String("provider", pc.Name), zap.Error(err))
+			continue
+		}
+		providers[pc.Name] = p
+	}
+
+	return providers
+}
+
+func (cfg *Config) newOpenAPIRouter(logger *zap.Logger) routers.Router {
+	if !cfg.OpenAPI.ValidateRequests && cfg.OpenAPI.ValidateResponses == "" {
+		return nil
+	}
+
+	// Validate has loaded it already.
+	r, err := middlewares.NewOpenAPIRouter(docs.Swagger)
+	if err != nil {
+		logger.Error("failed to load openapi description", zap.Error(err))
+		return nil
+	}
+
+	return r
+}
+
+func (cfg *Config) newRPCProxy(logger *zap.Logger, upstream grpc.ClientConnInterface) *rpcproxy.Proxy {
+	if len(cfg.RPC.Procedures) == 0 {
+		return nil
+	}
+
+	p, err := rpcproxy.New(&rpcproxy.Config{Conn: upstream, Procedures: cfg.RPC.Procedures})
+	if err != nil {
+		logger.Error("failed to init rpc proxy, procedures not served", zap.Error(err))
+		return nil
+	}
 
-	r := routes.New(&routes.Config{
-		Logger:     logger,
-		Handler:    h,
-		Middleware: m,
+	return p
+}
+
+// newCrashSinks leaves out the directory it cannot create, logging why.
+func (cfg *Config) newCrashSinks(logger *zap.Logger) []crash.Sink {
+	var sinks []crash.Sink
+	if cfg.Crash.ReportsDir != "" {
+		sink, err := crash.NewDirSink(cfg.Crash.ReportsDir)
+		if err != nil {
+			logger.Error("failed to create crash reports directory, reports not written", zap.Error(err))
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+	if cfg.Crash.ReportsURL != "" {
+		sinks = append(sinks, crash.NewHTTPSink(&crash.HTTPSinkConfig{
+			URL:     cfg.Crash.ReportsURL,
+			Timeout: cfg.Crash.ReportsTimeout,
+		}))
+	}
+
+	return sinks
+}
+
+func (cfg *Config) newIdentitySigner(logger *zap.Logger) *identity.Signer {
+	if cfg.Identity.SigningKey == "" {
+		return nil
+	}
+
+	key, err := identity.ParsePrivateKey(cfg.Identity.SigningKey)
+	if err != nil {
+		logger.Error("failed to parse identity signing key, identity tokens disabled", zap.Error(err))
+		return nil
+	}
+	s := identity.NewSigner(key, cfg.Identity.TokenTTL)
+	logger.Info("identity tokens enabled",
+		zap.String("public_key", base64.StdEncoding.EncodeToString(s.PublicKey())))
+
+	return s
+}
+
+func (cfg *Config) newWebAuthn(logger *zap.Logger) *webauthn.WebAuthn {
+	if cfg.WebAuthn.RPID == "" {
+		return nil
+	}
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthn.RPID,
+		RPDisplayName: cfg.WebAuthn.RPDisplayName,
+		RPOrigins:     cfg.WebAuthn.RPOrigins,
 	})
+	if err != nil {
+		logger.Error("failed to init webauthn, passkeys disabled", zap.Error(err))
+		return nil
+	}
+
+	return w
+}
+
+// newCSRFSecret falls back to a random secret, which works for a single
+// instance but invalidates issued tokens on every restart.
+func (cfg *Config) newCSRFSecret(logger *zap.Logger) []byte {
+	if cfg.CSRF.Secret != "" {
+		return []byte(cfg.CSRF.Secret)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		logger.Fatal("failed to generate csrf secret", zap.Error(err))
+	}
+	logger.Warn("CSRF_SECRET is not set, using a random secret")
+
+	return secret
+}
+
+// newTokenPepper falls back to a random pepper like newCSRFSecret, which
+// logs every user out on restart and breaks sessions across replicas, as the
+// memory backend does anyway.
+func (cfg *Config) newTokenPepper(logger *zap.Logger) []byte {
+	if cfg.TokenStore.Key != store.TokenKeyHMAC {
+		return nil
+	}
+	if cfg.TokenStore.Pepper != "" {
+		return []byte(cfg.TokenStore.Pepper)
+	}
 
-	s := server.New(&server.Config{
-		Port:   cfg.Port,
-		Logger: logger,
-		Router: r,
+	pepper := make([]byte, 32)
+	if _, err := rand.Read(pepper); err != nil {
+		logger.Fatal("failed to generate token pepper", zap.Error(err))
+	}
+	if cfg.TokenStore.Backend != "memory" {
+		logger.Warn("TOKEN_STORE_PEPPER is not set, using a random pepper")
+	}
+
+	return pepper
+}
+
+func (cfg *Config) degradedJWTSecret() []byte {
+	if cfg.Redis.Fallback != RedisFallbackVerify {
+		return nil
+	}
+
+	return []byte(cfg.AuthService.AccessSecret)
+}
+
+// oauthClients parses OAuth.Clients, checked by Validate.
+func (cfg *Config) oauthClients() map[string]string {
+	clients := make(map[string]string, len(cfg.OAuth.Clients))
+	for _, pair := range cfg.OAuth.Clients {
+		id, secret, _ := strings.Cut(pair, "=")
+		clients[id] = secret
+	}
+
+	return clients
+}
+
+// oauthClientScopes grants ClientScopeRevoke to OAuth.RevokeClients.
+func (cfg *Config) oauthClientScopes() map[string][]string {
+	scopes := make(map[string][]string, len(cfg.OAuth.RevokeClients))
+	for _, id := range cfg.OAuth.RevokeClients {
+		scopes[id] = append(scopes[id], middlewares.ClientScopeRevoke)
+	}
+
+	return scopes
+}
+
+func (cfg *Config) newTenantResolver() *tenant.Resolver {
+	hosts := make(map[string]string, len(cfg.Tenant.Hosts))
+	for _, pair := range cfg.Tenant.Hosts {
+		host, name, _ := strings.Cut(pair, "=")
+		hosts[strings.ToLower(host)] = name
+	}
+
+	return tenant.NewResolver(&tenant.Config{
+		Hosts: hosts,
+		Claim: cfg.Tenant.Claim,
 	})
+}
 
-	return &Services{
-		Server: s,
+// routeLimits parses the HTTP limits, checked by Validate.
+func (cfg *Config) routeLimits() routes.Limits {
+	limits := routes.Limits{
+		MaxBodyBytes:      cfg.HTTP.MaxBodyBytes,
+		Timeout:           cfg.HTTP.HandlerTimeout,
+		RouteMaxBodyBytes: make(map[string]int64),
+		RouteTimeouts:     make(map[string]time.Duration),
+		RateLimits:        make(map[string]routes.RateLimit),
 	}
+	for _, pair := range cfg.HTTP.RouteMaxBodyBytes {
+		pattern, v, _ := strings.Cut(pair, "=")
+		limits.RouteMaxBodyBytes[pattern], _ = strconv.ParseInt(v, 10, 64)
+	}
+	for _, pair := range cfg.HTTP.RouteTimeouts {
+		pattern, v, _ := strings.Cut(pair, "=")
+		limits.RouteTimeouts[pattern], _ = time.ParseDuration(v)
+	}
+	for _, pair := range cfg.HTTP.RateLimits {
+		class, v, _ := strings.Cut(pair, "=")
+		limits.RateLimits[class], _ = parseRateLimit(v)
+	}
+
+	return limits
+}
+
+func (cfg *Config) deprecations() map[string]routes.Deprecation {
+	deprecations := make(map[string]routes.Deprecation)
+	for _, pair := range cfg.API.Deprecations {
+		key, v, _ := strings.Cut(pair, "=")
+		date, _ := time.Parse(time.DateOnly, v)
+		deprecations[key] = routes.Deprecation{Date: date, Link: cfg.API.DeprecationLink}
+	}
+	for _, pair := range cfg.API.Sunsets {
+		key, v, _ := strings.Cut(pair, "=")
+		d := deprecations[key]
+		d.Sunset, _ = time.Parse(time.DateOnly, v)
+		deprecations[key] = d
+	}
+
+	return deprecations
 }
@@ -0,0 +1,441 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"workmap/gateway/internal/routes"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoad_Layers(t *testing.T) {
+	file := writeFile(t, "gateway.yaml", `
+PORT: 5000
+REDIS_HOST: file-redis
+AUTH_SERVICE_HOST: file-auth
+SHUTDOWN_TIMEOUT: 10s
+CSRF_TRUSTED_ORIGINS:
+  - http://localhost:3000
+  - https://work-map.test
+`)
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		expected func(cfg *Config)
+	}{
+		{
+			name: "defaults and file",
+			args: []string{"--config", file},
+			expected: func(cfg *Config) {
+				assert.Equal(t, "5000", cfg.Port)
+				assert.Equal(t, "6379", cfg.Redis.Port)
+				assert.Equal(t, "file-redis", cfg.Redis.Host)
+				assert.Equal(t, 10*time.Second, cfg.ShutdownTimeout)
+				assert.Equal(t, []string{"http://localhost:3000", "https://work-map.test"}, cfg.CSRF.TrustedOrigins)
+			},
+		},
+		{
+			name: "env overrides file",
+			env: map[string]string{
+				"REDIS_HOST":           "env-redis",
+				"CSRF_TRUSTED_ORIGINS": "https://a.test, ,https://b.test",
+			},
+			args: []string{"--config", file},
+			expected: func(cfg *Config) {
+				assert.Equal(t, "env-redis", cfg.Redis.Host)
+				assert.Equal(t, "file-auth", cfg.AuthService.Host)
+				assert.Equal(t, []string{"https://a.test", "https://b.test"}, cfg.CSRF.TrustedOrigins)
+			},
+		},
+		{
+			name: "flags override env",
+			env:  map[string]string{"REDIS_HOST": "env-redis", "CONFIG_FILE": file},
+			args: []string{"--redis-host", "flag-redis", "--shutdown-timeout", "1m"},
+			expected: func(cfg *Config) {
+				assert.Equal(t, "flag-redis", cfg.Redis.Host)
+				assert.Equal(t, time.Minute, cfg.ShutdownTimeout)
+				assert.Equal(t, "5000", cfg.Port)
+			},
+		},
+		{
+			name: "no config file",
+			env:  map[string]string{"REDIS_HOST": "env-redis"},
+			expected: func(cfg *Config) {
+				assert.Equal(t, "4001", cfg.Port)
+				assert.Equal(t, "env-redis", cfg.Redis.Host)
+				assert.Equal(t, 5*time.Second, cfg.ShutdownTimeout)
+				assert.Empty(t, cfg.CSRF.TrustedOrigins)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, printConfig, err := Load(tt.args)
+			require.NoError(t, err)
+			assert.False(t, printConfig)
+			tt.expected(cfg)
+		})
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	file := writeFile(t, "gateway.toml", `
+PORT = "5001"
+WEBAUTHN_RP_ORIGINS = ["https://work-map.test"]
+`)
+
+	cfg, _, err := Load([]string{"--config", file})
+	require.NoError(t, err)
+	assert.Equal(t, "5001", cfg.Port)
+	assert.Equal(t, []string{"https://work-map.test"}, cfg.WebAuthn.RPOrigins)
+}
+
+func TestLoad_SecretFiles(t *testing.T) {
+	t.Run("read from file", func(t *testing.T) {
+		t.Setenv("REDIS_PASSWORD_FILE", writeFile(t, "redis_password", "s3cret\n"))
+
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", cfg.Redis.Password)
+	})
+
+	t.Run("flag takes precedence", func(t *testing.T) {
+		t.Setenv("REDIS_PASSWORD_FILE", writeFile(t, "redis_password", "s3cret\n"))
+
+		cfg, _, err := Load([]string{"--redis-password", "from-flag"})
+		require.NoError(t, err)
+		assert.Equal(t, "from-flag", cfg.Redis.Password)
+	})
+
+	t.Run("both set", func(t *testing.T) {
+		t.Setenv("REDIS_PASSWORD", "from-env")
+		t.Setenv("REDIS_PASSWORD_FILE", writeFile(t, "redis_password", "s3cret\n"))
+
+		_, _, err := Load(nil)
+		assert.ErrorContains(t, err, "REDIS_PASSWORD and REDIS_PASSWORD_FILE are both set")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("CSRF_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+
+		_, _, err := Load(nil)
+		assert.ErrorContains(t, err, "CSRF_SECRET_FILE")
+	})
+}
+
+func TestLoad_PrintConfig(t *testing.T) {
+	_, printConfig, err := Load([]string{"--print-config"})
+	require.NoError(t, err)
+	assert.True(t, printConfig)
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Port:            "4001",
+			ShutdownTimeout: 5 * time.Second,
+			Environment:     "production",
+			AuthService:     AuthService{Host: "auth", Port: "8080"},
+			Redis:           Redis{Mode: "standalone", Host: "gateway-redis", Port: "6379", Fallback: "none", HealthInterval: time.Second},
+			TokenStore:      TokenStore{Backend: "redis", Key: "hmac"},
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, valid().Validate())
+	})
+
+	t.Run("redis sentinel", func(t *testing.T) {
+		cfg := valid()
+		cfg.Redis.Mode = "sentinel"
+		cfg.Redis.Host, cfg.Redis.Port = "", ""
+
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "REDIS_ADDRS is required in sentinel mode")
+		assert.ErrorContains(t, err, "REDIS_SENTINEL_MASTER is required in sentinel mode")
+
+		cfg.Redis.Addrs = []string{"sentinel:26379"}
+		cfg.Redis.SentinelMaster = "mymaster"
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("redis fallback", func(t *testing.T) {
+		cfg := valid()
+		cfg.Redis.Fallback = "verify"
+		assert.EqualError(t, cfg.Validate(), "JWT_ACCESS_SECRET_KEY is required when REDIS_FALLBACK is verify")
+
+		cfg.AuthService.AccessSecret = "access-secret"
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Redis.Fallback = "open"
+		assert.EqualError(t, cfg.Validate(), `REDIS_FALLBACK must be none, reconnect or verify, got "open"`)
+	})
+
+	t.Run("redis database", func(t *testing.T) {
+		cfg := valid()
+		cfg.Redis.DB = -1
+		assert.EqualError(t, cfg.Validate(), "REDIS_DB must not be negative")
+
+		cfg.Redis.DB = 2
+		cfg.Redis.Mode = "cluster"
+		cfg.Redis.Addrs = []string{"redis-1:6379"}
+		assert.EqualError(t, cfg.Validate(), "REDIS_DB must be 0 in cluster mode")
+	})
+
+	t.Run("tenants", func(t *testing.T) {
+		cfg := valid()
+		cfg.Tenant.Hosts = []string{"acme.work-map.test=acme"}
+		cfg.Tenant.Claim = "tenant"
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Tenant.Hosts = []string{"acme.work-map.test=Acme Corp"}
+		assert.EqualError(t, cfg.Validate(), `TENANT_HOSTS must be host=tenant pairs with lowercase tenants, got "acme.work-map.test=Acme Corp"`)
+
+		cfg.Tenant.Hosts = nil
+		cfg.TokenStore = TokenStore{Backend: "memory", MemoryShards: 16, Key: "hmac"}
+		assert.EqualError(t, cfg.Validate(), "TENANT_HOSTS and TENANT_CLAIM need the redis token store")
+	})
+
+	t.Run("http limits", func(t *testing.T) {
+		cfg := valid()
+		cfg.HTTP.WriteTimeout = 15 * time.Second
+		cfg.HTTP.HandlerTimeout = 10 * time.Second
+		cfg.HTTP.RouteMaxBodyBytes = []string{"POST /user/passkey/register/finish=262144"}
+		cfg.HTTP.RouteTimeouts = []string{"GET /auth/{provider}/callback=12s"}
+		cfg.HTTP.RateLimits = []string{"auth=20/1m"}
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, routes.RateLimit{Requests: 20, Per: time.Minute}, cfg.routeLimits().RateLimits["auth"])
+
+		cfg.HTTP.RouteTimeouts = []string{"GET /auth/{provider}/callback=0"}
+		assert.EqualError(t, cfg.Validate(), "HTTP_WRITE_TIMEOUT must be longer than the handler timeouts")
+
+		cfg.HTTP.RouteTimeouts = []string{"/user/login=5s"}
+		cfg.HTTP.RouteMaxBodyBytes = []string{"POST /user/login=1k"}
+		cfg.HTTP.RateLimits = []string{"auth=20", "auth=0/1m"}
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, `HTTP_ROUTE_MAX_BODY_BYTES must be pattern=bytes pairs, got "POST /user/login=1k"`)
+		assert.ErrorContains(t, err, `HTTP_ROUTE_TIMEOUTS must be pattern=duration pairs, got "/user/login=5s"`)
+		assert.ErrorContains(t, err, `HTTP_RATE_LIMITS must be class=requests/window pairs, got "auth=20"`)
+		assert.ErrorContains(t, err, `HTTP_RATE_LIMITS must be class=requests/window pairs, got "auth=0/1m"`)
+	})
+
+	t.Run("compression", func(t *testing.T) {
+		cfg := valid()
+		cfg.Compression.Encodings = []string{"zstd", "gzip"}
+		cfg.Compression.ContentTypes = []string{"application/json", "text/"}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Compression.Encodings = []string{"gzip", "deflate", "gzip"}
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, `COMPRESSION_ENCODINGS must be some of br, zstd, gzip, got "deflate"`)
+		assert.ErrorContains(t, err, `COMPRESSION_ENCODINGS must be some of br, zstd, gzip, got "gzip"`)
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		cfg := valid()
+		cfg.Environment = "staging"
+		assert.EqualError(t, cfg.Validate(), `ENVIRONMENT must be production, development or test, got "staging"`)
+	})
+
+	t.Run("openapi validation", func(t *testing.T) {
+		cfg := valid()
+		cfg.OpenAPI = OpenAPI{ValidateRequests: true, ValidateResponses: "log"}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.OpenAPI.ValidateResponses = "fail"
+		assert.EqualError(t, cfg.Validate(), "OPENAPI_VALIDATE_RESPONSES=fail is only allowed in development and test")
+
+		cfg.Environment = "test"
+		assert.NoError(t, cfg.Validate())
+
+		cfg.OpenAPI.ValidateResponses = "panic"
+		assert.EqualError(t, cfg.Validate(), `OPENAPI_VALIDATE_RESPONSES must be empty, log or fail, got "panic"`)
+	})
+
+	t.Run("rpc procedures", func(t *testing.T) {
+		cfg := valid()
+		cfg.RPC.Procedures = []string{"/auth.AuthService/Register", "/auth.AuthService/Login"}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.RPC.Procedures = []string{"/auth.AuthService/Signup"}
+		assert.EqualError(t, cfg.Validate(), `RPC_PROCEDURES must be browser-safe unary procedures of backend/proto: procedure "/auth.AuthService/Signup": auth.AuthService has no method Signup`)
+
+		cfg.RPC.Procedures = []string{"/auth.AuthService/Login", "/auth.AuthService/GetMfa"}
+		assert.EqualError(t, cfg.Validate(), `RPC_PROCEDURES must be browser-safe unary procedures of backend/proto: procedure "/auth.AuthService/GetMfa" is not browser-safe`)
+	})
+
+	t.Run("oauth clients", func(t *testing.T) {
+		cfg := valid()
+		cfg.OAuth.Clients = []string{"billing=s3cret", "search=0ther=="}
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, map[string]string{"billing": "s3cret", "search": "0ther=="}, cfg.oauthClients())
+
+		cfg.OAuth.Clients = []string{"billing"}
+		assert.EqualError(t, cfg.Validate(), "OAUTH_CLIENTS must be id=secret pairs")
+
+		cfg.OAuth.Clients = []string{"billing=s3cret", "billing=0ther"}
+		assert.EqualError(t, cfg.Validate(), `OAUTH_CLIENTS has client "billing" twice`)
+
+		cfg.OAuth.Clients = []string{"billing=s3cret"}
+		cfg.OAuth.RevokeClients = []string{"billing"}
+		assert.NoError(t, cfg.Validate())
+		assert.Equal(t, map[string][]string{"billing": {"revoke"}}, cfg.oauthClientScopes())
+
+		cfg.OAuth.RevokeClients = []string{"search"}
+		assert.EqualError(t, cfg.Validate(), `OAUTH_REVOKE_CLIENTS must be clients of OAUTH_CLIENTS, got "search"`)
+	})
+
+	t.Run("grpc port", func(t *testing.T) {
+		cfg := valid()
+		cfg.GRPC.Port = "4002"
+		assert.EqualError(t, cfg.Validate(), "GRPC_CLIENT_TOKEN is required with GRPC_PORT")
+
+		cfg.GRPC.ClientToken = "s3cret"
+		assert.NoError(t, cfg.Validate())
+
+		cfg.GRPC.Port = cfg.Port
+		assert.EqualError(t, cfg.Validate(), "GRPC_PORT must differ from PORT")
+
+		cfg.GRPC.Port = "grpc"
+		assert.EqualError(t, cfg.Validate(), `GRPC_PORT must be a port number, got "grpc"`)
+	})
+
+	t.Run("metrics port", func(t *testing.T) {
+		cfg := valid()
+		cfg.Metrics.Port = "9090"
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Metrics.Port = cfg.Port
+		assert.EqualError(t, cfg.Validate(), "METRICS_PORT must differ from PORT and GRPC_PORT")
+
+		cfg.Metrics.Port = "metrics"
+		assert.EqualError(t, cfg.Validate(), `METRICS_PORT must be a port number, got "metrics"`)
+	})
+
+	t.Run("identity tokens", func(t *testing.T) {
+		cfg := valid()
+		cfg.Identity = Identity{SigningKey: base64.StdEncoding.EncodeToString(make([]byte, 32)), TokenTTL: time.Minute}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Identity.TokenTTL = 0
+		assert.EqualError(t, cfg.Validate(), "IDENTITY_TOKEN_TTL must be positive")
+
+		cfg.Identity = Identity{SigningKey: "c2VlZA==", TokenTTL: time.Minute}
+		assert.EqualError(t, cfg.Validate(), "IDENTITY_SIGNING_KEY must be the base64 of an Ed25519 seed: ed25519 seed is 4 bytes, not 32")
+	})
+
+	t.Run("crash reports", func(t *testing.T) {
+		cfg := valid()
+		cfg.Crash = Crash{ReportsDir: "/var/crash/gateway", ReportsURL: "https://crash.example.com/api/reports", ReportsTimeout: 5 * time.Second}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.Crash = Crash{ReportsURL: "crash.example.com"}
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, "CRASH_REPORTS_URL must be an absolute URL")
+		assert.ErrorContains(t, err, "CRASH_REPORTS_TIMEOUT must be positive")
+	})
+
+	t.Run("api deprecations", func(t *testing.T) {
+		cfg := valid()
+		cfg.API = API{
+			Deprecations:    []string{"v1=2026-10-01", "unversioned=2026-10-01", "GET /v1/user/profile=2026-11-01"},
+			Sunsets:         []string{"unversioned=2027-04-01"},
+			DeprecationLink: "https://docs.example.com/api/v2",
+		}
+		assert.NoError(t, cfg.Validate())
+
+		cfg.API = API{
+			Deprecations:    []string{"v1=October", "beta=2026-10-01"},
+			Sunsets:         []string{"v1=2026-01-01", "v2=2027-04-01"},
+			DeprecationLink: "/docs",
+		}
+		err := cfg.Validate()
+		assert.ErrorContains(t, err, `API_DEPRECATIONS must be route=date pairs like v1=2026-10-01, got "v1=October"`)
+		assert.ErrorContains(t, err, `API_DEPRECATIONS must be route=date pairs like v1=2026-10-01, got "beta=2026-10-01"`)
+		assert.ErrorContains(t, err, `API_SUNSETS must follow the API_DEPRECATIONS of the same route, got "v1=2026-01-01"`)
+		assert.ErrorContains(t, err, `API_SUNSETS must follow the API_DEPRECATIONS of the same route, got "v2=2027-04-01"`)
+		assert.ErrorContains(t, err, `API_DEPRECATION_LINK must be an absolute URL, got "/docs"`)
+	})
+
+	t.Run("token store", func(t *testing.T) {
+		cfg := valid()
+		cfg.TokenStore = TokenStore{Backend: "postgres", Key: "jti"}
+		assert.EqualError(t, cfg.Validate(), "TOKEN_STORE_POSTGRES_DSN is required by the postgres token store")
+
+		cfg.TokenStore = TokenStore{Backend: "etcd", Key: "hmac"}
+		assert.EqualError(t, cfg.Validate(), `TOKEN_STORE must be one of memory, postgres, redis, got "etcd"`)
+
+		cfg.TokenStore = TokenStore{Backend: "memory", MemoryShards: 16, Key: "sha1"}
+		assert.EqualError(t, cfg.Validate(), `TOKEN_STORE_KEY must be hmac or jti, got "sha1"`)
+	})
+
+	t.Run("reports every field", func(t *testing.T) {
+		cfg := valid()
+		cfg.Port = "port"
+		cfg.ShutdownTimeout = 0
+		cfg.ShutdownDrain = -time.Second
+		cfg.Redis.Host = ""
+		cfg.AuthService.Port = "70000"
+		cfg.OAuth.GitHubClientID = "id"
+		cfg.WebAuthn.RPID = "localhost"
+		cfg.CSRF.TrustedOrigins = []string{"localhost:3000"}
+		cfg.CSRF.ExemptPaths = []string{"webhook"}
+
+		err := cfg.Validate()
+		require.Error(t, err)
+		for _, msg := range []string{
+			`PORT must be a port number, got "port"`,
+			"SHUTDOWN_TIMEOUT must be positive",
+			"SHUTDOWN_DRAIN_PERIOD must not be negative",
+			"REDIS_HOST is required",
+			`AUTH_SERVICE_PORT must be a port number, got "70000"`,
+			"OAUTH_GITHUB_CLIENT_SECRET is required",
+			"OAUTH_REDIRECT_BASE_URL must be an absolute URL",
+			"OAUTH_FRONTEND_URL must be an absolute URL",
+			"WEBAUTHN_RP_ORIGINS is required",
+			`CSRF_TRUSTED_ORIGINS must be origins like https://example.com, got "localhost:3000"`,
+			`CSRF_EXEMPT_PATHS must be absolute paths, got "webhook"`,
+		} {
+			assert.ErrorContains(t, err, msg)
+		}
+	})
+}
+
+func TestPrint(t *testing.T) {
+	cfg := &Config{
+		Port:            "4001",
+		ShutdownTimeout: 5 * time.Second,
+		Redis:           Redis{Host: "gateway-redis", Password: "password"},
+		CSRF:            CSRF{TrustedOrigins: []string{"https://a.test", "https://b.test"}},
+	}
+
+	var buf bytes.Buffer
+	cfg.Print(&buf)
+
+	out := buf.String()
+	assert.Contains(t, out, "PORT = 4001\n")
+	assert.Contains(t, out, "SHUTDOWN_TIMEOUT = 5s\n")
+	assert.Contains(t, out, "LOG_LEVEL = info\n")
+	assert.Contains(t, out, "REDIS_PASSWORD = [REDACTED]\n")
+	assert.Contains(t, out, "CSRF_SECRET = \n")
+	assert.Contains(t, out, "CSRF_TRUSTED_ORIGINS = https://a.test,https://b.test\n")
+	assert.NotContains(t, out, "password")
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// field is a config key found by walking the mapstructure tags of Config.
+type field struct {
+	key     string
+	secret  bool
+	restart bool
+}
+
+// setting is a field with its value formatted as in the environment.
+type setting struct {
+	field
+	value string
+}
+
+// Load builds the config from the built-in defaults, a config file, the
+// environment and the command-line flags in args, each layer overriding the
+// previous one. The config file is the one given by --config or CONFIG_FILE,
+// its format taken from the extension (.yaml, .toml or .env), otherwise an
+// optional .env in the working directory or /app. It also reports whether
+// --print-config was given. The result is not validated.
+func Load(args []string) (*Config, bool, error) {
+	v := viper.New()
+	fields := configFields()
+
+	flags := pflag.NewFlagSet("gateway", pflag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML, TOML or .env config file")
+	printConfig := flags.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+	for _, f := range fields {
+		flags.String(flagName(f.key), "", "overrides "+f.key)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	for _, f := range fields {
+		if d, ok := defaults[f.key]; ok {
+			v.SetDefault(f.key, d)
+		}
+		if err := v.BindEnv(f.key); err != nil {
+			return nil, false, err
+		}
+		if err := v.BindPFlag(f.key, flags.Lookup(flagName(f.key))); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := readConfigFile(v, *configFile); err != nil {
+		return nil, false, err
+	}
+
+	if err := readSecretFiles(v, fields, flags); err != nil {
+		return nil, false, err
+	}
+
+	var cfg Config
+	err := v.Unmarshal(&cfg, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.TextUnmarshallerHookFunc(),
+		stringToListHook,
+	)))
+	if err != nil {
+		return nil, false, err
+	}
+	cfg.args = args
+	cfg.file = v.ConfigFileUsed()
+
+	return &cfg, *printConfig, nil
+}
+
+func readConfigFile(v *viper.Viper, path string) error {
+	if path != "" {
+		v.SetConfigFile(path)
+
+		return v.ReadInConfig()
+	}
+
+	v.SetConfigType("env")
+	v.AddConfigPath(".")    // path for local development
+	v.AddConfigPath("/app") // path for container
+	v.SetConfigName(".env")
+
+	err := v.ReadInConfig()
+	if errors.As(err, &viper.ConfigFileNotFoundError{}) {
+		return nil
+	}
+
+	return err
+}
+
+// readSecretFiles follows the Docker secrets convention: <KEY>_FILE names a
+// file holding the value of KEY. A flag still takes precedence over it.
+func readSecretFiles(v *viper.Viper, fields []field, flags *pflag.FlagSet) error {
+	var errs []error
+	for _, f := range fields {
+		path := os.Getenv(f.key + "_FILE")
+		if path == "" || flags.Changed(flagName(f.key)) {
+			continue
+		}
+
+		if os.Getenv(f.key) != "" {
+			errs = append(errs, fmt.Errorf("%s and %s_FILE are both set", f.key, f.key))
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s_FILE: %w", f.key, err))
+			continue
+		}
+
+		v.Set(f.key, strings.TrimRight(string(data), "\r\n"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// stringToListHook decodes a comma separated value, as lists are written in
+// the environment and flags, trimming spaces and dropping empty items.
+func stringToListHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf([]string{}) {
+		return data, nil
+	}
+
+	var list []string
+	for _, s := range strings.Split(data.(string), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+
+	return list, nil
+}
+
+// Print writes the config in the .env format, one KEY = value per line in
+// field order, with secrets redacted.
+func (cfg *Config) Print(w io.Writer) {
+	for _, s := range cfg.settings() {
+		fmt.Fprintf(w, "%s = %s\n", s.key, s.redacted())
+	}
+}
+
+func (cfg *Config) settings() []setting {
+	var settings []setting
+	walkFields(reflect.ValueOf(cfg).Elem(), func(f field, v reflect.Value) {
+		value := fmt.Sprint(v.Interface())
+		if list, ok := v.Interface().([]string); ok {
+			value = strings.Join(list, ",")
+		}
+
+		settings = append(settings, setting{field: f, value: value})
+	})
+
+	return settings
+}
+
+func (s setting) redacted() string {
+	if s.secret && s.value != "" {
+		return redacted
+	}
+
+	return s.value
+}
+
+// keepRestartFields copies the fields tagged restart from old.
+func (cfg *Config) keepRestartFields(old *Config) {
+	var values []reflect.Value
+	walkFields(reflect.ValueOf(old).Elem(), func(_ field, v reflect.Value) {
+		values = append(values, v)
+	})
+
+	var i int
+	walkFields(reflect.ValueOf(cfg).Elem(), func(f field, v reflect.Value) {
+		if f.restart {
+			v.Set(values[i])
+		}
+		i++
+	})
+}
+
+func configFields() []field {
+	var fields []field
+	walkFields(reflect.ValueOf(&Config{}).Elem(), func(f field, _ reflect.Value) {
+		fields = append(fields, f)
+	})
+
+	return fields
+}
+
+// walkFields calls fn for every config key of the struct v in field order,
+// descending into squashed structs and skipping unexported fields.
+func walkFields(v reflect.Value, fn func(f field, v reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag := sf.Tag.Get("mapstructure")
+		if tag == ",squash" {
+			walkFields(v.Field(i), fn)
+			continue
+		}
+
+		fn(field{
+			key:     tag,
+			secret:  sf.Tag.Get("secret") == "true",
+			restart: sf.Tag.Get("restart") == "true",
+		}, v.Field(i))
+	}
+}
+
+// flagName turns a key like REDIS_HOST into the flag name redis-host.
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Change is a config key whose value differs between two configs, with
+// secrets redacted.
+type Change struct {
+	Key     string
+	Old     string
+	New     string
+	Restart bool
+}
+
+// Diff returns the keys that differ from old to cfg in field order.
+func (cfg *Config) Diff(old *Config) []Change {
+	var changes []Change
+	prev := old.settings()
+	for i, s := range cfg.settings() {
+		if s.value == prev[i].value {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Key:     s.key,
+			Old:     prev[i].redacted(),
+			New:     s.redacted(),
+			Restart: s.restart,
+		})
+	}
+
+	return changes
+}
+
+// Reload loads the config again from the same arguments, file and
+// environment and, if it is valid, applies it: the log level is changed and a
+// new route table, with its handlers and middleware policies, replaces the
+// current one. Requests in flight finish on the old route table. Changes to
+// keys tagged restart are only logged.
+func (s *Services) Reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, _, err := Load(s.cfg.args)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		s.logger.Error("config reload failed, keeping the current config", zap.Error(err))
+		return
+	}
+
+	changes := cfg.Diff(s.cfg)
+	if len(changes) == 0 {
+		s.logger.Info("config reloaded, nothing changed")
+		return
+	}
+
+	for _, c := range changes {
+		if c.Restart {
+			s.logger.Warn("config change needs a restart to apply", zap.String("key", c.Key), zap.String("old", c.Old), zap.String("new", c.New))
+			continue
+		}
+		s.logger.Info("config changed", zap.String("key", c.Key), zap.String("old", c.Old), zap.String("new", c.New))
+	}
+
+	// The connections keep the settings they were opened with, so keep them
+	// in the config too to warn again only if they change once more.
+	cfg.keepRestartFields(s.cfg)
+
+	if cfg.CSRF.Secret != s.cfg.CSRF.Secret {
+		s.csrfSecret = cfg.newCSRFSecret(s.logger)
+	}
+
+	s.level.SetLevel(cfg.LogLevel)
+	s.Server.SetRouter(s.newRouter(cfg))
+	s.cfg = cfg
+
+	s.logger.Info("config reloaded", zap.Int("changes", len(changes)))
+}
+
+// WatchConfigFile reloads the config whenever the config file it was loaded
+// from changes.
+func (s *Services) WatchConfigFile() {
+	if s.cfg.file == "" {
+		s.logger.Info("no config file to watch")
+		return
+	}
+
+	v := viper.New()
+	v.SetConfigFile(s.cfg.file)
+	v.OnConfigChange(func(e fsnotify.Event) {
+		s.logger.Info("config file changed", zap.String("file", e.Name))
+		s.Reload()
+	})
+	v.WatchConfig()
+}
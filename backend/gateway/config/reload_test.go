@@ -0,0 +1,79 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"workmap/gateway/internal/server"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Config{Port: "4001", Redis: Redis{Password: "old"}, CORS: CORS{AllowedOrigins: []string{"*"}}}
+	cfg := &Config{Port: "5000", Redis: Redis{Password: "new"}, CORS: CORS{AllowedOrigins: []string{"https://a.test"}}}
+
+	assert.Equal(t, []Change{
+		{Key: "PORT", Old: "4001", New: "5000", Restart: true},
+		{Key: "REDIS_PASSWORD", Old: redacted, New: redacted, Restart: true},
+		{Key: "CORS_ALLOWED_ORIGINS", Old: "*", New: "https://a.test"},
+	}, cfg.Diff(old))
+	assert.Empty(t, cfg.Diff(cfg))
+}
+
+func TestServicesReload(t *testing.T) {
+	file := writeFile(t, "gateway.yaml", `
+AUTH_SERVICE_HOST: auth
+REDIS_HOST: gateway-redis
+LOG_LEVEL: info
+CORS_ALLOWED_ORIGINS: "*"
+`)
+
+	cfg, _, err := Load([]string{"--config", file})
+	require.NoError(t, err)
+
+	level := zap.NewAtomicLevelAt(cfg.LogLevel)
+	s := &Services{logger: zap.NewNop(), level: level, cfg: cfg, csrfSecret: []byte("secret")}
+	s.Server = server.New(&server.Config{Logger: s.logger, Router: s.newRouter(cfg)})
+
+	corsOrigin := func() string {
+		req := httptest.NewRequest(http.MethodOptions, "/user/login", nil)
+		req.Header.Set("Origin", "https://work-map.test")
+		rr := httptest.NewRecorder()
+		s.Server.ServeHTTP(rr, req)
+
+		return rr.Header().Get("Access-Control-Allow-Origin")
+	}
+	assert.Equal(t, "*", corsOrigin())
+
+	t.Run("applies a valid config", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte(`
+AUTH_SERVICE_HOST: auth
+REDIS_HOST: other-redis
+LOG_LEVEL: warn
+CORS_ALLOWED_ORIGINS: https://work-map.test
+`), 0o600))
+
+		s.Reload()
+
+		assert.Equal(t, zapcore.WarnLevel, level.Level())
+		assert.Equal(t, "https://work-map.test", corsOrigin())
+		assert.Equal(t, "gateway-redis", s.cfg.Redis.Host, "restart-only keys keep the running value")
+	})
+
+	t.Run("keeps the config when invalid", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte(`
+AUTH_SERVICE_HOST: auth
+LOG_LEVEL: error
+CORS_ALLOWED_ORIGINS: "*"
+`), 0o600))
+
+		s.Reload()
+
+		assert.Equal(t, zapcore.WarnLevel, level.Level())
+		assert.Equal(t, "https://work-map.test", corsOrigin())
+	})
+}
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"workmap/gateway/docs"
+	"workmap/gateway/internal/middlewares"
+	"workmap/gateway/internal/redis"
+	"workmap/gateway/internal/routes"
+	"workmap/gateway/internal/rpcproxy"
+	"workmap/gateway/internal/tenant"
+	"workmap/gateway/pkg/identity"
+)
+
+// Validate reports every missing or invalid field at once, so that a broken
+// deployment can be fixed in one go.
+func (cfg *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	checkPort := func(key, port string) {
+		if port == "" {
+			check(false, "%s is required", key)
+			return
+		}
+		p, err := strconv.Atoi(port)
+		check(err == nil && p > 0 && p <= 65535, "%s must be a port number, got %q", key, port)
+	}
+
+	checkPort("PORT", cfg.Port)
+	check(cfg.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(cfg.ShutdownDrain >= 0, "SHUTDOWN_DRAIN_PERIOD must not be negative")
+	check(slices.Contains([]string{EnvironmentProduction, EnvironmentDevelopment, EnvironmentTest}, cfg.Environment),
+		"ENVIRONMENT must be %s, %s or %s, got %q", EnvironmentProduction, EnvironmentDevelopment, EnvironmentTest, cfg.Environment)
+
+	check(cfg.HTTP.ReadHeaderTimeout >= 0 && cfg.HTTP.ReadTimeout >= 0 && cfg.HTTP.WriteTimeout >= 0 && cfg.HTTP.IdleTimeout >= 0,
+		"HTTP_READ_HEADER_TIMEOUT, HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT and HTTP_IDLE_TIMEOUT must not be negative")
+	check(cfg.HTTP.MaxHeaderBytes >= 0 && cfg.HTTP.MaxBodyBytes >= 0, "HTTP_MAX_HEADER_BYTES and HTTP_MAX_BODY_BYTES must not be negative")
+	check(cfg.HTTP.HandlerTimeout >= 0, "HTTP_HANDLER_TIMEOUT must not be negative")
+	handlerTimeout := cfg.HTTP.HandlerTimeout
+	for _, pair := range cfg.HTTP.RouteMaxBodyBytes {
+		pattern, v, ok := strings.Cut(pair, "=")
+		n, err := strconv.ParseInt(v, 10, 64)
+		check(ok && validPattern(pattern) && err == nil && n >= 0, "HTTP_ROUTE_MAX_BODY_BYTES must be pattern=bytes pairs, got %q", pair)
+	}
+	for _, pair := range cfg.HTTP.RouteTimeouts {
+		pattern, v, ok := strings.Cut(pair, "=")
+		d, err := time.ParseDuration(v)
+		check(ok && validPattern(pattern) && err == nil && d >= 0, "HTTP_ROUTE_TIMEOUTS must be pattern=duration pairs, got %q", pair)
+		// Zero is no timeout, the longest.
+		if err == nil && handlerTimeout > 0 && (d == 0 || d > handlerTimeout) {
+			handlerTimeout = d
+		}
+	}
+	for _, pair := range cfg.HTTP.RateLimits {
+		class, v, ok := strings.Cut(pair, "=")
+		_, valid := parseRateLimit(v)
+		check(ok && class != "" && valid, "HTTP_RATE_LIMITS must be class=requests/window pairs, got %q", pair)
+	}
+	// The 504 of a handler timing out must be written before the write
+	// timeout closes the connection.
+	check(cfg.HTTP.WriteTimeout == 0 || handlerTimeout > 0 && handlerTimeout < cfg.HTTP.WriteTimeout,
+		"HTTP_WRITE_TIMEOUT must be longer than the handler timeouts")
+
+	encodings := []string{middlewares.EncodingBrotli, middlewares.EncodingZstd, middlewares.EncodingGzip}
+	for i, e := range cfg.Compression.Encodings {
+		check(slices.Contains(encodings, e) && !slices.Contains(cfg.Compression.Encodings[:i], e),
+			"COMPRESSION_ENCODINGS must be some of %s, got %q", strings.Join(encodings, ", "), e)
+	}
+	check(cfg.Compression.MinSize >= 0, "COMPRESSION_MIN_SIZE must not be negative")
+	for _, t := range cfg.Compression.ContentTypes {
+		check(strings.Count(t, "/") == 1, "COMPRESSION_CONTENT_TYPES must be media types like text/ or application/json, got %q", t)
+	}
+
+	switch cfg.OpenAPI.ValidateResponses {
+	case "", middlewares.ResponseValidationLog:
+	case middlewares.ResponseValidationFail:
+		check(cfg.Environment != EnvironmentProduction,
+			"OPENAPI_VALIDATE_RESPONSES=%s is only allowed in %s and %s", middlewares.ResponseValidationFail,
+			EnvironmentDevelopment, EnvironmentTest)
+	default:
+		check(false, "OPENAPI_VALIDATE_RESPONSES must be empty, %s or %s, got %q",
+			middlewares.ResponseValidationLog, middlewares.ResponseValidationFail, cfg.OpenAPI.ValidateResponses)
+	}
+	// Validation must not silently turn off when the embedded description
+	// is broken.
+	if cfg.OpenAPI.ValidateRequests || cfg.OpenAPI.ValidateResponses != "" {
+		_, err := middlewares.NewOpenAPIRouter(docs.Swagger)
+		check(err == nil, "OPENAPI_VALIDATE_REQUESTS and OPENAPI_VALIDATE_RESPONSES need a valid docs/swagger.yaml: %v", err)
+	}
+
+	deprecated := make(map[string]time.Time)
+	for _, pair := range cfg.API.Deprecations {
+		key, v, ok := strings.Cut(pair, "=")
+		date, err := time.Parse(time.DateOnly, v)
+		check(ok && validRouteKey(key) && err == nil, "API_DEPRECATIONS must be route=date pairs like v1=2026-10-01, got %q", pair)
+		if ok && err == nil {
+			deprecated[key] = date
+		}
+	}
+	for _, pair := range cfg.API.Sunsets {
+		key, v, ok := strings.Cut(pair, "=")
+		sunset, err := time.Parse(time.DateOnly, v)
+		check(ok && validRouteKey(key) && err == nil, "API_SUNSETS must be route=date pairs like v1=2027-04-01, got %q", pair)
+		if date, found := deprecated[key]; ok && err == nil {
+			check(found && sunset.After(date), "API_SUNSETS must follow the API_DEPRECATIONS of the same route, got %q", pair)
+		}
+	}
+	if cfg.API.DeprecationLink != "" {
+		check(isOrigin(cfg.API.DeprecationLink, true), "API_DEPRECATION_LINK must be an absolute URL, got %q", cfg.API.DeprecationLink)
+	}
+
+	if len(cfg.RPC.Procedures) > 0 {
+		_, err := rpcproxy.New(&rpcproxy.Config{Procedures: cfg.RPC.Procedures})
+		check(err == nil, "RPC_PROCEDURES must be browser-safe unary procedures of backend/proto: %v", err)
+	}
+
+	if cfg.GRPC.Port != "" {
+		checkPort("GRPC_PORT", cfg.GRPC.Port)
+		check(cfg.GRPC.Port != cfg.Port, "GRPC_PORT must differ from PORT")
+		check(cfg.GRPC.ClientToken != "", "GRPC_CLIENT_TOKEN is required with GRPC_PORT")
+	}
+
+	if cfg.Metrics.Port != "" {
+		checkPort("METRICS_PORT", cfg.Metrics.Port)
+		check(cfg.Metrics.Port != cfg.Port && cfg.Metrics.Port != cfg.GRPC.Port, "METRICS_PORT must differ from PORT and GRPC_PORT")
+	}
+
+	if cfg.Identity.SigningKey != "" {
+		_, err := identity.ParsePrivateKey(cfg.Identity.SigningKey)
+		check(err == nil, "IDENTITY_SIGNING_KEY must be the base64 of an Ed25519 seed: %v", err)
+		check(cfg.Identity.TokenTTL > 0, "IDENTITY_TOKEN_TTL must be positive")
+	}
+
+	if cfg.Crash.ReportsURL != "" {
+		check(isOrigin(cfg.Crash.ReportsURL, true), "CRASH_REPORTS_URL must be an absolute URL")
+		check(cfg.Crash.ReportsTimeout > 0, "CRASH_REPORTS_TIMEOUT must be positive")
+	}
+
+	check(cfg.AuthService.Host != "", "AUTH_SERVICE_HOST is required")
+	checkPort("AUTH_SERVICE_PORT", cfg.AuthService.Port)
+
+	switch cfg.Redis.Mode {
+	case store.RedisModeStandalone:
+		check(cfg.Redis.Host != "", "REDIS_HOST is required")
+		checkPort("REDIS_PORT", cfg.Redis.Port)
+	case store.RedisModeSentinel:
+		check(len(cfg.Redis.Addrs) > 0, "REDIS_ADDRS is required in sentinel mode")
+		check(cfg.Redis.SentinelMaster != "", "REDIS_SENTINEL_MASTER is required in sentinel mode")
+	case store.RedisModeCluster:
+		check(len(cfg.Redis.Addrs) > 0, "REDIS_ADDRS is required in cluster mode")
+		check(cfg.Redis.DB == 0, "REDIS_DB must be 0 in cluster mode")
+	default:
+		check(false, "REDIS_MODE must be %s, %s or %s, got %q",
+			store.RedisModeStandalone, store.RedisModeSentinel, store.RedisModeCluster, cfg.Redis.Mode)
+	}
+
+	check(cfg.Redis.DB >= 0, "REDIS_DB must not be negative")
+
+	switch cfg.Redis.Fallback {
+	case RedisFallbackNone, RedisFallbackReconnect:
+	case RedisFallbackVerify:
+		check(cfg.AuthService.AccessSecret != "", "JWT_ACCESS_SECRET_KEY is required when REDIS_FALLBACK is %s", RedisFallbackVerify)
+	default:
+		check(false, "REDIS_FALLBACK must be %s, %s or %s, got %q",
+			RedisFallbackNone, RedisFallbackReconnect, RedisFallbackVerify, cfg.Redis.Fallback)
+	}
+	check(cfg.Redis.HealthInterval > 0, "REDIS_HEALTH_INTERVAL must be positive")
+
+	backends := store.TokenBackends()
+	check(slices.Contains(backends, cfg.TokenStore.Backend), "TOKEN_STORE must be one of %s, got %q",
+		strings.Join(backends, ", "), cfg.TokenStore.Backend)
+	switch cfg.TokenStore.Backend {
+	case "memory":
+		check(cfg.TokenStore.MemoryShards > 0, "TOKEN_STORE_MEMORY_SHARDS must be positive")
+	case "postgres":
+		check(cfg.TokenStore.PostgresDSN != "", "TOKEN_STORE_POSTGRES_DSN is required by the postgres token store")
+	}
+	check(cfg.TokenStore.Key == store.TokenKeyHMAC || cfg.TokenStore.Key == store.TokenKeyJTI,
+		"TOKEN_STORE_KEY must be %s or %s, got %q", store.TokenKeyHMAC, store.TokenKeyJTI, cfg.TokenStore.Key)
+
+	for _, pair := range cfg.Tenant.Hosts {
+		host, name, ok := strings.Cut(pair, "=")
+		check(ok && host != "" && tenant.Valid(name), "TENANT_HOSTS must be host=tenant pairs with lowercase tenants, got %q", pair)
+	}
+	if len(cfg.Tenant.Hosts) > 0 || cfg.Tenant.Claim != "" {
+		check(cfg.TokenStore.Backend == "redis", "TENANT_HOSTS and TENANT_CLAIM need the redis token store")
+	}
+
+	check(cfg.TokenCache.Size >= 0, "TOKEN_CACHE_SIZE must not be negative")
+	if cfg.TokenCache.Size > 0 {
+		check(cfg.TokenCache.MaxAge > 0, "TOKEN_CACHE_MAX_AGE must be positive")
+		check(cfg.TokenCache.NegativeAge > 0, "TOKEN_CACHE_NEGATIVE_AGE must be positive")
+	}
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		check(origin == "*" || isOrigin(origin, false), "CORS_ALLOWED_ORIGINS must be * or origins like https://example.com, got %q", origin)
+	}
+
+	o := cfg.OAuth
+	providers := []struct {
+		name, id, secret string
+	}{
+		{"GOOGLE", o.GoogleClientID, o.GoogleClientSecret},
+		{"GITHUB", o.GitHubClientID, o.GitHubClientSecret},
+		{"LINKEDIN", o.LinkedInClientID, o.LinkedInClientSecret},
+	}
+	var oauthEnabled bool
+	for _, p := range providers {
+		if p.id == "" {
+			continue
+		}
+		oauthEnabled = true
+		check(p.secret != "", "OAUTH_%s_CLIENT_SECRET is required when OAUTH_%s_CLIENT_ID is set", p.name, p.name)
+	}
+	if oauthEnabled {
+		check(isOrigin(o.RedirectBaseURL, true), "OAUTH_REDIRECT_BASE_URL must be an absolute URL, got %q", o.RedirectBaseURL)
+		check(isOrigin(o.FrontendURL, true), "OAUTH_FRONTEND_URL must be an absolute URL, got %q", o.FrontendURL)
+	}
+	clients := make(map[string]bool)
+	for _, pair := range o.Clients {
+		// The pairs are secret, only the IDs are quoted.
+		id, secret, ok := strings.Cut(pair, "=")
+		check(ok && id != "" && secret != "", "OAUTH_CLIENTS must be id=secret pairs")
+		check(!clients[id], "OAUTH_CLIENTS has client %q twice", id)
+		clients[id] = true
+	}
+	for _, id := range o.RevokeClients {
+		check(clients[id], "OAUTH_REVOKE_CLIENTS must be clients of OAUTH_CLIENTS, got %q", id)
+	}
+
+	if cfg.WebAuthn.RPID != "" {
+		check(len(cfg.WebAuthn.RPOrigins) > 0, "WEBAUTHN_RP_ORIGINS is required when WEBAUTHN_RP_ID is set")
+	}
+	for _, origin := range cfg.WebAuthn.RPOrigins {
+		check(isOrigin(origin, false), "WEBAUTHN_RP_ORIGINS must be origins like https://example.com, got %q", origin)
+	}
+
+	for _, origin := range cfg.CSRF.TrustedOrigins {
+		check(isOrigin(origin, false), "CSRF_TRUSTED_ORIGINS must be origins like https://example.com, got %q", origin)
+	}
+	for _, path := range cfg.CSRF.ExemptPaths {
+		check(len(path) > 0 && path[0] == '/', "CSRF_EXEMPT_PATHS must be absolute paths, got %q", path)
+	}
+
+	return errors.Join(errs...)
+}
+
+// isOrigin reports whether s is a scheme and host, with a path only if
+// withPath is set.
+func isOrigin(s string, withPath bool) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	return withPath || u.Path == "" && u.RawQuery == ""
+}
+
+// validRouteKey reports whether key names routes in the API settings: a
+// version like v1, routes.Unversioned or a route pattern.
+func validRouteKey(key string) bool {
+	if v, ok := strings.CutPrefix(key, "v"); ok {
+		if _, err := strconv.Atoi(v); err == nil {
+			return true
+		}
+	}
+
+	return key == routes.Unversioned || validPattern(key)
+}
+
+// validPattern reports whether pattern is a route pattern like
+// "POST /user/login".
+// parseRateLimit parses a rate limit like "20/1m", both parts positive.
+func parseRateLimit(v string) (routes.RateLimit, bool) {
+	requests, per, ok := strings.Cut(v, "/")
+	n, err := strconv.Atoi(requests)
+	if !ok || err != nil || n <= 0 {
+		return routes.RateLimit{}, false
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return routes.RateLimit{}, false
+	}
+
+	return routes.RateLimit{Requests: n, Per: d}, true
+}
+
+func validPattern(pattern string) bool {
+	method, path, ok := strings.Cut(pattern, " ")
+
+	return ok && method != "" && method == strings.ToUpper(method) && strings.HasPrefix(path, "/")
+}
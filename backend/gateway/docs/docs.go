@@ -0,0 +1,34 @@
+// Package docs embeds the OpenAPI description of the gateway and the page
+// browsing it.
+package docs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"sync"
+)
+
+// Swagger is the OpenAPI description, in YAML.
+//
+//go:embed swagger.yaml
+var Swagger []byte
+
+// UI is a Swagger UI page on the description served at /openapi.json.
+//
+//go:embed index.html
+var UI []byte
+
+var openAPI = sync.OnceValues(func() ([]byte, error) {
+	var spec any
+	if err := yaml.Unmarshal(Swagger, &spec); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(spec)
+})
+
+// OpenAPI returns the OpenAPI description as JSON.
+func OpenAPI() ([]byte, error) {
+	return openAPI()
+}
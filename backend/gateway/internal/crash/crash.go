@@ -0,0 +1,100 @@
+// Package crash sends the reports of the panics recovered while serving
+// requests to a sink, like a directory or an HTTP endpoint.
+package crash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Report describes a panic and the request it happened in.
+type Report struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	// Route is the name of the route, empty outside the router.
+	Route  string `json:"route,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	Panic  string `json:"panic"`
+	Stack  string `json:"stack"`
+}
+
+// Sink receives the reports. Send is called in the background, once the
+// response is done.
+type Sink interface {
+	Send(ctx context.Context, r *Report) error
+}
+
+// DirSink writes each report to a JSON file of its directory, named after
+// its time and request ID.
+type DirSink struct {
+	dir string
+}
+
+// NewDirSink creates dir if needed.
+func NewDirSink(dir string) (*DirSink, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &DirSink{dir: dir}, nil
+}
+
+func (s *DirSink) Send(ctx context.Context, r *Report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.json", r.Time.UTC().Format("20060102T150405.000000000Z"), r.RequestID)
+
+	return os.WriteFile(filepath.Join(s.dir, name), b, 0o600)
+}
+
+type HTTPSinkConfig struct {
+	// URL receives the reports as JSON POST requests.
+	URL     string
+	Timeout time.Duration
+}
+
+// HTTPSink posts each report to an endpoint, like a crash collector.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(cfg *HTTPSinkConfig) *HTTPSink {
+	return &HTTPSink{
+		url:    cfg.URL,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, r *Report) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("crash report refused with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package crash
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testReport() *Report {
+	return &Report{
+		Time:      time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		RequestID: "r1",
+		Method:    http.MethodGet,
+		Path:      "/v1/user/profile",
+		Route:     "user.profile",
+		Panic:     "boom",
+		Stack:     "goroutine 1 [running]:",
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crashes")
+	sink, err := NewDirSink(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Send(context.Background(), testReport()))
+
+	b, err := os.ReadFile(filepath.Join(dir, "20261019T120000.000000000Z-r1.json"))
+	require.NoError(t, err)
+	var got Report
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, *testReport(), got)
+}
+
+func TestHTTPSink(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		expectedErr bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "refused", status: http.StatusInternalServerError, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Report
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			sink := NewHTTPSink(&HTTPSinkConfig{URL: srv.URL, Timeout: time.Second})
+			err := sink.Send(context.Background(), testReport())
+
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, *testReport(), got)
+		})
+	}
+}
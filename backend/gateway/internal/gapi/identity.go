@@ -0,0 +1,41 @@
+package gapi
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"workmap/gateway/internal/principal"
+	"workmap/gateway/internal/requestid"
+	"workmap/gateway/internal/tenant"
+	"workmap/gateway/pkg/identity"
+)
+
+// forwardIdentity mints an identity token for the principal CheckAuth
+// authenticated the request as, if any, and sends it in the metadata of the
+// calls made for the request, proxied ones included.
+func forwardIdentity(signer *identity.Signer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		p, ok := principal.FromContext(ctx)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		id := &identity.Identity{
+			Kind:      p.Kind,
+			UserID:    p.UserID,
+			Email:     p.Email,
+			APIKeyID:  p.APIKeyID,
+			Scopes:    p.Scopes,
+			RequestID: requestid.FromContext(ctx),
+		}
+		id.Tenant, _ = tenant.FromContext(ctx)
+		token, err := signer.Sign(id)
+		if err != nil {
+			return err
+		}
+
+		ctx = metadata.AppendToOutgoingContext(ctx, identity.MetadataKey, token)
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,57 @@
+package gapi
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"testing"
+	"time"
+	"workmap/gateway/internal/principal"
+	"workmap/gateway/internal/requestid"
+	"workmap/gateway/internal/tenant"
+	"workmap/gateway/pkg/identity"
+)
+
+func TestForwardIdentity(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := identity.NewSigner(key, time.Minute)
+	interceptor := forwardIdentity(signer)
+
+	// sent returns the identity token of the call made with ctx.
+	sent := func(ctx context.Context) []string {
+		var tokens []string
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			tokens = md.Get(identity.MetadataKey)
+			return nil
+		}
+		require.NoError(t, interceptor(ctx, "/auth.AuthService/ListPasskeys", nil, nil, nil, invoker))
+		return tokens
+	}
+
+	t.Run("authenticated caller", func(t *testing.T) {
+		ctx := principal.NewContext(context.Background(), &principal.Principal{Kind: principal.KindUser, UserID: "42", Email: "user@email.com"})
+		ctx = requestid.NewContext(ctx, "req-1")
+		ctx = tenant.NewContext(ctx, "acme")
+
+		tokens := sent(ctx)
+
+		require.Len(t, tokens, 1)
+		id, err := identity.NewVerifier(signer.PublicKey()).Verify(tokens[0])
+		require.NoError(t, err)
+		assert.Equal(t, identity.KindUser, id.Kind)
+		assert.Equal(t, "42", id.UserID)
+		assert.Equal(t, "user@email.com", id.Email)
+		assert.Equal(t, "req-1", id.RequestID)
+		assert.Equal(t, "acme", id.Tenant)
+	})
+
+	t.Run("anonymous caller", func(t *testing.T) {
+		assert.Empty(t, sent(context.Background()))
+	})
+}
@@ -19,10 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	AuthService_Register_FullMethodName     = "/auth.AuthService/Register"
-	AuthService_Login_FullMethodName        = "/auth.AuthService/Login"
-	AuthService_Logout_FullMethodName       = "/auth.AuthService/Logout"
-	AuthService_RefreshToken_FullMethodName = "/auth.AuthService/RefreshToken"
+	AuthService_Register_FullMethodName           = "/auth.AuthService/Register"
+	AuthService_Login_FullMethodName              = "/auth.AuthService/Login"
+	AuthService_Logout_FullMethodName             = "/auth.AuthService/Logout"
+	AuthService_RefreshToken_FullMethodName       = "/auth.AuthService/RefreshToken"
+	AuthService_ExternalLogin_FullMethodName      = "/auth.AuthService/ExternalLogin"
+	AuthService_AddPasskey_FullMethodName         = "/auth.AuthService/AddPasskey"
+	AuthService_ListPasskeys_FullMethodName       = "/auth.AuthService/ListPasskeys"
+	AuthService_PasskeyLogin_FullMethodName       = "/auth.AuthService/PasskeyLogin"
+	AuthService_EnableMfa_FullMethodName          = "/auth.AuthService/EnableMfa"
+	AuthService_GetMfa_FullMethodName             = "/auth.AuthService/GetMfa"
+	AuthService_UseRecoveryCode_FullMethodName    = "/auth.AuthService/UseRecoveryCode"
+	AuthService_RevokeRefreshToken_FullMethodName = "/auth.AuthService/RevokeRefreshToken"
 )
 
 // AuthServiceClient is the client API for AuthService service.
@@ -33,6 +41,14 @@ type AuthServiceClient interface {
 	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginReply, error)
 	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutReply, error)
 	RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...grpc.CallOption) (*RefreshTokenReply, error)
+	ExternalLogin(ctx context.Context, in *ExternalLoginRequest, opts ...grpc.CallOption) (*LoginReply, error)
+	AddPasskey(ctx context.Context, in *AddPasskeyRequest, opts ...grpc.CallOption) (*AddPasskeyReply, error)
+	ListPasskeys(ctx context.Context, in *ListPasskeysRequest, opts ...grpc.CallOption) (*ListPasskeysReply, error)
+	PasskeyLogin(ctx context.Context, in *PasskeyLoginRequest, opts ...grpc.CallOption) (*LoginReply, error)
+	EnableMfa(ctx context.Context, in *EnableMfaRequest, opts ...grpc.CallOption) (*EnableMfaReply, error)
+	GetMfa(ctx context.Context, in *GetMfaRequest, opts ...grpc.CallOption) (*GetMfaReply, error)
+	UseRecoveryCode(ctx context.Context, in *UseRecoveryCodeRequest, opts ...grpc.CallOption) (*UseRecoveryCodeReply, error)
+	RevokeRefreshToken(ctx context.Context, in *RevokeRefreshTokenRequest, opts ...grpc.CallOption) (*RevokeRefreshTokenReply, error)
 }
 
 type authServiceClient struct {
@@ -79,6 +95,78 @@ func (c *authServiceClient) RefreshToken(ctx context.Context, in *RefreshTokenRe
 	return out, nil
 }
 
+func (c *authServiceClient) ExternalLogin(ctx context.Context, in *ExternalLoginRequest, opts ...grpc.CallOption) (*LoginReply, error) {
+	out := new(LoginReply)
+	err := c.cc.Invoke(ctx, AuthService_ExternalLogin_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) AddPasskey(ctx context.Context, in *AddPasskeyRequest, opts ...grpc.CallOption) (*AddPasskeyReply, error) {
+	out := new(AddPasskeyReply)
+	err := c.cc.Invoke(ctx, AuthService_AddPasskey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) ListPasskeys(ctx context.Context, in *ListPasskeysRequest, opts ...grpc.CallOption) (*ListPasskeysReply, error) {
+	out := new(ListPasskeysReply)
+	err := c.cc.Invoke(ctx, AuthService_ListPasskeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) PasskeyLogin(ctx context.Context, in *PasskeyLoginRequest, opts ...grpc.CallOption) (*LoginReply, error) {
+	out := new(LoginReply)
+	err := c.cc.Invoke(ctx, AuthService_PasskeyLogin_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) EnableMfa(ctx context.Context, in *EnableMfaRequest, opts ...grpc.CallOption) (*EnableMfaReply, error) {
+	out := new(EnableMfaReply)
+	err := c.cc.Invoke(ctx, AuthService_EnableMfa_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) GetMfa(ctx context.Context, in *GetMfaRequest, opts ...grpc.CallOption) (*GetMfaReply, error) {
+	out := new(GetMfaReply)
+	err := c.cc.Invoke(ctx, AuthService_GetMfa_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) UseRecoveryCode(ctx context.Context, in *UseRecoveryCodeRequest, opts ...grpc.CallOption) (*UseRecoveryCodeReply, error) {
+	out := new(UseRecoveryCodeReply)
+	err := c.cc.Invoke(ctx, AuthService_UseRecoveryCode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) RevokeRefreshToken(ctx context.Context, in *RevokeRefreshTokenRequest, opts ...grpc.CallOption) (*RevokeRefreshTokenReply, error) {
+	out := new(RevokeRefreshTokenReply)
+	err := c.cc.Invoke(ctx, AuthService_RevokeRefreshToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthServiceServer is the server API for AuthService service.
 // All implementations must embed UnimplementedAuthServiceServer
 // for forward compatibility
@@ -87,6 +175,14 @@ type AuthServiceServer interface {
 	Login(context.Context, *LoginRequest) (*LoginReply, error)
 	Logout(context.Context, *LogoutRequest) (*LogoutReply, error)
 	RefreshToken(context.Context, *RefreshTokenRequest) (*RefreshTokenReply, error)
+	ExternalLogin(context.Context, *ExternalLoginRequest) (*LoginReply, error)
+	AddPasskey(context.Context, *AddPasskeyRequest) (*AddPasskeyReply, error)
+	ListPasskeys(context.Context, *ListPasskeysRequest) (*ListPasskeysReply, error)
+	PasskeyLogin(context.Context, *PasskeyLoginRequest) (*LoginReply, error)
+	EnableMfa(context.Context, *EnableMfaRequest) (*EnableMfaReply, error)
+	GetMfa(context.Context, *GetMfaRequest) (*GetMfaReply, error)
+	UseRecoveryCode(context.Context, *UseRecoveryCodeRequest) (*UseRecoveryCodeReply, error)
+	RevokeRefreshToken(context.Context, *RevokeRefreshTokenRequest) (*RevokeRefreshTokenReply, error)
 	mustEmbedUnimplementedAuthServiceServer()
 }
 
@@ -106,6 +202,30 @@ func (UnimplementedAuthServiceServer) Logout(context.Context, *LogoutRequest) (*
 func (UnimplementedAuthServiceServer) RefreshToken(context.Context, *RefreshTokenRequest) (*RefreshTokenReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RefreshToken not implemented")
 }
+func (UnimplementedAuthServiceServer) ExternalLogin(context.Context, *ExternalLoginRequest) (*LoginReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExternalLogin not implemented")
+}
+func (UnimplementedAuthServiceServer) AddPasskey(context.Context, *AddPasskeyRequest) (*AddPasskeyReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPasskey not implemented")
+}
+func (UnimplementedAuthServiceServer) ListPasskeys(context.Context, *ListPasskeysRequest) (*ListPasskeysReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPasskeys not implemented")
+}
+func (UnimplementedAuthServiceServer) PasskeyLogin(context.Context, *PasskeyLoginRequest) (*LoginReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PasskeyLogin not implemented")
+}
+func (UnimplementedAuthServiceServer) EnableMfa(context.Context, *EnableMfaRequest) (*EnableMfaReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableMfa not implemented")
+}
+func (UnimplementedAuthServiceServer) GetMfa(context.Context, *GetMfaRequest) (*GetMfaReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMfa not implemented")
+}
+func (UnimplementedAuthServiceServer) UseRecoveryCode(context.Context, *UseRecoveryCodeRequest) (*UseRecoveryCodeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UseRecoveryCode not implemented")
+}
+func (UnimplementedAuthServiceServer) RevokeRefreshToken(context.Context, *RevokeRefreshTokenRequest) (*RevokeRefreshTokenReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeRefreshToken not implemented")
+}
 func (UnimplementedAuthServiceServer) mustEmbedUnimplementedAuthServiceServer() {}
 
 // UnsafeAuthServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -191,6 +311,150 @@ func _AuthService_RefreshToken_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthService_ExternalLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExternalLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).ExternalLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_ExternalLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).ExternalLogin(ctx, req.(*ExternalLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_AddPasskey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddPasskeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).AddPasskey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_AddPasskey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).AddPasskey(ctx, req.(*AddPasskeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_ListPasskeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPasskeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).ListPasskeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_ListPasskeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).ListPasskeys(ctx, req.(*ListPasskeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_PasskeyLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PasskeyLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).PasskeyLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_PasskeyLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).PasskeyLogin(ctx, req.(*PasskeyLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_EnableMfa_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnableMfaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).EnableMfa(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_EnableMfa_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).EnableMfa(ctx, req.(*EnableMfaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_GetMfa_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMfaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).GetMfa(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_GetMfa_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).GetMfa(ctx, req.(*GetMfaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_UseRecoveryCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UseRecoveryCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).UseRecoveryCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_UseRecoveryCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).UseRecoveryCode(ctx, req.(*UseRecoveryCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_RevokeRefreshToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRefreshTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).RevokeRefreshToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_RevokeRefreshToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).RevokeRefreshToken(ctx, req.(*RevokeRefreshTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AuthService_ServiceDesc is the grpc.ServiceDesc for AuthService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -214,6 +478,38 @@ var AuthService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RefreshToken",
 			Handler:    _AuthService_RefreshToken_Handler,
 		},
+		{
+			MethodName: "ExternalLogin",
+			Handler:    _AuthService_ExternalLogin_Handler,
+		},
+		{
+			MethodName: "AddPasskey",
+			Handler:    _AuthService_AddPasskey_Handler,
+		},
+		{
+			MethodName: "ListPasskeys",
+			Handler:    _AuthService_ListPasskeys_Handler,
+		},
+		{
+			MethodName: "PasskeyLogin",
+			Handler:    _AuthService_PasskeyLogin_Handler,
+		},
+		{
+			MethodName: "EnableMfa",
+			Handler:    _AuthService_EnableMfa_Handler,
+		},
+		{
+			MethodName: "GetMfa",
+			Handler:    _AuthService_GetMfa_Handler,
+		},
+		{
+			MethodName: "UseRecoveryCode",
+			Handler:    _AuthService_UseRecoveryCode_Handler,
+		},
+		{
+			MethodName: "RevokeRefreshToken",
+			Handler:    _AuthService_RevokeRefreshToken_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "auth.proto",
@@ -0,0 +1,189 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v5.26.1
+// source: introspection.proto
+
+package proto_gen
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	TokenIntrospection_ValidateToken_FullMethodName = "/gateway.TokenIntrospection/ValidateToken"
+	TokenIntrospection_GetPrincipal_FullMethodName  = "/gateway.TokenIntrospection/GetPrincipal"
+	TokenIntrospection_RevokeToken_FullMethodName   = "/gateway.TokenIntrospection/RevokeToken"
+)
+
+// TokenIntrospectionClient is the client API for TokenIntrospection service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TokenIntrospectionClient interface {
+	// ValidateToken reports whether an access token is live, and its session.
+	ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*ValidateTokenReply, error)
+	// GetPrincipal returns who an access token or an API key authenticates.
+	GetPrincipal(ctx context.Context, in *GetPrincipalRequest, opts ...grpc.CallOption) (*GetPrincipalReply, error)
+	// RevokeToken deletes an access token, ending its session.
+	RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RevokeTokenReply, error)
+}
+
+type tokenIntrospectionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenIntrospectionClient(cc grpc.ClientConnInterface) TokenIntrospectionClient {
+	return &tokenIntrospectionClient{cc}
+}
+
+func (c *tokenIntrospectionClient) ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*ValidateTokenReply, error) {
+	out := new(ValidateTokenReply)
+	err := c.cc.Invoke(ctx, TokenIntrospection_ValidateToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenIntrospectionClient) GetPrincipal(ctx context.Context, in *GetPrincipalRequest, opts ...grpc.CallOption) (*GetPrincipalReply, error) {
+	out := new(GetPrincipalReply)
+	err := c.cc.Invoke(ctx, TokenIntrospection_GetPrincipal_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenIntrospectionClient) RevokeToken(ctx context.Context, in *RevokeTokenRequest, opts ...grpc.CallOption) (*RevokeTokenReply, error) {
+	out := new(RevokeTokenReply)
+	err := c.cc.Invoke(ctx, TokenIntrospection_RevokeToken_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenIntrospectionServer is the server API for TokenIntrospection service.
+// All implementations must embed UnimplementedTokenIntrospectionServer
+// for forward compatibility
+type TokenIntrospectionServer interface {
+	// ValidateToken reports whether an access token is live, and its session.
+	ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenReply, error)
+	// GetPrincipal returns who an access token or an API key authenticates.
+	GetPrincipal(context.Context, *GetPrincipalRequest) (*GetPrincipalReply, error)
+	// RevokeToken deletes an access token, ending its session.
+	RevokeToken(context.Context, *RevokeTokenRequest) (*RevokeTokenReply, error)
+	mustEmbedUnimplementedTokenIntrospectionServer()
+}
+
+// UnimplementedTokenIntrospectionServer must be embedded to have forward compatible implementations.
+type UnimplementedTokenIntrospectionServer struct {
+}
+
+func (UnimplementedTokenIntrospectionServer) ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateToken not implemented")
+}
+func (UnimplementedTokenIntrospectionServer) GetPrincipal(context.Context, *GetPrincipalRequest) (*GetPrincipalReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrincipal not implemented")
+}
+func (UnimplementedTokenIntrospectionServer) RevokeToken(context.Context, *RevokeTokenRequest) (*RevokeTokenReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeToken not implemented")
+}
+func (UnimplementedTokenIntrospectionServer) mustEmbedUnimplementedTokenIntrospectionServer() {}
+
+// UnsafeTokenIntrospectionServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenIntrospectionServer will
+// result in compilation errors.
+type UnsafeTokenIntrospectionServer interface {
+	mustEmbedUnimplementedTokenIntrospectionServer()
+}
+
+func RegisterTokenIntrospectionServer(s grpc.ServiceRegistrar, srv TokenIntrospectionServer) {
+	s.RegisterService(&TokenIntrospection_ServiceDesc, srv)
+}
+
+func _TokenIntrospection_ValidateToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenIntrospectionServer).ValidateToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenIntrospection_ValidateToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenIntrospectionServer).ValidateToken(ctx, req.(*ValidateTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenIntrospection_GetPrincipal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPrincipalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenIntrospectionServer).GetPrincipal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenIntrospection_GetPrincipal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenIntrospectionServer).GetPrincipal(ctx, req.(*GetPrincipalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TokenIntrospection_RevokeToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenIntrospectionServer).RevokeToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TokenIntrospection_RevokeToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenIntrospectionServer).RevokeToken(ctx, req.(*RevokeTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TokenIntrospection_ServiceDesc is the grpc.ServiceDesc for TokenIntrospection service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TokenIntrospection_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gateway.TokenIntrospection",
+	HandlerType: (*TokenIntrospectionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateToken",
+			Handler:    _TokenIntrospection_ValidateToken_Handler,
+		},
+		{
+			MethodName: "GetPrincipal",
+			Handler:    _TokenIntrospection_GetPrincipal_Handler,
+		},
+		{
+			MethodName: "RevokeToken",
+			Handler:    _TokenIntrospection_RevokeToken_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "introspection.proto",
+}
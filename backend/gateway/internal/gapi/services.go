@@ -5,21 +5,49 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/pkg/identity"
 )
 
 type AuthConfig struct {
 	Host string
 	Port string
+	// Identity signs the identity tokens sent along the calls made for an
+	// authenticated caller, nil to send none.
+	Identity *identity.Signer
 }
 
-func NewAuthService(cfg *AuthConfig) (pb.AuthServiceClient, error) {
+// AuthService is a client of the Auth service owning its connection.
+type AuthService struct {
+	pb.AuthServiceClient
+	conn *grpc.ClientConn
+}
+
+func NewAuthService(cfg *AuthConfig) (*AuthService, error) {
 	addr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if cfg.Identity != nil {
+		opts = append(opts, grpc.WithUnaryInterceptor(forwardIdentity(cfg.Identity)))
+	}
+	conn, err := grpc.Dial(addr, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	client := pb.NewAuthServiceClient(conn)
+	client := &AuthService{
+		AuthServiceClient: pb.NewAuthServiceClient(conn),
+		conn:              conn,
+	}
 
 	return client, nil
 }
+
+// Conn is the connection to the Auth service, for the calls proxied as they
+// are.
+func (s *AuthService) Conn() grpc.ClientConnInterface {
+	return s.conn
+}
+
+// Close closes the connection, failing the calls in flight.
+func (s *AuthService) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,122 @@
+// Package grpcserver serves the gRPC services of the gateway to the internal
+// services, along the HTTP server.
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"strings"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/introspection"
+)
+
+type Config struct {
+	Port          string
+	Logger        *zap.Logger
+	Introspection *introspection.Service
+	// ClientToken is the bearer token the callers must send in the
+	// authorization metadata, none required if empty.
+	ClientToken string
+}
+
+type Server struct {
+	grpcServer *grpc.Server
+	health     *health.Server
+	addr       string
+	logger     *zap.Logger
+	errc       chan error
+}
+
+func New(cfg *Config) *Server {
+	s := &Server{
+		health: health.NewServer(),
+		addr:   fmt.Sprintf(":%s", cfg.Port),
+		logger: cfg.Logger,
+		errc:   make(chan error, 1),
+	}
+
+	var opts []grpc.ServerOption
+	if cfg.ClientToken != "" {
+		opts = append(opts, grpc.UnaryInterceptor(checkToken(cfg.ClientToken)))
+	}
+	s.grpcServer = grpc.NewServer(opts...)
+	pb.RegisterTokenIntrospectionServer(s.grpcServer, cfg.Introspection)
+	healthpb.RegisterHealthServer(s.grpcServer, s.health)
+
+	return s
+}
+
+// Start listens on the port and serves in the background. Errors of the
+// listener after that are sent to Err.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.grpcServer.Serve(ln); err != nil {
+			s.errc <- err
+		}
+	}()
+	s.logger.Info("grpc server is ready to handle requests", zap.String("address", s.addr))
+
+	return nil
+}
+
+// Err receives the error the server stopped serving with, if not stopped by
+// Stop.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
+// Stop reports the services as not serving, stops accepting calls and waits
+// for those in flight until ctx is done, then closes their connections.
+func (s *Server) Stop(ctx context.Context) error {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.logger.Warn("calls still in flight at the shutdown timeout, closing their connections", zap.Error(ctx.Err()))
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// checkToken refuses the calls without the bearer token, but the health
+// checks.
+func checkToken(want string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		var got string
+		if v := md.Get("authorization"); len(v) > 0 {
+			got = strings.TrimPrefix(v[0], "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+
+		return handler(ctx, req)
+	}
+}
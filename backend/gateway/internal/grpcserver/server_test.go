@@ -0,0 +1,108 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/introspection"
+	"workmap/gateway/internal/memstore"
+	"workmap/gateway/internal/redis"
+)
+
+// newServer starts a server on a free port and returns a connection to it.
+func newServer(t *testing.T, clientToken string) (*Server, *grpc.ClientConn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, ln.Close())
+
+	s := New(&Config{
+		Port:   port,
+		Logger: zap.NewNop(),
+		Introspection: introspection.New(&introspection.Config{
+			Logger: zap.NewNop(),
+			Tokens: store.NewTokenStore(memstore.New(&memstore.Config{}), store.TokenKeyConfig{}),
+		}),
+		ClientToken: clientToken,
+	})
+	require.NoError(t, s.Start(context.Background()))
+
+	conn, err := grpc.Dial("127.0.0.1:"+port, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return s, conn
+}
+
+func TestServer(t *testing.T) {
+	t.Run("serves the introspection", func(t *testing.T) {
+		s, conn := newServer(t, "")
+		defer s.Stop(context.Background())
+
+		reply, err := pb.NewTokenIntrospectionClient(conn).ValidateToken(context.Background(), &pb.ValidateTokenRequest{AccessToken: "unknown"})
+
+		require.NoError(t, err)
+		assert.False(t, reply.Active)
+	})
+
+	t.Run("checks the client token", func(t *testing.T) {
+		s, conn := newServer(t, "client-token")
+		defer s.Stop(context.Background())
+		client := pb.NewTokenIntrospectionClient(conn)
+
+		tests := []struct {
+			name          string
+			authorization string
+			expectedCode  codes.Code
+		}{
+			{name: "valid", authorization: "Bearer client-token", expectedCode: codes.OK},
+			{name: "wrong", authorization: "Bearer other", expectedCode: codes.Unauthenticated},
+			{name: "missing", expectedCode: codes.Unauthenticated},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				if tt.authorization != "" {
+					ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.authorization)
+				}
+
+				_, err := client.ValidateToken(ctx, &pb.ValidateTokenRequest{AccessToken: "unknown"})
+
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+			})
+		}
+
+		resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err, "health checks need no token")
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		s, conn := newServer(t, "")
+
+		require.NoError(t, s.Stop(context.Background()))
+
+		_, err := pb.NewTokenIntrospectionClient(conn).ValidateToken(context.Background(), &pb.ValidateTokenRequest{AccessToken: "unknown"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Empty(t, s.Err(), "a stopped server is not a failure")
+	})
+
+	t.Run("fails to start on a taken port", func(t *testing.T) {
+		s, _ := newServer(t, "")
+		defer s.Stop(context.Background())
+
+		other := New(&Config{Port: s.addr[1:], Logger: zap.NewNop(), Introspection: introspection.New(&introspection.Config{})})
+		assert.Error(t, other.Start(context.Background()))
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+	"workmap/gateway/internal/models"
+	"workmap/gateway/internal/pkg/apikey"
+	"workmap/gateway/internal/redis"
+)
+
+// CreateAPIKey issues an API key for the caller. The key is only returned
+// here, the gateway keeps just its hash.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	var c models.APIKeyCreate
+	if !h.decodeJSON(w, r, &c) {
+		return
+	}
+
+	if err := c.Validate(); err != nil {
+		h.logger.Error("api key data is not valid", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	key, id, err := apikey.Generate()
+	if err != nil {
+		h.logger.Error("failed to generate api key", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	k := store.APIKey{
+		ID:        id,
+		Email:     email,
+		Name:      c.Name,
+		Scopes:    c.Scopes,
+		CreatedAt: now,
+		ExpiresAt: now.AddDate(0, 0, c.ExpiresInDays),
+	}
+
+	err = h.apiKeys.SaveAPIKey(apikey.Hash(key), k)
+	if err != nil {
+		h.logger.Error("failed to save api key to redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, struct {
+		Key string `json:"key"`
+		store.APIKey
+	}{
+		Key:    key,
+		APIKey: k,
+	})
+
+	h.logger.Info("user api key created", zap.String("email", email), zap.String("id", id))
+}
+
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeys.ListAPIKeys(email)
+	if err != nil {
+		h.logger.Error("failed to list api keys from redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	deleted, err := h.apiKeys.DeleteAPIKey(email, id)
+	if err != nil {
+		h.logger.Error("failed to delete api key from redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !deleted {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	h.logger.Info("user api key revoked", zap.String("email", email), zap.String("id", id))
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"workmap/gateway/internal/pkg/apikey"
+	store "workmap/gateway/internal/redis"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	logger := zap.NewNop()
+
+	tests := []struct {
+		name           string
+		body           string
+		mockStoreError error
+		expectedStatus int
+	}{
+		{
+			name:           "valid request",
+			body:           `{"name":"CI","scopes":["profile:read"],"expires_in_days":30}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "scope that cannot be granted",
+			body:           `{"name":"CI","scopes":["account"],"expires_in_days":30}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid body",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "store error",
+			body:           `{"name":"CI","scopes":["profile:read"],"expires_in_days":30}`,
+			mockStoreError: errors.New("redis error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKeys := new(MockAPIKeyStore)
+			handler := &Handler{logger: logger, apiKeys: mockKeys}
+
+			mockKeys.On("SaveAPIKey", mock.Anything, mock.Anything).Return(tt.mockStoreError)
+
+			req := httptest.NewRequest(http.MethodPost, "/user/api-keys", strings.NewReader(tt.body))
+			req = withPrincipal(req, mfaTestEmail)
+			rr := httptest.NewRecorder()
+			handler.CreateAPIKey(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			var res struct {
+				Key string `json:"key"`
+				store.APIKey
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+
+			saved := mockKeys.Calls[0].Arguments
+			assert.Equal(t, apikey.Hash(res.Key), saved.String(0))
+			assert.Equal(t, saved.Get(1).(store.APIKey), res.APIKey)
+			assert.Equal(t, mfaTestEmail, res.Email)
+			assert.Equal(t, []string{"profile:read"}, res.Scopes)
+			assert.Equal(t, 30*24*time.Hour, res.ExpiresAt.Sub(res.CreatedAt))
+		})
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	mockKeys := new(MockAPIKeyStore)
+	handler := &Handler{logger: zap.NewNop(), apiKeys: mockKeys}
+
+	keys := []store.APIKey{{ID: "key-id", Email: mfaTestEmail, Name: "CI"}}
+	mockKeys.On("ListAPIKeys", mfaTestEmail).Return(keys, nil)
+
+	req := withPrincipal(httptest.NewRequest(http.MethodGet, "/user/api-keys", nil), mfaTestEmail)
+	rr := httptest.NewRecorder()
+	handler.ListAPIKeys(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), `"key"`)
+
+	var res []store.APIKey
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+	assert.Equal(t, keys, res)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	logger := zap.NewNop()
+
+	tests := []struct {
+		name           string
+		mockDeleted    bool
+		mockStoreError error
+		expectedStatus int
+	}{
+		{
+			name:           "revoked",
+			mockDeleted:    true,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "unknown key",
+			mockDeleted:    false,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "store error",
+			mockStoreError: errors.New("redis error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKeys := new(MockAPIKeyStore)
+			handler := &Handler{logger: logger, apiKeys: mockKeys}
+
+			mockKeys.On("DeleteAPIKey", mfaTestEmail, "key-id").Return(tt.mockDeleted, tt.mockStoreError)
+
+			req := httptest.NewRequest(http.MethodDelete, "/user/api-keys/key-id", nil)
+			req.SetPathValue("id", "key-id")
+			req = withPrincipal(req, mfaTestEmail)
+			rr := httptest.NewRecorder()
+			handler.RevokeAPIKey(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
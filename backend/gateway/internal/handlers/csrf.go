@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"workmap/gateway/internal/pkg/csrf"
+)
+
+// CSRFToken issues the token that cookie-authenticated requests must repeat
+// in the X-CSRF-Token header. It is returned in the body too, because a
+// frontend on another origin cannot read the gateway's cookies. The token is
+// bound to the refresh token cookie, so it must be fetched again after login.
+func (h *Handler) CSRFToken(w http.ResponseWriter, r *http.Request) {
+	var session string
+	if cookie, err := r.Cookie(csrf.SessionCookieName); err == nil {
+		session = cookie.Value
+	}
+
+	t, err := csrf.Generate(h.csrfSecret, session)
+	if err != nil {
+		h.logger.Error("failed to generate csrf token", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:   csrf.CookieName,
+		Value:  t,
+		Path:   "/",
+		MaxAge: 604800,
+		//Secure:   true,
+		SameSite: 0,
+	})
+	w.Header().Set(csrf.HeaderName, t)
+
+	writeJSON(w, http.StatusOK, struct {
+		CSRFToken string `json:"csrf_token"`
+	}{
+		CSRFToken: t,
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"workmap/gateway/internal/pkg/csrf"
+)
+
+func TestCSRFToken(t *testing.T) {
+	secret := []byte("secret")
+	handler := &Handler{logger: zap.NewNop(), csrfSecret: secret}
+
+	req := httptest.NewRequest(http.MethodGet, "/csrf", nil)
+	req.AddCookie(&http.Cookie{Name: csrf.SessionCookieName, Value: "refresh-token"})
+	rr := httptest.NewRecorder()
+	handler.CSRFToken(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var res struct {
+		CSRFToken string `json:"csrf_token"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+	assert.True(t, csrf.Valid(secret, res.CSRFToken, "refresh-token"))
+	assert.False(t, csrf.Valid(secret, res.CSRFToken, "other-refresh-token"))
+	assert.Equal(t, res.CSRFToken, rr.Header().Get(csrf.HeaderName))
+
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, csrf.CookieName, cookies[0].Name)
+	assert.Equal(t, res.CSRFToken, cookies[0].Value)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"workmap/gateway/docs"
+)
+
+// OpenAPI serves the OpenAPI description of the gateway.
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	spec, err := docs.OpenAPI()
+	if err != nil {
+		h.logger.Error("failed to convert openapi description", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(spec)
+}
+
+// APIDocs serves a page browsing the OpenAPI description.
+func (h *Handler) APIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(docs.UI)
+}
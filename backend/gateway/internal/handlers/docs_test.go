@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	handler := &Handler{logger: zap.NewNop()}
+
+	rr := httptest.NewRecorder()
+	handler.OpenAPI(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Contains(t, spec.Paths["/user/register"], "post")
+}
+
+func TestAPIDocs(t *testing.T) {
+	handler := &Handler{logger: zap.NewNop()}
+
+	rr := httptest.NewRecorder()
+	handler.APIDocs(rr, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `url: "/openapi.json"`)
+}
@@ -1,27 +1,191 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
 	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/lifecycle"
+	"workmap/gateway/internal/oauth"
+	"workmap/gateway/internal/principal"
 	"workmap/gateway/internal/redis"
+	"workmap/gateway/internal/tenant"
 )
 
 type Config struct {
 	Logger     *zap.Logger
 	Auth       pb.AuthServiceClient
 	TokenStore store.TokenStore
+	// SessionStore is the token store if it lists sessions, nil otherwise.
+	SessionStore    store.SessionStore
+	Tenants         *tenant.Resolver
+	OAuthStateStore store.OAuthStateStore
+	OAuthProviders  map[string]oauth.Provider
+	// OAuthFrontendURL is the page of the frontend OAuthCallback redirects
+	// the browser to.
+	OAuthFrontendURL string
+	MFAStore         store.MFAStore
+	// WebAuthn is nil when passkeys are not configured.
+	WebAuthn             *webauthn.WebAuthn
+	WebAuthnSessionStore store.WebAuthnSessionStore
+	APIKeyStore          store.APIKeyStore
+	CSRFSecret           []byte
+	RedisHealth          store.HealthChecker
+	// DegradedReads tells Readyz that verified tokens are accepted for reads
+	// while Redis is down.
+	DegradedReads bool
+	// Lifecycle tells Readyz that the gateway is draining, nil if it never
+	// does.
+	Lifecycle lifecycle.Drainer
+	// Introspection looks up the access tokens of OAuthIntrospect and
+	// OAuthRevoke.
+	Introspection Introspector
 }
 
 type Handler struct {
-	logger     *zap.Logger
-	auth       pb.AuthServiceClient
-	tokenStore store.TokenStore
+	logger           *zap.Logger
+	auth             pb.AuthServiceClient
+	tokenStore       store.TokenStore
+	sessions         store.SessionStore
+	tenants          *tenant.Resolver
+	oauthStates      store.OAuthStateStore
+	oauthProviders   map[string]oauth.Provider
+	oauthFrontendURL string
+	mfa              store.MFAStore
+	webAuthn         *webauthn.WebAuthn
+	webAuthnSessions store.WebAuthnSessionStore
+	apiKeys          store.APIKeyStore
+	csrfSecret       []byte
+	introspection    Introspector
+	redisHealth      store.HealthChecker
+	degradedReads    bool
+	lifecycle        lifecycle.Drainer
 }
 
 func New(cfg *Config) *Handler {
+	sessions := cfg.SessionStore
+	if sessions == nil {
+		sessions = noSessions{}
+	}
+
 	return &Handler{
-		logger:     cfg.Logger,
-		auth:       cfg.Auth,
-		tokenStore: cfg.TokenStore,
+		logger:           cfg.Logger,
+		auth:             cfg.Auth,
+		tokenStore:       cfg.TokenStore,
+		sessions:         sessions,
+		tenants:          cfg.Tenants,
+		oauthStates:      cfg.OAuthStateStore,
+		oauthProviders:   cfg.OAuthProviders,
+		oauthFrontendURL: cfg.OAuthFrontendURL,
+		mfa:              cfg.MFAStore,
+		webAuthn:         cfg.WebAuthn,
+		webAuthnSessions: cfg.WebAuthnSessionStore,
+		apiKeys:          cfg.APIKeyStore,
+		csrfSecret:       cfg.CSRFSecret,
+		introspection:    cfg.Introspection,
+		redisHealth:      cfg.RedisHealth,
+		degradedReads:    cfg.DegradedReads,
+		lifecycle:        cfg.Lifecycle,
+	}
+}
+
+// principalEmail returns the email of the principal CheckAuth authenticated
+// the request as, writing an error response if there is none.
+func (h *Handler) principalEmail(w http.ResponseWriter, r *http.Request) (string, bool) {
+	p, ok := principal.FromContext(r.Context())
+	if !ok {
+		h.logger.Error("request has no principal")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+
+	return p.Email, true
+}
+
+// saveSession stores the access token with the client it was issued to and
+// the hash of the refresh token it came with.
+func (h *Handler) saveSession(r *http.Request, accessToken, refreshToken string) error {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
 	}
+
+	s, err := store.NewSession(accessToken, store.Client{IP: ip, UserAgent: r.UserAgent()})
+	if err != nil {
+		return err
+	}
+	s.RefreshTokenHash = store.SessionID(refreshToken)
+
+	// A login has no access token to resolve the tenant from yet.
+	ctx := r.Context()
+	if _, ok := tenant.FromContext(ctx); !ok {
+		if t, ok := h.tenants.FromToken(accessToken); ok {
+			ctx = tenant.NewContext(ctx, t)
+		}
+	}
+
+	return h.tokenStore.SaveAccessToken(ctx, accessToken, s)
+}
+
+// noSessions stands in for a token store that cannot list sessions.
+type noSessions struct{}
+
+func (noSessions) ListSessions(context.Context, string) ([]store.Session, error) {
+	return nil, store.ErrUnsupported
+}
+
+func (noSessions) RevokeSession(context.Context, string, string) (bool, error) {
+	return false, store.ErrUnsupported
+}
+
+// decodeJSON decodes the request body into v, refusing unknown fields and
+// trailing data. If the body is invalid or over the body limit, it answers
+// the request and returns false.
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := decodeStrict(r.Body, v)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.logger.Error("request body too large", zap.Int64("limit", tooLarge.Limit))
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return false
+	}
+	if err != nil {
+		h.logger.Error("failed to decode request body", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// decodeStrict returns the errors of reading body as they are, so that a
+// *http.MaxBytesError is still found after the JSON value.
+func decodeStrict(body io.Reader, v any) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+
+	switch err := dec.Decode(&struct{}{}); err {
+	case io.EOF:
+		return nil
+	case nil:
+		return errors.New("unexpected data after the JSON value")
+	default:
+		return err
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
 }
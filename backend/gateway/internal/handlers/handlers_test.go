@@ -3,7 +3,12 @@ package handlers
 import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"workmap/gateway/internal/models"
+	"workmap/gateway/internal/principal"
 )
 
 func TestNew(t *testing.T) {
@@ -23,3 +28,69 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, logger, handler.logger)
 	assert.Equal(t, mockAuthService, handler.auth)
 }
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		limit          int64
+		expectedOK     bool
+		expectedStatus int
+	}{
+		{
+			name:       "valid",
+			body:       `{"email":"user@email.com","password":"password"}`,
+			expectedOK: true,
+		},
+		{
+			name:           "unknown field",
+			body:           `{"email":"user@email.com","password":"password","admin":true}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "trailing data",
+			body:           `{"email":"user@email.com","password":"password"} {}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "over the body limit",
+			body:           `{"email":"user@email.com","password":"` + strings.Repeat("p", 100) + `"}`,
+			limit:          64,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "over the body limit after the JSON value",
+			body:           `{"email":"user@email.com","password":"password"}` + strings.Repeat(" ", 100),
+			limit:          64,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &Handler{logger: zap.NewNop()}
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(tt.body))
+			if tt.limit > 0 {
+				req.Body = http.MaxBytesReader(rr, req.Body, tt.limit)
+			}
+
+			var u models.User
+			ok := handler.decodeJSON(rr, req, &u)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			if !ok {
+				assert.Equal(t, tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+// withPrincipal authenticates req as the user with email, like CheckAuth does.
+func withPrincipal(req *http.Request, email string) *http.Request {
+	return req.WithContext(principal.NewContext(req.Context(), &principal.Principal{
+		Kind:  principal.KindUser,
+		Email: email,
+	}))
+}
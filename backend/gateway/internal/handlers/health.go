@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// Readyz reports whether the gateway can serve requests. While Redis is down
+// it is degraded if verified tokens are accepted for reads, and unavailable
+// otherwise. It is draining once shutting down, so that load balancers stop
+// sending requests before the server stops accepting them.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	type readiness struct {
+		Status string `json:"status"`
+		Redis  string `json:"redis"`
+	}
+
+	redis := "down"
+	if h.redisHealth.Up() {
+		redis = "up"
+	}
+
+	switch {
+	case h.lifecycle != nil && h.lifecycle.Draining():
+		writeJSON(w, http.StatusServiceUnavailable, readiness{Status: "draining", Redis: redis})
+	case h.redisHealth.Up():
+		writeJSON(w, http.StatusOK, readiness{Status: "ready", Redis: "up"})
+	case h.degradedReads:
+		writeJSON(w, http.StatusOK, readiness{Status: "degraded", Redis: "down"})
+	default:
+		writeJSON(w, http.StatusServiceUnavailable, readiness{Status: "unavailable", Redis: "down"})
+	}
+}
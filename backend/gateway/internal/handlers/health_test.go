@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeHealth bool
+
+func (f fakeHealth) Up() bool {
+	return bool(f)
+}
+
+type fakeDrainer bool
+
+func (f fakeDrainer) Draining() bool {
+	return bool(f)
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name          string
+		up            bool
+		degradedReads bool
+		draining      bool
+		expectedCode  int
+		expectedBody  string
+	}{
+		{
+			name:         "redis up",
+			up:           true,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"status":"ready","redis":"up"}`,
+		},
+		{
+			name:          "redis down with degraded reads",
+			degradedReads: true,
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"status":"degraded","redis":"down"}`,
+		},
+		{
+			name:         "redis down",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"unavailable","redis":"down"}`,
+		},
+		{
+			name:         "draining",
+			up:           true,
+			draining:     true,
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: `{"status":"draining","redis":"up"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(&Config{RedisHealth: fakeHealth(tt.up), DegradedReads: tt.degradedReads, Lifecycle: fakeDrainer(tt.draining)})
+
+			rr := httptest.NewRecorder()
+			handler.Readyz(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/principal"
+	"workmap/gateway/internal/redis"
+)
+
+// Introspector looks up the session of an access token, see
+// introspection.Service.
+type Introspector interface {
+	// Session returns store.ErrNotFound for inactive tokens and reports
+	// with degraded the tokens verified without the token store.
+	Session(ctx context.Context, accessToken string) (session store.Session, degraded bool, err error)
+}
+
+// introspectionResponse is the RFC 7662 introspection response.
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+}
+
+// OAuthIntrospect tells the resource servers whether an access token is
+// active and whose it is, as in RFC 7662. Refresh tokens and anything else
+// are inactive. A token verified without the token store may have been
+// revoked, so the resource servers are told to retry instead.
+func (h *Handler) OAuthIntrospect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	t := r.PostFormValue("token")
+	if t == "" {
+		writeJSON(w, http.StatusBadRequest, oauthError{Error: "invalid_request"})
+		return
+	}
+
+	session, degraded, err := h.introspection.Session(r.Context(), t)
+	if errors.Is(err, store.ErrNotFound) {
+		writeJSON(w, http.StatusOK, introspectionResponse{Active: false})
+		return
+	}
+	if err != nil || degraded {
+		h.logger.Error("failed to introspect token", zap.Bool("degraded", degraded), zap.Error(err))
+		writeJSON(w, http.StatusServiceUnavailable, oauthError{Error: "temporarily_unavailable"})
+		return
+	}
+
+	res := introspectionResponse{
+		Active:    true,
+		Scope:     strings.Join(principal.UserScopes, " "),
+		TokenType: "access_token",
+		Sub:       session.UserID,
+		Email:     session.Email,
+	}
+	if !session.ExpiresAt.IsZero() {
+		res.Exp = session.ExpiresAt.Unix()
+	}
+	if !session.IssuedAt.IsZero() {
+		res.Iat = session.IssuedAt.Unix()
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// OAuthRevoke revokes an access or a refresh token, as in RFC 7009. The
+// tokens are looked up as access tokens first unless hinted otherwise, and
+// unknown tokens are not an error.
+func (h *Handler) OAuthRevoke(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	t := r.PostFormValue("token")
+	if t == "" {
+		writeJSON(w, http.StatusBadRequest, oauthError{Error: "invalid_request"})
+		return
+	}
+
+	if r.PostFormValue("token_type_hint") != "refresh_token" {
+		_, _, err := h.introspection.Session(r.Context(), t)
+		if err == nil {
+			err = h.tokenStore.DeleteAccessToken(r.Context(), t)
+		}
+		if err == nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if !errors.Is(err, store.ErrNotFound) {
+			h.logger.Error("failed to revoke access token", zap.Error(err))
+			writeJSON(w, http.StatusServiceUnavailable, oauthError{Error: "temporarily_unavailable"})
+			return
+		}
+	}
+
+	_, err := h.auth.Logout(r.Context(), &pb.LogoutRequest{RefreshToken: t})
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		h.logger.Error("failed to revoke refresh token", zap.Error(err))
+		writeJSON(w, http.StatusServiceUnavailable, oauthError{Error: "temporarily_unavailable"})
+		return
+	case codes.OK:
+	default:
+		// The Auth service refuses the tokens it does not know.
+		h.logger.Info("refresh token not revoked", zap.Error(err))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// oauthError is the error response of RFC 6749 5.2.
+type oauthError struct {
+	Error string `json:"error"`
+}
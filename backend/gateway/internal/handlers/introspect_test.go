@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/introspection"
+	store "workmap/gateway/internal/redis"
+)
+
+func newTokenRequest(path string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return req
+}
+
+func TestOAuthIntrospect(t *testing.T) {
+	issuedAt := time.Unix(1760000000, 0)
+	session := store.Session{UserID: "42", Email: mfaTestEmail, IssuedAt: issuedAt, ExpiresAt: issuedAt.Add(15 * time.Minute)}
+
+	tests := []struct {
+		name           string
+		token          string
+		mockSession    store.Session
+		mockDegraded   bool
+		mockError      error
+		expectedStatus int
+		expectedBody   map[string]any
+	}{
+		{
+			name:           "active",
+			token:          "access_token",
+			mockSession:    session,
+			expectedStatus: http.StatusOK,
+			expectedBody: map[string]any{
+				"active":     true,
+				"scope":      "profile:read account",
+				"token_type": "access_token",
+				"sub":        "42",
+				"email":      mfaTestEmail,
+				"exp":        float64(1760000900),
+				"iat":        float64(1760000000),
+			},
+		},
+		{
+			name:           "inactive",
+			token:          "refresh_token",
+			mockError:      store.ErrNotFound,
+			expectedStatus: http.StatusOK,
+			expectedBody:   map[string]any{"active": false},
+		},
+		{
+			name:           "store unavailable",
+			token:          "access_token",
+			mockError:      introspection.ErrUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   map[string]any{"error": "temporarily_unavailable"},
+		},
+		{
+			name:           "verified without the store",
+			token:          "access_token",
+			mockSession:    session,
+			mockDegraded:   true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   map[string]any{"error": "temporarily_unavailable"},
+		},
+		{
+			name:           "missing token",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   map[string]any{"error": "invalid_request"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockIntrospector := new(MockIntrospector)
+			mockIntrospector.On("Session", tt.token).Return(tt.mockSession, tt.mockDegraded, tt.mockError)
+			handler := New(&Config{Logger: zap.NewNop(), Introspection: mockIntrospector})
+
+			rr := httptest.NewRecorder()
+			handler.OAuthIntrospect(rr, newTokenRequest("/oauth/introspect", url.Values{"token": {tt.token}}))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+			assert.Equal(t, tt.expectedBody, body)
+		})
+	}
+}
+
+func TestOAuthRevoke(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		mockSession    error
+		mockDelete     error
+		mockLogout     error
+		expectDelete   bool
+		expectLogout   bool
+		expectedStatus int
+	}{
+		{
+			name:           "access token",
+			form:           url.Values{"token": {"access_token"}},
+			expectDelete:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "refresh token",
+			form:           url.Values{"token": {"refresh_token"}},
+			mockSession:    store.ErrNotFound,
+			expectLogout:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "refresh token hint",
+			form:           url.Values{"token": {"refresh_token"}, "token_type_hint": {"refresh_token"}},
+			expectLogout:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown token",
+			form:           url.Values{"token": {"unknown"}},
+			mockSession:    store.ErrNotFound,
+			mockLogout:     status.Error(codes.InvalidArgument, "invalid token"),
+			expectLogout:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "token store unavailable",
+			form:           url.Values{"token": {"access_token"}},
+			mockSession:    introspection.ErrUnavailable,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "delete fails",
+			form:           url.Values{"token": {"access_token"}},
+			mockDelete:     errors.New("redis error"),
+			expectDelete:   true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "auth service unavailable",
+			form:           url.Values{"token": {"refresh_token"}, "token_type_hint": {"refresh_token"}},
+			mockLogout:     status.Error(codes.Unavailable, "connection refused"),
+			expectLogout:   true,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "missing token",
+			form:           url.Values{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockIntrospector := new(MockIntrospector)
+			mockIntrospector.On("Session", mock.Anything).Return(store.Session{}, false, tt.mockSession)
+			mockRedis := new(MockRedis)
+			mockRedis.On("DeleteAccessToken", "access_token").Return(tt.mockDelete)
+			mockAuth := new(MockAuthServiceClient)
+			mockAuth.On("Logout", mock.Anything, mock.Anything).Return(&pb.LogoutReply{IsSuccess: tt.mockLogout == nil}, tt.mockLogout)
+			handler := New(&Config{Logger: zap.NewNop(), Auth: mockAuth, TokenStore: mockRedis, Introspection: mockIntrospector})
+
+			rr := httptest.NewRecorder()
+			handler.OAuthRevoke(rr, newTokenRequest("/oauth/revoke", tt.form))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectDelete {
+				mockRedis.AssertCalled(t, "DeleteAccessToken", "access_token")
+			} else {
+				mockRedis.AssertNotCalled(t, "DeleteAccessToken", mock.Anything)
+			}
+			if tt.expectLogout {
+				mockAuth.AssertCalled(t, "Logout", mock.Anything, &pb.LogoutRequest{RefreshToken: tt.form.Get("token")})
+			} else {
+				mockAuth.AssertNotCalled(t, "Logout", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
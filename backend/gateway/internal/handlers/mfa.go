@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"github.com/skip2/go-qrcode"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/models"
+	"workmap/gateway/internal/oauth"
+	"workmap/gateway/internal/pkg/token"
+	"workmap/gateway/internal/pkg/totp"
+	"workmap/gateway/internal/redis"
+)
+
+const (
+	totpIssuer        = "Work Map"
+	mfaSetupTTL       = 10 * time.Minute
+	mfaChallengeTTL   = 5 * time.Minute
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 10
+
+	// totpStepTTL keeps a used step recorded for as long as Validate accepts it.
+	totpStepTTL = (2*totp.Skew + 1) * totp.Period
+)
+
+// MFASetup starts 2FA enrollment. The secret stays pending until MFAConfirm
+// proves the user's authenticator produces valid codes.
+func (h *Handler) MFASetup(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	enabled, err := h.mfaSecret(r.Context(), email)
+	if err != nil {
+		h.logger.Error("failed to get 2fa secret", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if enabled != "" {
+		http.Error(w, "2FA already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		h.logger.Error("failed to generate 2fa secret", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.mfa.SavePendingMFASecret(email, secret, mfaSetupTTL)
+	if err != nil {
+		h.logger.Error("failed to save 2fa secret to redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	uri := totp.URI(totpIssuer, email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		h.logger.Error("failed to encode 2fa qr code", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		OTPAuthURI string `json:"otpauth_uri"`
+		QRCode     []byte `json:"qr_code_png"`
+	}{
+		OTPAuthURI: uri,
+		QRCode:     png,
+	})
+
+	h.logger.Info("user 2fa setup started", zap.String("email", email))
+}
+
+// MFAConfirm enables 2FA and returns the one-time recovery codes, which are
+// only ever shown here.
+func (h *Handler) MFAConfirm(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	var c models.MFAConfirm
+	if !h.decodeJSON(w, r, &c) {
+		return
+	}
+
+	if err := c.Validate(); err != nil {
+		h.logger.Error("2fa confirm data is not valid", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	secret, err := h.mfa.GetPendingMFASecret(email)
+	if err != nil {
+		h.logger.Error("2fa setup not found", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := totp.Validate(secret, c.Code, time.Now())
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		h.logger.Error("failed to generate recovery codes", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// The confirmation code is spent so it cannot be replayed as a login code.
+	if _, err = h.mfa.UseMFAStep(email, step, totpStepTTL); err == nil {
+		_, err = h.auth.EnableMfa(r.Context(), &pb.EnableMfaRequest{
+			Email:              email,
+			Secret:             secret,
+			RecoveryCodeHashes: hashes,
+		})
+	}
+	if err != nil {
+		h.logger.Error("failed to enable 2fa", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	})
+
+	h.logger.Info("user 2fa enabled", zap.String("email", email))
+}
+
+// UserLoginMFA redeems the challenge issued by a login for an account with
+// 2FA enabled, using a TOTP code or a recovery code.
+func (h *Handler) UserLoginMFA(w http.ResponseWriter, r *http.Request) {
+	var l models.MFALogin
+	if !h.decodeJSON(w, r, &l) {
+		return
+	}
+
+	if err := l.Validate(); err != nil {
+		h.logger.Error("2fa login data is not valid", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.mfa.GetMFAChallenge(l.ChallengeToken)
+	if err != nil {
+		h.logger.Error("2fa challenge not found", zap.Error(err))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	valid, err := h.verifySecondFactor(r.Context(), c.Email, &l)
+	if err != nil {
+		h.logger.Error("failed to verify second factor", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		attempts, err := h.mfa.FailMFAChallenge(l.ChallengeToken, mfaChallengeTTL)
+		if err != nil || attempts >= mfaMaxAttempts {
+			_ = h.mfa.DeleteMFAChallenge(l.ChallengeToken)
+		}
+		h.logger.Error("invalid 2fa code", zap.String("email", c.Email), zap.Int64("attempts", attempts))
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	if err = h.mfa.DeleteMFAChallenge(l.ChallengeToken); err != nil {
+		h.logger.Error("failed to delete 2fa challenge from redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.saveSession(r, c.AccessToken, c.RefreshToken)
+	if err != nil {
+		h.logger.Error("failed to save access token to redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeLoginTokens(w, c.AccessToken, c.RefreshToken)
+
+	h.logger.Info("user 2fa login success", zap.String("email", c.Email))
+}
+
+func (h *Handler) verifySecondFactor(ctx context.Context, email string, l *models.MFALogin) (bool, error) {
+	secret, err := h.mfaSecret(ctx, email)
+	if err != nil || secret == "" {
+		return false, err
+	}
+
+	if l.RecoveryCode != "" {
+		res, err := h.auth.UseRecoveryCode(ctx, &pb.UseRecoveryCodeRequest{
+			Email:    email,
+			CodeHash: hashRecoveryCode(l.RecoveryCode),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		return res.GetIsValid(), nil
+	}
+
+	step, ok := totp.Validate(secret, l.Code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return h.mfa.UseMFAStep(email, step, totpStepTTL)
+}
+
+// completeLogin finishes a successful first-factor login. Accounts with 2FA
+// get a challenge token instead of the tokens, which are kept until the
+// challenge is redeemed. It reports whether the response was written without errors.
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, accessToken, refreshToken string) bool {
+	challenge, ok := h.startLogin(w, r, accessToken, refreshToken)
+	if !ok {
+		return false
+	}
+
+	if challenge == "" {
+		writeLoginTokens(w, accessToken, refreshToken)
+		return true
+	}
+
+	writeJSON(w, http.StatusAccepted, struct {
+		MFARequired    bool   `json:"mfa_required"`
+		ChallengeToken string `json:"challenge_token"`
+	}{
+		MFARequired:    true,
+		ChallengeToken: challenge,
+	})
+
+	return true
+}
+
+// startLogin saves the session of a successful first-factor login, or the
+// 2FA challenge of the tokens for accounts with 2FA, returning its token. It
+// writes an error response and reports false if that fails.
+func (h *Handler) startLogin(w http.ResponseWriter, r *http.Request, accessToken, refreshToken string) (string, bool) {
+	e := &token.AccessTokenExtractor{}
+	email, err := e.ExtractEmail(accessToken)
+	if err != nil {
+		h.logger.Error("failed to extract email from access token", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return "", false
+	}
+
+	secret, err := h.mfaSecret(r.Context(), email)
+	if err != nil {
+		h.logger.Error("failed to get 2fa secret", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return "", false
+	}
+
+	if secret == "" {
+		err = h.saveSession(r, accessToken, refreshToken)
+		if err != nil {
+			h.logger.Error("failed to save access token to redis store", zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return "", false
+		}
+
+		return "", true
+	}
+
+	challenge, err := oauth.RandomString()
+	if err == nil {
+		err = h.mfa.SaveMFAChallenge(challenge, store.MFAChallenge{
+			Email:        email,
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+		}, mfaChallengeTTL)
+	}
+	if err != nil {
+		h.logger.Error("failed to save 2fa challenge to redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return "", false
+	}
+
+	return challenge, true
+}
+
+// mfaSecret returns an empty secret when 2FA is not enabled for email. A
+// secret still kept in Redis by an older gateway is moved to the Auth service.
+func (h *Handler) mfaSecret(ctx context.Context, email string) (string, error) {
+	res, err := h.auth.GetMfa(ctx, &pb.GetMfaRequest{Email: email})
+	if err != nil {
+		return "", err
+	}
+	if res.GetSecret() != "" {
+		return res.GetSecret(), nil
+	}
+
+	secret, codes, err := h.mfa.GetLegacyMFA(email)
+	if err != nil || secret == "" {
+		return "", err
+	}
+
+	_, err = h.auth.EnableMfa(ctx, &pb.EnableMfaRequest{
+		Email:              email,
+		Secret:             secret,
+		RecoveryCodeHashes: codes,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err = h.mfa.DeleteLegacyMFA(email); err != nil {
+		h.logger.Warn("failed to delete migrated 2fa secret from redis store", zap.Error(err))
+	}
+
+	return secret, nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes formatted for the user and the hashes to store.
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc"
+	"time"
 	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/oauth"
+	store "workmap/gateway/internal/redis"
 )
 
 // MockAuthServiceClient is a mock for AuthServiceClient
@@ -26,36 +29,193 @@ func (m *MockAuthServiceClient) Login(ctx context.Context, in *pb.LoginRequest,
 }
 
 func (m *MockAuthServiceClient) Logout(ctx context.Context, in *pb.LogoutRequest, opts ...grpc.CallOption) (*pb.LogoutReply, error) {
-	return nil, nil
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.LogoutReply), args.Error(1)
 }
 
 func (m *MockAuthServiceClient) RefreshToken(ctx context.Context, in *pb.RefreshTokenRequest, opts ...grpc.CallOption) (*pb.RefreshTokenReply, error) {
 	return nil, nil
 }
 
+func (m *MockAuthServiceClient) ExternalLogin(ctx context.Context, in *pb.ExternalLoginRequest, opts ...grpc.CallOption) (*pb.LoginReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.LoginReply), args.Error(1)
+}
+
+func (m *MockAuthServiceClient) AddPasskey(ctx context.Context, in *pb.AddPasskeyRequest, opts ...grpc.CallOption) (*pb.AddPasskeyReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.AddPasskeyReply), args.Error(1)
+}
+
+func (m *MockAuthServiceClient) ListPasskeys(ctx context.Context, in *pb.ListPasskeysRequest, opts ...grpc.CallOption) (*pb.ListPasskeysReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.ListPasskeysReply), args.Error(1)
+}
+
+func (m *MockAuthServiceClient) PasskeyLogin(ctx context.Context, in *pb.PasskeyLoginRequest, opts ...grpc.CallOption) (*pb.LoginReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.LoginReply), args.Error(1)
+}
+
+func (m *MockAuthServiceClient) EnableMfa(ctx context.Context, in *pb.EnableMfaRequest, opts ...grpc.CallOption) (*pb.EnableMfaReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.EnableMfaReply), args.Error(1)
+}
+
+func (m *MockAuthServiceClient) GetMfa(ctx context.Context, in *pb.GetMfaRequest, opts ...grpc.CallOption) (*pb.GetMfaReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.GetMfaReply), args.Error(1)
+}
+
+func (m *MockAuthServiceClient) UseRecoveryCode(ctx context.Context, in *pb.UseRecoveryCodeRequest, opts ...grpc.CallOption) (*pb.UseRecoveryCodeReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.UseRecoveryCodeReply), args.Error(1)
+}
+
+func (m *MockAuthServiceClient) RevokeRefreshToken(ctx context.Context, in *pb.RevokeRefreshTokenRequest, opts ...grpc.CallOption) (*pb.RevokeRefreshTokenReply, error) {
+	args := m.Called(ctx, in)
+
+	return args.Get(0).(*pb.RevokeRefreshTokenReply), args.Error(1)
+}
+
 // MockRedis is a mock for Redis
 type MockRedis struct {
 	mock.Mock
 }
 
-func (m *MockRedis) GetAccessToken(accessToken string) error {
+func (m *MockRedis) GetAccessToken(ctx context.Context, accessToken string) (store.Session, error) {
 	args := m.Called(accessToken)
 
+	return args.Get(0).(store.Session), args.Error(1)
+}
+
+func (m *MockRedis) GetAccessTokens(ctx context.Context, accessTokens []string) (map[string]store.Session, error) {
+	args := m.Called(accessTokens)
+
+	return args.Get(0).(map[string]store.Session), args.Error(1)
+}
+
+func (m *MockRedis) SaveAccessToken(ctx context.Context, accessToken string, s store.Session) error {
+	args := m.Called(accessToken, s)
+
 	return args.Error(0)
 }
 
-func (m *MockRedis) SaveAccessToken(accessToken string) error {
+func (m *MockRedis) DeleteAccessToken(ctx context.Context, accessToken string) error {
 	args := m.Called(accessToken)
 
 	return args.Error(0)
 }
 
-func (m *MockRedis) DeleteAccessToken(accessToken string) error {
-	args := m.Called(accessToken)
+func (m *MockRedis) DeleteAccessTokens(ctx context.Context, accessTokens []string) error {
+	args := m.Called(accessTokens)
 
 	return args.Error(0)
 }
 
+// MockOAuthStateStore is a mock for OAuth state storage
+type MockOAuthStateStore struct {
+	mock.Mock
+}
+
+func (m *MockOAuthStateStore) SaveOAuthState(state string, s store.OAuthState, ttl time.Duration) error {
+	args := m.Called(state, s, ttl)
+
+	return args.Error(0)
+}
+
+func (m *MockOAuthStateStore) TakeOAuthState(state string) (store.OAuthState, error) {
+	args := m.Called(state)
+
+	return args.Get(0).(store.OAuthState), args.Error(1)
+}
+
+// MockMFAStore is a mock for 2FA storage
+type MockMFAStore struct {
+	mock.Mock
+}
+
+func (m *MockMFAStore) SavePendingMFASecret(email, secret string, ttl time.Duration) error {
+	args := m.Called(email, secret, ttl)
+
+	return args.Error(0)
+}
+
+func (m *MockMFAStore) GetPendingMFASecret(email string) (string, error) {
+	args := m.Called(email)
+
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMFAStore) GetLegacyMFA(email string) (string, []string, error) {
+	args := m.Called(email)
+
+	return args.String(0), args.Get(1).([]string), args.Error(2)
+}
+
+func (m *MockMFAStore) DeleteLegacyMFA(email string) error {
+	args := m.Called(email)
+
+	return args.Error(0)
+}
+
+func (m *MockMFAStore) UseMFAStep(email string, step int64, ttl time.Duration) (bool, error) {
+	args := m.Called(email, step, ttl)
+
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAStore) SaveMFAChallenge(challenge string, c store.MFAChallenge, ttl time.Duration) error {
+	args := m.Called(challenge, c, ttl)
+
+	return args.Error(0)
+}
+
+func (m *MockMFAStore) GetMFAChallenge(challenge string) (store.MFAChallenge, error) {
+	args := m.Called(challenge)
+
+	return args.Get(0).(store.MFAChallenge), args.Error(1)
+}
+
+func (m *MockMFAStore) FailMFAChallenge(challenge string, ttl time.Duration) (int64, error) {
+	args := m.Called(challenge, ttl)
+
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockMFAStore) DeleteMFAChallenge(challenge string) error {
+	args := m.Called(challenge)
+
+	return args.Error(0)
+}
+
+// MockOAuthProvider is a mock for an external OAuth provider
+type MockOAuthProvider struct {
+	mock.Mock
+}
+
+func (m *MockOAuthProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return "https://provider.example/authorize?state=" + state
+}
+
+func (m *MockOAuthProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*oauth.Identity, error) {
+	args := m.Called(code, verifier, nonce)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*oauth.Identity), args.Error(1)
+}
+
 type MockValidator struct {
 	mock.Mock
 }
@@ -67,3 +227,77 @@ func (m *MockValidator) Validate() error {
 
 	return args.Error(0)
 }
+
+// MockWebAuthnSessionStore is a mock for WebAuthnSessionStore
+type MockWebAuthnSessionStore struct {
+	mock.Mock
+}
+
+func (m *MockWebAuthnSessionStore) SaveWebAuthnSession(id string, s store.WebAuthnSession, ttl time.Duration) error {
+	args := m.Called(id, s, ttl)
+
+	return args.Error(0)
+}
+
+func (m *MockWebAuthnSessionStore) TakeWebAuthnSession(id string) (store.WebAuthnSession, error) {
+	args := m.Called(id)
+
+	return args.Get(0).(store.WebAuthnSession), args.Error(1)
+}
+
+// MockAPIKeyStore is a mock for APIKeyStore
+type MockAPIKeyStore struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyStore) GetAPIKey(hash string) (store.APIKey, error) {
+	args := m.Called(hash)
+
+	return args.Get(0).(store.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStore) SaveAPIKey(hash string, k store.APIKey) error {
+	args := m.Called(hash, k)
+
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyStore) ListAPIKeys(email string) ([]store.APIKey, error) {
+	args := m.Called(email)
+
+	return args.Get(0).([]store.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStore) DeleteAPIKey(email, id string) (bool, error) {
+	args := m.Called(email, id)
+
+	return args.Bool(0), args.Error(1)
+}
+
+// MockSessionStore is a mock for SessionStore
+type MockSessionStore struct {
+	mock.Mock
+}
+
+func (m *MockSessionStore) ListSessions(ctx context.Context, email string) ([]store.Session, error) {
+	args := m.Called(email)
+
+	return args.Get(0).([]store.Session), args.Error(1)
+}
+
+func (m *MockSessionStore) RevokeSession(ctx context.Context, email, id string) (bool, error) {
+	args := m.Called(email, id)
+
+	return args.Bool(0), args.Error(1)
+}
+
+// MockIntrospector is a mock for the token lookups of introspection.Service
+type MockIntrospector struct {
+	mock.Mock
+}
+
+func (m *MockIntrospector) Session(ctx context.Context, accessToken string) (store.Session, bool, error) {
+	args := m.Called(accessToken)
+
+	return args.Get(0).(store.Session), args.Bool(1), args.Error(2)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/url"
+	"time"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/oauth"
+	"workmap/gateway/internal/redis"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthStart redirects the user to the provider's consent page, starting the
+// authorization code flow with PKCE.
+func (h *Handler) OAuthStart(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	p, ok := h.oauthProviders[name]
+	if !ok {
+		h.logger.Error("unknown oauth provider", zap.String("provider", name))
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var state, nonce, verifier string
+	for _, v := range []*string{&state, &nonce, &verifier} {
+		s, err := oauth.RandomString()
+		if err != nil {
+			h.logger.Error("failed to generate oauth state", zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		*v = s
+	}
+
+	err := h.oauthStates.SaveOAuthState(state, store.OAuthState{
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, oauthStateTTL)
+	if err != nil {
+		h.logger.Error("failed to save oauth state to redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, p.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// OAuthCallback completes the authorization code flow and logs the user in
+// with the identity confirmed by the provider. The browser is redirected to
+// the frontend with the refresh token cookie, or with the challenge_token
+// query parameter when the user has 2FA.
+func (h *Handler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	p, ok := h.oauthProviders[name]
+	if !ok {
+		h.logger.Error("unknown oauth provider", zap.String("provider", name))
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		h.logger.Error("oauth provider returned error", zap.String("provider", name), zap.String("error", e))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s, err := h.oauthStates.TakeOAuthState(q.Get("state"))
+	if err != nil || s.Provider != name {
+		h.logger.Error("invalid oauth state", zap.String("provider", name), zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	identity, err := p.Exchange(r.Context(), q.Get("code"), s.CodeVerifier, s.Nonce)
+	if err != nil {
+		h.logger.Error("failed to exchange oauth code", zap.String("provider", name), zap.Error(err))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	res, err := h.auth.ExternalLogin(r.Context(), &pb.ExternalLoginRequest{
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+	})
+	if err != nil {
+		if e, ok := status.FromError(err); ok {
+			h.logger.Error(
+				"failed auth request",
+				zap.String("code", e.Code().String()),
+				zap.String("description", e.Proto().Message),
+			)
+
+			if e.Code() == codes.PermissionDenied {
+				http.Error(w, e.Proto().Message, http.StatusForbidden)
+				return
+			}
+
+			http.Error(w, e.Proto().Message, http.StatusBadRequest)
+			return
+		}
+
+		h.logger.Error("unexpected error", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	challenge, ok := h.startLogin(w, r, res.AccessToken, res.RefreshToken)
+	if !ok {
+		return
+	}
+
+	u, err := url.Parse(h.oauthFrontendURL)
+	if err != nil {
+		h.logger.Error("invalid oauth frontend url", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if challenge == "" {
+		setRefreshTokenCookie(w, res.RefreshToken)
+	} else {
+		q := u.Query()
+		q.Set("challenge_token", challenge)
+		u.RawQuery = q.Encode()
+	}
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+
+	h.logger.Info("user oauth login success", zap.String("provider", name), zap.String("email", identity.Email))
+}
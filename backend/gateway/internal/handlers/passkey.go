@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"time"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/models"
+	"workmap/gateway/internal/oauth"
+	"workmap/gateway/internal/redis"
+)
+
+const passkeySessionTTL = 5 * time.Minute
+
+// PasskeyRegisterBegin returns the options for navigator.credentials.create()
+// and the session the created credential has to be sent back with.
+func (h *Handler) PasskeyRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	if h.webAuthn == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	user, ok := h.loadPasskeyUser(r.Context(), w, email)
+	if !ok {
+		return
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, len(user.credentials))
+	for i, c := range user.credentials {
+		exclusions[i] = c.Descriptor()
+	}
+
+	creation, session, err := h.webAuthn.BeginRegistration(
+		user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			UserVerification: protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		h.logger.Error("failed to begin passkey registration", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	id, ok := h.savePasskeySession(w, store.WebAuthnSession{
+		Ceremony: store.CeremonyRegistration,
+		Email:    email,
+		Data:     *session,
+	})
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		SessionID string `json:"session_id"`
+		*protocol.CredentialCreation
+	}{
+		SessionID:          id,
+		CredentialCreation: creation,
+	})
+}
+
+// PasskeyRegisterFinish verifies the attestation of the new credential and
+// stores its public key with the Auth service.
+func (h *Handler) PasskeyRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	if h.webAuthn == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	s, err := h.webAuthnSessions.TakeWebAuthnSession(r.URL.Query().Get("session_id"))
+	if err != nil || s.Ceremony != store.CeremonyRegistration || s.Email != email {
+		h.logger.Error("invalid passkey registration session", zap.String("email", email), zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	credential, err := h.webAuthn.FinishRegistration(&passkeyUser{email: email}, s.Data, r)
+	if err != nil {
+		h.logger.Error("failed to verify passkey attestation", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.auth.AddPasskey(r.Context(), &pb.AddPasskeyRequest{
+		Email:   email,
+		Passkey: passkeyToProto(credential),
+	})
+	if err != nil {
+		h.authError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	h.logger.Info("user passkey registered", zap.String("email", email))
+}
+
+// PasskeyLoginBegin returns the options for navigator.credentials.get()
+// limited to the passkeys registered for the email.
+func (h *Handler) PasskeyLoginBegin(w http.ResponseWriter, r *http.Request) {
+	if h.webAuthn == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var l models.PasskeyLogin
+	if !h.decodeJSON(w, r, &l) {
+		return
+	}
+
+	if err := l.Validate(); err != nil {
+		h.logger.Error("passkey login data is not valid", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.loadPasskeyUser(r.Context(), w, l.Email)
+	if !ok {
+		return
+	}
+
+	if len(user.credentials) == 0 {
+		http.Error(w, "No passkeys registered", http.StatusBadRequest)
+		return
+	}
+
+	assertion, session, err := h.webAuthn.BeginLogin(user, webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		h.logger.Error("failed to begin passkey login", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	id, ok := h.savePasskeySession(w, store.WebAuthnSession{
+		Ceremony: store.CeremonyLogin,
+		Email:    l.Email,
+		Data:     *session,
+	})
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		SessionID string `json:"session_id"`
+		*protocol.CredentialAssertion
+	}{
+		SessionID:           id,
+		CredentialAssertion: assertion,
+	})
+}
+
+// PasskeyLoginFinish verifies the assertion and logs the user in. A passkey
+// with user verification is already two factors, so 2FA is not asked for.
+func (h *Handler) PasskeyLoginFinish(w http.ResponseWriter, r *http.Request) {
+	if h.webAuthn == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	s, err := h.webAuthnSessions.TakeWebAuthnSession(r.URL.Query().Get("session_id"))
+	if err != nil || s.Ceremony != store.CeremonyLogin {
+		h.logger.Error("invalid passkey login session", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := h.loadPasskeyUser(r.Context(), w, s.Email)
+	if !ok {
+		return
+	}
+
+	credential, err := h.webAuthn.FinishLogin(user, s.Data, r)
+	if err != nil {
+		h.logger.Error("failed to verify passkey assertion", zap.String("email", s.Email), zap.Error(err))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if credential.Authenticator.CloneWarning {
+		h.logger.Error("passkey signature counter went backwards, possible cloned authenticator", zap.String("email", s.Email))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	res, err := h.auth.PasskeyLogin(r.Context(), &pb.PasskeyLoginRequest{
+		Email:        s.Email,
+		CredentialId: credential.ID,
+		SignCount:    credential.Authenticator.SignCount,
+		BackupState:  credential.Flags.BackupState,
+	})
+	if err != nil {
+		h.authError(w, err)
+		return
+	}
+
+	err = h.saveSession(r, res.AccessToken, res.RefreshToken)
+	if err != nil {
+		h.logger.Error("failed to save access token to redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeLoginTokens(w, res.AccessToken, res.RefreshToken)
+
+	h.logger.Info("user passkey login success", zap.String("email", s.Email))
+}
+
+func (h *Handler) savePasskeySession(w http.ResponseWriter, s store.WebAuthnSession) (string, bool) {
+	id, err := oauth.RandomString()
+	if err == nil {
+		err = h.webAuthnSessions.SaveWebAuthnSession(id, s, passkeySessionTTL)
+	}
+	if err != nil {
+		h.logger.Error("failed to save webauthn session to redis store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return "", false
+	}
+
+	return id, true
+}
+
+// loadPasskeyUser loads the passkeys registered for email, writing an error
+// response if the Auth service refuses.
+func (h *Handler) loadPasskeyUser(ctx context.Context, w http.ResponseWriter, email string) (*passkeyUser, bool) {
+	res, err := h.auth.ListPasskeys(ctx, &pb.ListPasskeysRequest{Email: email})
+	if err != nil {
+		h.authError(w, err)
+		return nil, false
+	}
+
+	u := &passkeyUser{email: email}
+	for _, p := range res.Passkeys {
+		u.credentials = append(u.credentials, passkeyFromProto(p))
+	}
+
+	return u, true
+}
+
+func (h *Handler) authError(w http.ResponseWriter, err error) {
+	if e, ok := status.FromError(err); ok {
+		h.logger.Error(
+			"failed auth request",
+			zap.String("code", e.Code().String()),
+			zap.String("description", e.Proto().Message),
+		)
+		http.Error(w, e.Proto().Message, http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Error("unexpected error", zap.Error(err))
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
+
+// passkeyUser adapts an account and its passkeys to webauthn.User.
+type passkeyUser struct {
+	email       string
+	credentials []webauthn.Credential
+}
+
+// WebAuthnID is derived from the email, the only user identifier the gateway
+// has, so that it is the same for every ceremony without being stored.
+func (u *passkeyUser) WebAuthnID() []byte {
+	sum := sha256.Sum256([]byte(u.email))
+
+	return sum[:]
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.email
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.email
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+func passkeyToProto(c *webauthn.Credential) *pb.Passkey {
+	transports := make([]string, len(c.Transport))
+	for i, t := range c.Transport {
+		transports[i] = string(t)
+	}
+
+	return &pb.Passkey{
+		CredentialId:    c.ID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transports:      transports,
+		Aaguid:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		BackupEligible:  c.Flags.BackupEligible,
+		BackupState:     c.Flags.BackupState,
+	}
+}
+
+func passkeyFromProto(p *pb.Passkey) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, len(p.Transports))
+	for i, t := range p.Transports {
+		transports[i] = protocol.AuthenticatorTransport(t)
+	}
+
+	return webauthn.Credential{
+		ID:              p.CredentialId,
+		PublicKey:       p.PublicKey,
+		AttestationType: p.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: p.BackupEligible,
+			BackupState:    p.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    p.Aaguid,
+			SignCount: p.SignCount,
+		},
+	}
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	store "workmap/gateway/internal/redis"
+)
+
+const (
+	passkeyTestRPID   = "localhost"
+	passkeyTestOrigin = "http://localhost:3000"
+)
+
+// softAuthenticator answers WebAuthn ceremonies the way a browser with a
+// platform authenticator would, with an ES256 key and "none" attestation.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+
+	return &softAuthenticator{key: key, credentialID: id}
+}
+
+// authData builds the authenticator data, with the attested credential when
+// attested is set.
+func (a *softAuthenticator) authData(t *testing.T, rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attested {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if !attested {
+		return data
+	}
+
+	data = append(data, make([]byte, 16)...) // AAGUID
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+	data = append(data, a.credentialID...)
+
+	return append(data, a.publicKey(t)...)
+}
+
+// publicKey returns the credential public key in COSE format.
+func (a *softAuthenticator) publicKey(t *testing.T) []byte {
+	key, err := cbor.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	return key
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony protocol.CeremonyType, challenge []byte, origin string) []byte {
+	data, err := json.Marshal(map[string]string{
+		"type":      string(ceremony),
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	require.NoError(t, err)
+
+	return data
+}
+
+// create returns the body navigator.credentials.create() would have produced.
+func (a *softAuthenticator) create(t *testing.T, options protocol.PublicKeyCredentialCreationOptions, origin string) []byte {
+	attestation, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(t, options.RelyingParty.ID, true),
+	})
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64(a.clientData(t, protocol.CreateCeremony, options.Challenge, origin)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// get returns the body navigator.credentials.get() would have produced.
+func (a *softAuthenticator) get(t *testing.T, options protocol.PublicKeyCredentialRequestOptions, origin string) []byte {
+	a.signCount++
+
+	authData := a.authData(t, options.RelyingPartyID, false)
+	clientData := a.clientData(t, protocol.AssertCeremony, options.Challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+	})
+}
+
+func (a *softAuthenticator) credential(t *testing.T, response map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       b64(a.credentialID),
+		"rawId":    b64(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+
+	return data
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newTestWebAuthn(t *testing.T) *webauthn.WebAuthn {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          passkeyTestRPID,
+		RPDisplayName: "Work Map",
+		RPOrigins:     []string{passkeyTestOrigin},
+	})
+	require.NoError(t, err)
+
+	return w
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	logger := zap.NewNop()
+	wa := newTestWebAuthn(t)
+
+	tests := []struct {
+		name           string
+		origin         string
+		sessionEmail   string
+		mockAuthError  error
+		expectedStatus int
+	}{
+		{
+			name:           "valid attestation",
+			origin:         passkeyTestOrigin,
+			sessionEmail:   mfaTestEmail,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "wrong origin",
+			origin:         "https://phishing.example",
+			sessionEmail:   mfaTestEmail,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "session of another user",
+			origin:         passkeyTestOrigin,
+			sessionEmail:   "other@email.com",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "auth service error",
+			origin:         passkeyTestOrigin,
+			sessionEmail:   mfaTestEmail,
+			mockAuthError:  status.New(codes.AlreadyExists, "Passkey already registered").Err(),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(MockAuthServiceClient)
+			mockSessions := new(MockWebAuthnSessionStore)
+			authenticator := newSoftAuthenticator(t)
+
+			handler := &Handler{
+				logger:           logger,
+				auth:             mockAuthService,
+				webAuthn:         wa,
+				webAuthnSessions: mockSessions,
+			}
+
+			mockAuthService.On("ListPasskeys", mock.Anything, &pb.ListPasskeysRequest{Email: mfaTestEmail}).Return(&pb.ListPasskeysReply{}, nil)
+			mockAuthService.On("AddPasskey", mock.Anything, mock.Anything).Return(&pb.AddPasskeyReply{IsSuccess: true}, tt.mockAuthError)
+			mockSessions.On("SaveWebAuthnSession", mock.Anything, mock.Anything, passkeySessionTTL).Return(nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/user/passkey/register/begin", nil)
+			req = withPrincipal(req, mfaTestEmail)
+			rr := httptest.NewRecorder()
+			handler.PasskeyRegisterBegin(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var begin struct {
+				SessionID string                                      `json:"session_id"`
+				PublicKey protocol.PublicKeyCredentialCreationOptions `json:"publicKey"`
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&begin))
+
+			saved := mockSessions.Calls[0].Arguments
+			assert.Equal(t, begin.SessionID, saved.Get(0))
+			session := saved.Get(1).(store.WebAuthnSession)
+			assert.Equal(t, store.CeremonyRegistration, session.Ceremony)
+			session.Email = tt.sessionEmail
+			mockSessions.On("TakeWebAuthnSession", begin.SessionID).Return(session, nil)
+
+			body := authenticator.create(t, begin.PublicKey, tt.origin)
+			req = httptest.NewRequest(http.MethodPost, "/user/passkey/register/finish?session_id="+begin.SessionID, bytes.NewReader(body))
+			req = withPrincipal(req, mfaTestEmail)
+			rr = httptest.NewRecorder()
+			handler.PasskeyRegisterFinish(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus != http.StatusCreated {
+				return
+			}
+
+			added := mockAuthService.Calls[1].Arguments.Get(1).(*pb.AddPasskeyRequest)
+			assert.Equal(t, mfaTestEmail, added.Email)
+			assert.Equal(t, authenticator.credentialID, added.Passkey.CredentialId)
+			assert.Equal(t, "none", added.Passkey.AttestationType)
+			assert.NotEmpty(t, added.Passkey.PublicKey)
+		})
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	logger := zap.NewNop()
+	wa := newTestWebAuthn(t)
+
+	registered := newSoftAuthenticator(t)
+	passkey := &pb.Passkey{
+		CredentialId:    registered.credentialID,
+		PublicKey:       registered.publicKey(t),
+		AttestationType: "none",
+	}
+
+	tests := []struct {
+		name           string
+		authenticator  *softAuthenticator
+		mockRedisError error
+		expectedStatus int
+	}{
+		{
+			name:           "valid assertion",
+			authenticator:  registered,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "signed with another key",
+			authenticator: &softAuthenticator{
+				key:          newSoftAuthenticator(t).key,
+				credentialID: registered.credentialID,
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "save access token error",
+			authenticator:  registered,
+			mockRedisError: errors.New("tokenStore error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(MockAuthServiceClient)
+			mockRedis := new(MockRedis)
+			mockSessions := new(MockWebAuthnSessionStore)
+
+			handler := &Handler{
+				logger:           logger,
+				auth:             mockAuthService,
+				tokenStore:       mockRedis,
+				webAuthn:         wa,
+				webAuthnSessions: mockSessions,
+			}
+
+			mockAuthService.On("ListPasskeys", mock.Anything, &pb.ListPasskeysRequest{Email: mfaTestEmail}).Return(&pb.ListPasskeysReply{
+				Passkeys: []*pb.Passkey{passkey},
+			}, nil)
+			mockAuthService.On("PasskeyLogin", mock.Anything, mock.Anything).Return(&pb.LoginReply{
+				AccessToken:  mfaTestToken,
+				RefreshToken: "refresh-token",
+			}, nil)
+			mockSessions.On("SaveWebAuthnSession", mock.Anything, mock.Anything, passkeySessionTTL).Return(nil)
+			mockRedis.On("SaveAccessToken", mfaTestToken, mock.Anything).Return(tt.mockRedisError)
+
+			req := httptest.NewRequest(http.MethodPost, "/user/login/passkey/begin", strings.NewReader(`{"email":"`+mfaTestEmail+`"}`))
+			rr := httptest.NewRecorder()
+			handler.PasskeyLoginBegin(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var begin struct {
+				SessionID string                                     `json:"session_id"`
+				PublicKey protocol.PublicKeyCredentialRequestOptions `json:"publicKey"`
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&begin))
+
+			session := mockSessions.Calls[0].Arguments.Get(1).(store.WebAuthnSession)
+			assert.Equal(t, store.CeremonyLogin, session.Ceremony)
+			assert.Equal(t, mfaTestEmail, session.Email)
+			mockSessions.On("TakeWebAuthnSession", begin.SessionID).Return(session, nil)
+
+			body := tt.authenticator.get(t, begin.PublicKey, passkeyTestOrigin)
+			req = httptest.NewRequest(http.MethodPost, "/user/login/passkey/finish?session_id="+begin.SessionID, bytes.NewReader(body))
+			rr = httptest.NewRecorder()
+			handler.PasskeyLoginFinish(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				mockAuthService.AssertNotCalled(t, "PasskeyLogin", mock.Anything, mock.Anything)
+				return
+			}
+
+			mockAuthService.AssertCalled(t, "PasskeyLogin", mock.Anything, &pb.PasskeyLoginRequest{
+				Email:        mfaTestEmail,
+				CredentialId: registered.credentialID,
+				SignCount:    tt.authenticator.signCount,
+			})
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "Bearer "+mfaTestToken, rr.Header().Get("Authorization"))
+				assert.Contains(t, rr.Header().Get("Set-Cookie"), "refresh_token=refresh-token")
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginBegin(t *testing.T) {
+	logger := zap.NewNop()
+
+	tests := []struct {
+		name           string
+		webAuthn       *webauthn.WebAuthn
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "no passkeys registered",
+			webAuthn:       newTestWebAuthn(t),
+			body:           `{"email":"` + mfaTestEmail + `"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid email",
+			webAuthn:       newTestWebAuthn(t),
+			body:           `{"email":"user"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "passkeys disabled",
+			body:           `{"email":"` + mfaTestEmail + `"}`,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(MockAuthServiceClient)
+			mockSessions := new(MockWebAuthnSessionStore)
+
+			handler := &Handler{
+				logger:           logger,
+				auth:             mockAuthService,
+				webAuthn:         tt.webAuthn,
+				webAuthnSessions: mockSessions,
+			}
+
+			mockAuthService.On("ListPasskeys", mock.Anything, mock.Anything).Return(&pb.ListPasskeysReply{}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/user/login/passkey/begin", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handler.PasskeyLoginBegin(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			mockSessions.AssertNotCalled(t, "SaveWebAuthnSession", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/redis"
+)
+
+// ListSessions returns the live sessions of the caller in its tenant,
+// marking the one of the request.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.sessions.ListSessions(r.Context(), email)
+	if errors.Is(err, store.ErrUnsupported) {
+		http.Error(w, "Not implemented", http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to list sessions from token store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	type session struct {
+		store.Session
+		Current bool `json:"current"`
+	}
+
+	current := store.SessionID(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	res := make([]session, len(sessions))
+	for i, s := range sessions {
+		s.RefreshTokenHash = ""
+		res[i] = session{Session: s, Current: s.ID == current}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// RevokeSession logs the caller's session with the given ID out, revoking
+// the refresh token it was issued with in the Auth service too. Sessions
+// refreshed from the same login share that refresh token, so they can no
+// longer be refreshed either.
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.PathValue("id")
+	sessions, err := h.sessions.ListSessions(r.Context(), email)
+	if errors.Is(err, store.ErrUnsupported) {
+		http.Error(w, "Not implemented", http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to list sessions from token store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var refreshTokenHash string
+	for _, s := range sessions {
+		if s.ID == id {
+			refreshTokenHash = s.RefreshTokenHash
+		}
+	}
+
+	// The refresh token goes first, so that a failure leaves the session
+	// in place to retry with.
+	if refreshTokenHash != "" {
+		_, err = h.auth.RevokeRefreshToken(r.Context(), &pb.RevokeRefreshTokenRequest{
+			Email:            email,
+			RefreshTokenHash: refreshTokenHash,
+		})
+		if err != nil {
+			h.logger.Error("failed to revoke refresh token in auth service", zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	revoked, err := h.sessions.RevokeSession(r.Context(), email, id)
+	if err != nil {
+		h.logger.Error("failed to revoke session in token store", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if !revoked {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	h.logger.Info("user session revoked", zap.String("email", email), zap.String("id", id))
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	store "workmap/gateway/internal/redis"
+)
+
+func TestListSessions(t *testing.T) {
+	mockSessions := new(MockSessionStore)
+	handler := &Handler{logger: zap.NewNop(), sessions: mockSessions}
+
+	sessions := []store.Session{
+		{ID: store.SessionID("other_token"), Email: mfaTestEmail},
+		{ID: store.SessionID("access_token"), Email: mfaTestEmail, RefreshTokenHash: store.SessionID("refresh_token")},
+	}
+	mockSessions.On("ListSessions", mfaTestEmail).Return(sessions, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/user/sessions", nil)
+	req.Header.Set("Authorization", "Bearer access_token")
+	req = withPrincipal(req, mfaTestEmail)
+	rr := httptest.NewRecorder()
+	handler.ListSessions(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var res []struct {
+		ID      string `json:"id"`
+		Current bool   `json:"current"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&res))
+	require.Len(t, res, 2)
+	assert.False(t, res[0].Current)
+	assert.True(t, res[1].Current)
+	assert.Equal(t, sessions[1].ID, res[1].ID)
+	assert.NotContains(t, rr.Body.String(), "refresh_token_hash")
+}
+
+func TestListSessions_Unsupported(t *testing.T) {
+	handler := New(&Config{Logger: zap.NewNop()})
+
+	req := withPrincipal(httptest.NewRequest(http.MethodGet, "/user/sessions", nil), mfaTestEmail)
+	rr := httptest.NewRecorder()
+	handler.ListSessions(rr, req)
+
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
+
+func TestRevokeSession(t *testing.T) {
+	logger := zap.NewNop()
+	refreshTokenHash := store.SessionID("refresh_token")
+
+	tests := []struct {
+		name           string
+		mockSessions   []store.Session
+		mockListError  error
+		mockAuthError  error
+		mockRevoked    bool
+		mockStoreError error
+		expectAuth     bool
+		expectRevoke   bool
+		expectedStatus int
+	}{
+		{
+			name:           "revoked with refresh token",
+			mockSessions:   []store.Session{{ID: "session-id", RefreshTokenHash: refreshTokenHash}},
+			mockRevoked:    true,
+			expectAuth:     true,
+			expectRevoke:   true,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "revoked without refresh token",
+			mockSessions:   []store.Session{{ID: "session-id"}},
+			mockRevoked:    true,
+			expectRevoke:   true,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "unknown session",
+			mockSessions:   []store.Session{{ID: "other-id", RefreshTokenHash: refreshTokenHash}},
+			mockRevoked:    false,
+			expectRevoke:   true,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unsupported store",
+			mockListError:  store.ErrUnsupported,
+			expectedStatus: http.StatusNotImplemented,
+		},
+		{
+			name:           "list error",
+			mockListError:  errors.New("redis error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "auth service error",
+			mockSessions:   []store.Session{{ID: "session-id", RefreshTokenHash: refreshTokenHash}},
+			mockAuthError:  errors.New("auth service unavailable"),
+			expectAuth:     true,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "store error",
+			mockSessions:   []store.Session{{ID: "session-id"}},
+			mockStoreError: errors.New("redis error"),
+			expectRevoke:   true,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSessions := new(MockSessionStore)
+			mockAuth := new(MockAuthServiceClient)
+			handler := &Handler{logger: logger, sessions: mockSessions, auth: mockAuth}
+
+			mockSessions.On("ListSessions", mfaTestEmail).Return(tt.mockSessions, tt.mockListError)
+			mockAuth.On("RevokeRefreshToken", mock.Anything, &pb.RevokeRefreshTokenRequest{
+				Email:            mfaTestEmail,
+				RefreshTokenHash: refreshTokenHash,
+			}).Return(&pb.RevokeRefreshTokenReply{IsRevoked: true}, tt.mockAuthError)
+			mockSessions.On("RevokeSession", mfaTestEmail, "session-id").Return(tt.mockRevoked, tt.mockStoreError)
+
+			req := httptest.NewRequest(http.MethodDelete, "/user/sessions/session-id", nil)
+			req.SetPathValue("id", "session-id")
+			req = withPrincipal(req, mfaTestEmail)
+			rr := httptest.NewRecorder()
+			handler.RevokeSession(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectAuth {
+				mockAuth.AssertCalled(t, "RevokeRefreshToken", mock.Anything, mock.Anything)
+			} else {
+				mockAuth.AssertNotCalled(t, "RevokeRefreshToken", mock.Anything, mock.Anything)
+			}
+			if tt.expectRevoke {
+				mockSessions.AssertCalled(t, "RevokeSession", mfaTestEmail, "session-id")
+			} else {
+				mockSessions.AssertNotCalled(t, "RevokeSession", mfaTestEmail, "session-id")
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"go.uber.org/zap"
@@ -17,12 +16,9 @@ import (
 
 func (h *Handler) UserRegister(w http.ResponseWriter, r *http.Request) {
 	var u models.User
-	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		h.logger.Error("failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &u) {
 		return
 	}
-	defer r.Body.Close()
 
 	if err := u.Validate(); err != nil {
 		h.logger.Error("user data is not valid", zap.Error(err))
@@ -30,7 +26,7 @@ func (h *Handler) UserRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.auth.Register(context.TODO(), &pb.RegisterRequest{
+	res, err := h.auth.Register(r.Context(), &pb.RegisterRequest{
 		Email:    u.Email,
 		Password: u.Password,
 	})
@@ -56,7 +52,7 @@ func (h *Handler) UserRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.tokenStore.SaveAccessToken(res.AccessToken)
+	err = h.saveSession(r, res.AccessToken, res.RefreshToken)
 	if err != nil {
 		h.logger.Error("failed to save access token to redis store", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -93,12 +89,9 @@ func (h *Handler) UserRegister(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) UserLogin(w http.ResponseWriter, r *http.Request) {
 	var u models.User
-	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
-		h.logger.Error("failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !h.decodeJSON(w, r, &u) {
 		return
 	}
-	defer r.Body.Close()
 
 	if err := u.Validate(); err != nil {
 		h.logger.Error("user data is not valid", zap.Error(err))
@@ -106,7 +99,7 @@ func (h *Handler) UserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.auth.Login(context.TODO(), &pb.LoginRequest{
+	res, err := h.auth.Login(r.Context(), &pb.LoginRequest{
 		Email:    u.Email,
 		Password: u.Password,
 	})
@@ -127,15 +120,27 @@ func (h *Handler) UserLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.tokenStore.SaveAccessToken(res.AccessToken)
-	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if !h.completeLogin(w, r, res.AccessToken, res.RefreshToken) {
 		return
 	}
 
+	h.logger.Info("user login success", zap.String("email", u.Email))
+}
+
+// writeLoginTokens sends the token pair the way every login flow does:
+// the refresh token as a cookie and the access token in the Authorization header.
+func writeLoginTokens(w http.ResponseWriter, accessToken, refreshToken string) {
+	setRefreshTokenCookie(w, refreshToken)
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	w.WriteHeader(http.StatusOK)
+}
+
+func setRefreshTokenCookie(w http.ResponseWriter, refreshToken string) {
 	cookie := &http.Cookie{
 		Name:   "refresh_token",
-		Value:  res.RefreshToken,
+		Value:  refreshToken,
 		Path:   "/",
 		MaxAge: 604800,
 		//Secure:   true,
@@ -144,12 +149,6 @@ func (h *Handler) UserLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	http.SetCookie(w, cookie)
-
-	w.Header().Set("Content-Type", "text/plain")
-	w.Header().Set("Authorization", fmt.Sprintf("Bearer %s", res.AccessToken))
-	w.WriteHeader(http.StatusOK)
-
-	h.logger.Info("user login success", zap.String("email", u.Email))
 }
 
 func (h *Handler) UserRefreshToken(w http.ResponseWriter, r *http.Request) {
@@ -161,7 +160,7 @@ func (h *Handler) UserRefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 	rt := cookie.Value
 
-	res, err := h.auth.RefreshToken(context.TODO(), &pb.RefreshTokenRequest{
+	res, err := h.auth.RefreshToken(r.Context(), &pb.RefreshTokenRequest{
 		RefreshToken: rt,
 	})
 	if err != nil {
@@ -180,7 +179,7 @@ func (h *Handler) UserRefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.tokenStore.SaveAccessToken(res.AccessToken)
+	err = h.saveSession(r, res.AccessToken, rt)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -226,7 +225,7 @@ func (h *Handler) UserLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.auth.Logout(context.TODO(), &pb.LogoutRequest{
+	res, err := h.auth.Logout(r.Context(), &pb.LogoutRequest{
 		RefreshToken: rt,
 	})
 	if err != nil {
@@ -247,7 +246,7 @@ func (h *Handler) UserLogout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if res.IsSuccess {
-		err = h.tokenStore.DeleteAccessToken(at)
+		err = h.tokenStore.DeleteAccessToken(r.Context(), at)
 		if err != nil {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
@@ -262,20 +261,15 @@ func (h *Handler) UserLogout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UserProfile(w http.ResponseWriter, r *http.Request) {
-	bearer := r.Header.Get("Authorization")
-	at := strings.TrimPrefix(bearer, "Bearer ")
-
-	e := &token.AccessTokenExtractor{}
-	email, err := e.ExtractEmail(at)
-	if err != nil {
-		h.logger.Error("failed to extract email", zap.Error(err))
-		http.Error(w, "Bad request", http.StatusBadRequest)
+	email, ok := h.principalEmail(w, r)
+	if !ok {
+		return
 	}
 
 	w.WriteHeader(200)
 	w.Header().Set("Content-Type", "application/json")
 
-	err = json.NewEncoder(w).Encode(struct {
+	err := json.NewEncoder(w).Encode(struct {
 		Email string `json:"email"`
 	}{
 		Email: email,
@@ -124,7 +124,7 @@ func TestUserLogin(t *testing.T) {
 			},
 			mockAuthResponse: &pb.LoginReply{
 				RefreshToken: rt,
-				AccessToken:  "wrongToken",
+				AccessToken:  at,
 			},
 			mockAuthError:   nil,
 			mockRedisError:  errors.New("tokenStore error"),
@@ -137,6 +137,7 @@ func TestUserLogin(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockAuthService := new(MockAuthServiceClient)
 			mockRedis := new(MockRedis)
+			mockMFA := new(MockMFAStore)
 
 			var mockRedisStore store.TokenStore = mockRedis
 
@@ -144,10 +145,13 @@ func TestUserLogin(t *testing.T) {
 				logger:     logger,
 				auth:       mockAuthService,
 				tokenStore: mockRedisStore,
+				mfa:        mockMFA,
 			}
 
 			mockAuthService.On("Login", mock.Anything, mock.Anything).Return(tt.mockAuthResponse, tt.mockAuthError)
-			mockRedis.On("SaveAccessToken", mock.Anything).Return(tt.mockRedisError)
+			mockRedis.On("SaveAccessToken", mock.Anything, mock.Anything).Return(tt.mockRedisError)
+			mockAuthService.On("GetMfa", mock.Anything, mock.Anything).Return(&pb.GetMfaReply{}, nil)
+			mockMFA.On("GetLegacyMFA", mock.Anything).Return("", []string(nil), nil)
 
 			body, err := json.Marshal(tt.input)
 			if err != nil {
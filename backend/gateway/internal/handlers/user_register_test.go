@@ -174,7 +174,7 @@ func TestUserRegister(t *testing.T) {
 			}
 
 			mockAuthService.On("Register", mock.Anything, mock.Anything).Return(tt.mockAuthResponse, tt.mockAuthError)
-			mockRedis.On("SaveAccessToken", mock.Anything).Return(tt.mockRedisError)
+			mockRedis.On("SaveAccessToken", mock.Anything, mock.Anything).Return(tt.mockRedisError)
 			mockValidator.On("Validate").Return()
 
 			body, err := json.Marshal(tt.input)
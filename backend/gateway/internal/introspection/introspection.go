@@ -0,0 +1,226 @@
+// Package introspection serves the TokenIntrospection gRPC service, which
+// lets the internal services check access tokens and API keys the way the
+// auth middleware does. Its Session lookup also serves the OAuth
+// introspection endpoint.
+package introspection
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"time"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/pkg/apikey"
+	"workmap/gateway/internal/pkg/token"
+	"workmap/gateway/internal/principal"
+	"workmap/gateway/internal/redis"
+	"workmap/gateway/internal/tenant"
+)
+
+// ErrUnavailable is returned by Session when the token store fails and the
+// token cannot be verified locally.
+var ErrUnavailable = errors.New("token store unavailable")
+
+// TenantMetadata names the tenant of the token in the metadata of a call,
+// like the one the host of its HTTP requests maps to, ahead of the tenant
+// the token claims.
+const TenantMetadata = "x-tenant"
+
+type Config struct {
+	Logger  *zap.Logger
+	Tokens  TokenStore
+	APIKeys store.APIKeyGetter
+	// DegradedJWTSecret verifies the access tokens locally while the token
+	// store is unavailable, nil to answer Unavailable instead.
+	DegradedJWTSecret []byte
+	// Tenants finds the tenant an access token was saved for by its claim,
+	// none if nil.
+	Tenants *tenant.Resolver
+}
+
+// TokenStore is the part of store.TokenStore the service uses.
+type TokenStore interface {
+	store.TokenGetter
+	store.TokenDeleter
+}
+
+type Service struct {
+	pb.UnimplementedTokenIntrospectionServer
+
+	logger            *zap.Logger
+	tokens            TokenStore
+	apiKeys           store.APIKeyGetter
+	degradedJWTSecret []byte
+	tenants           *tenant.Resolver
+}
+
+func New(cfg *Config) *Service {
+	return &Service{
+		logger:            cfg.Logger,
+		tokens:            cfg.Tokens,
+		apiKeys:           cfg.APIKeys,
+		degradedJWTSecret: cfg.DegradedJWTSecret,
+		tenants:           cfg.Tenants,
+	}
+}
+
+func (s *Service) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenReply, error) {
+	if req.AccessToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "access token is required")
+	}
+	ctx, err := withMetadataTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, degraded, err := s.Session(ctx, req.AccessToken)
+	if errors.Is(err, store.ErrNotFound) {
+		return &pb.ValidateTokenReply{Active: false}, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	return &pb.ValidateTokenReply{
+		Active: true,
+		Session: &pb.Session{
+			Id:        session.ID,
+			UserId:    session.UserID,
+			Email:     session.Email,
+			IssuedAt:  unix(session.IssuedAt),
+			ExpiresAt: unix(session.ExpiresAt),
+			ClientIp:  session.Client.IP,
+			UserAgent: session.Client.UserAgent,
+		},
+		Degraded: degraded,
+	}, nil
+}
+
+func (s *Service) GetPrincipal(ctx context.Context, req *pb.GetPrincipalRequest) (*pb.GetPrincipalReply, error) {
+	ctx, err := withMetadataTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := req.Credential.(type) {
+	case *pb.GetPrincipalRequest_ApiKey:
+		k, err := s.apiKeys.GetAPIKey(apikey.Hash(c.ApiKey))
+		if errors.Is(err, store.ErrNotFound) {
+			s.logger.Info("api key not found", zap.Error(err))
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if !k.ExpiresAt.After(time.Now()) {
+			return nil, status.Error(codes.Unauthenticated, "api key expired")
+		}
+
+		return &pb.GetPrincipalReply{Principal: &pb.Principal{
+			Kind:     principal.KindAPIKey,
+			Email:    k.Email,
+			ApiKeyId: k.ID,
+			Scopes:   k.Scopes,
+		}}, nil
+
+	case *pb.GetPrincipalRequest_AccessToken:
+		session, degraded, err := s.Session(ctx, c.AccessToken)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		if session.Email == "" {
+			return nil, status.Error(codes.Unauthenticated, "session without email")
+		}
+
+		return &pb.GetPrincipalReply{
+			Principal: &pb.Principal{Kind: principal.KindUser, UserId: session.UserID, Email: session.Email},
+			Degraded:  degraded,
+		}, nil
+	}
+
+	return nil, status.Error(codes.InvalidArgument, "access token or api key is required")
+}
+
+// RevokeToken succeeds for tokens already gone, so that it can be retried.
+func (s *Service) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenReply, error) {
+	if req.AccessToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "access token is required")
+	}
+	ctx, err := withMetadataTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.tokens.DeleteAccessToken(s.withTenant(ctx, req.AccessToken), req.AccessToken); err != nil {
+		s.logger.Error("failed to delete access token", zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "token store unavailable")
+	}
+
+	return &pb.RevokeTokenReply{}, nil
+}
+
+// Session looks accessToken up in the token store, or verifies it locally
+// while the store is unavailable, reporting so with degraded. Unknown and
+// invalid tokens are store.ErrNotFound, expired ones too.
+func (s *Service) Session(ctx context.Context, accessToken string) (session store.Session, degraded bool, err error) {
+	session, err = s.tokens.GetAccessToken(s.withTenant(ctx, accessToken), accessToken)
+	if err == nil && !session.ExpiresAt.IsZero() && !session.ExpiresAt.After(time.Now()) {
+		return store.Session{}, false, store.ErrNotFound
+	}
+	if err == nil || errors.Is(err, store.ErrNotFound) {
+		return session, false, err
+	}
+
+	s.logger.Error("failed to get access token from token store", zap.Error(err))
+	if s.degradedJWTSecret == nil {
+		return store.Session{}, false, ErrUnavailable
+	}
+	c, err := token.Verify(accessToken, s.degradedJWTSecret)
+	if err != nil {
+		return store.Session{}, true, store.ErrNotFound
+	}
+
+	return store.Session{UserID: c.Subject, Email: c.Email, IssuedAt: c.IssuedAt, ExpiresAt: c.ExpiresAt}, true, nil
+}
+
+// withMetadataTenant puts the tenant of the TenantMetadata of the call in
+// ctx, if any.
+func withMetadataTenant(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get(TenantMetadata)
+	if len(v) == 0 || v[0] == "" {
+		return ctx, nil
+	}
+	if !tenant.Valid(v[0]) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid tenant %q", v[0])
+	}
+
+	return tenant.NewContext(ctx, v[0]), nil
+}
+
+// withTenant puts the tenant claimed by accessToken in ctx, the way the token
+// was saved, unless ctx has one already.
+func (s *Service) withTenant(ctx context.Context, accessToken string) context.Context {
+	if _, ok := tenant.FromContext(ctx); ok {
+		return ctx
+	}
+	if t, ok := s.tenants.FromToken(accessToken); ok {
+		return tenant.NewContext(ctx, t)
+	}
+
+	return ctx
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
@@ -0,0 +1,260 @@
+package introspection
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/memstore"
+	"workmap/gateway/internal/pkg/apikey"
+	"workmap/gateway/internal/redis"
+	"workmap/gateway/internal/redis/storetest"
+	"workmap/gateway/internal/tenant"
+)
+
+type mockAPIKeys map[string]store.APIKey
+
+func (m mockAPIKeys) GetAPIKey(hash string) (store.APIKey, error) {
+	k, ok := m[hash]
+	if !ok {
+		return store.APIKey{}, store.ErrNotFound
+	}
+	return k, nil
+}
+
+// downStore fails like an unavailable token store.
+type downStore struct{}
+
+func (downStore) GetAccessToken(ctx context.Context, accessToken string) (store.Session, error) {
+	return store.Session{}, errors.New("connection refused")
+}
+
+func (downStore) GetAccessTokens(ctx context.Context, accessTokens []string) (map[string]store.Session, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (downStore) DeleteAccessToken(ctx context.Context, accessToken string) error {
+	return errors.New("connection refused")
+}
+
+func (downStore) DeleteAccessTokens(ctx context.Context, accessTokens []string) error {
+	return errors.New("connection refused")
+}
+
+func TestValidateToken(t *testing.T) {
+	tokens := store.NewTokenStore(memstore.New(&memstore.Config{}), store.TokenKeyConfig{})
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, tokens.SaveAccessToken(context.Background(), at, session))
+
+	secret := []byte("secret")
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+		"sub":   "id-user@email.com",
+		"email": "user@email.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		tokens           TokenStore
+		secret           []byte
+		accessToken      string
+		expectedActive   bool
+		expectedEmail    string
+		expectedDegraded bool
+		expectedCode     codes.Code
+	}{
+		{name: "live token", tokens: tokens, accessToken: at, expectedActive: true, expectedEmail: "user@email.com"},
+		{name: "unknown token", tokens: tokens, accessToken: "unknown"},
+		{name: "missing token", tokens: tokens, expectedCode: codes.InvalidArgument},
+		{name: "store down", tokens: downStore{}, accessToken: signed, expectedCode: codes.Unavailable},
+		{name: "store down, verified", tokens: downStore{}, secret: secret, accessToken: signed, expectedActive: true, expectedEmail: "user@email.com", expectedDegraded: true},
+		{name: "store down, invalid", tokens: downStore{}, secret: []byte("other"), accessToken: signed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&Config{Logger: zap.NewNop(), Tokens: tt.tokens, DegradedJWTSecret: tt.secret})
+
+			reply, err := s.ValidateToken(context.Background(), &pb.ValidateTokenRequest{AccessToken: tt.accessToken})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.expectedActive, reply.Active)
+			assert.Equal(t, tt.expectedDegraded, reply.Degraded)
+			assert.Equal(t, tt.expectedEmail, reply.GetSession().GetEmail())
+			if tt.expectedActive {
+				assert.Equal(t, "id-user@email.com", reply.Session.UserId)
+				assert.NotZero(t, reply.Session.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestGetPrincipal(t *testing.T) {
+	tokens := store.NewTokenStore(memstore.New(&memstore.Config{}), store.TokenKeyConfig{})
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, tokens.SaveAccessToken(context.Background(), at, session))
+	apiKeys := mockAPIKeys{
+		apikey.Hash("wmk_live"):    {ID: "k1", Email: "user@email.com", Scopes: []string{"profile:read"}, ExpiresAt: time.Now().Add(time.Hour)},
+		apikey.Hash("wmk_expired"): {ID: "k2", Email: "user@email.com", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	s := New(&Config{Logger: zap.NewNop(), Tokens: tokens, APIKeys: apiKeys})
+
+	tests := []struct {
+		name         string
+		req          *pb.GetPrincipalRequest
+		expected     *pb.Principal
+		expectedCode codes.Code
+	}{
+		{
+			name:     "access token",
+			req:      &pb.GetPrincipalRequest{Credential: &pb.GetPrincipalRequest_AccessToken{AccessToken: at}},
+			expected: &pb.Principal{Kind: "user", UserId: "id-user@email.com", Email: "user@email.com"},
+		},
+		{
+			name:     "api key",
+			req:      &pb.GetPrincipalRequest{Credential: &pb.GetPrincipalRequest_ApiKey{ApiKey: "wmk_live"}},
+			expected: &pb.Principal{Kind: "api_key", Email: "user@email.com", ApiKeyId: "k1", Scopes: []string{"profile:read"}},
+		},
+		{
+			name:         "unknown access token",
+			req:          &pb.GetPrincipalRequest{Credential: &pb.GetPrincipalRequest_AccessToken{AccessToken: "unknown"}},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "expired api key",
+			req:          &pb.GetPrincipalRequest{Credential: &pb.GetPrincipalRequest_ApiKey{ApiKey: "wmk_expired"}},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "no credential",
+			req:          &pb.GetPrincipalRequest{},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := s.GetPrincipal(context.Background(), tt.req)
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expected != nil {
+				assert.Equal(t, tt.expected.Kind, reply.Principal.Kind)
+				assert.Equal(t, tt.expected.UserId, reply.Principal.UserId)
+				assert.Equal(t, tt.expected.Email, reply.Principal.Email)
+				assert.Equal(t, tt.expected.ApiKeyId, reply.Principal.ApiKeyId)
+				assert.Equal(t, tt.expected.Scopes, reply.Principal.Scopes)
+			}
+		})
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	tokens := store.NewTokenStore(memstore.New(&memstore.Config{}), store.TokenKeyConfig{})
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, tokens.SaveAccessToken(context.Background(), at, session))
+	s := New(&Config{Logger: zap.NewNop(), Tokens: tokens})
+
+	_, err := s.RevokeToken(context.Background(), &pb.RevokeTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+	_, err = tokens.GetAccessToken(context.Background(), at)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = s.RevokeToken(context.Background(), &pb.RevokeTokenRequest{AccessToken: at})
+	assert.NoError(t, err, "revoking twice")
+
+	_, err = s.RevokeToken(context.Background(), &pb.RevokeTokenRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	down := New(&Config{Logger: zap.NewNop(), Tokens: downStore{}})
+	_, err = down.RevokeToken(context.Background(), &pb.RevokeTokenRequest{AccessToken: at})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+// tenantStore records the tenant of each lookup and deletion.
+type tenantStore struct {
+	downStore
+	tenants []string
+}
+
+func (s *tenantStore) GetAccessToken(ctx context.Context, accessToken string) (store.Session, error) {
+	t, _ := tenant.FromContext(ctx)
+	s.tenants = append(s.tenants, t)
+	return store.Session{}, store.ErrNotFound
+}
+
+func (s *tenantStore) DeleteAccessToken(ctx context.Context, accessToken string) error {
+	t, _ := tenant.FromContext(ctx)
+	s.tenants = append(s.tenants, t)
+	return nil
+}
+
+func TestTenant(t *testing.T) {
+	at, err := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+		"email":  "user@email.com",
+		"tenant": "acme",
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	tokens := &tenantStore{}
+	s := New(&Config{Logger: zap.NewNop(), Tokens: tokens, Tenants: tenant.NewResolver(&tenant.Config{Claim: "tenant"})})
+
+	_, err = s.ValidateToken(context.Background(), &pb.ValidateTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+	_, err = s.RevokeToken(context.Background(), &pb.RevokeTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+	_, err = s.ValidateToken(tenant.NewContext(context.Background(), "other"), &pb.ValidateTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"acme", "acme", "other"}, tokens.tenants)
+}
+
+func TestTenant_Metadata(t *testing.T) {
+	mr := miniredis.RunT(t)
+	host, port, err := net.SplitHostPort(mr.Addr())
+	require.NoError(t, err)
+	r, err := store.NewRedis(&store.RedisConfig{Host: host, Port: port})
+	require.NoError(t, err)
+	tokens := store.NewTokenStore(&r, store.TokenKeyConfig{})
+
+	// The token of a host-mapped tenant claims none.
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, tokens.SaveAccessToken(tenant.NewContext(context.Background(), "acme"), at, session))
+	s := New(&Config{Logger: zap.NewNop(), Tokens: tokens, Tenants: tenant.NewResolver(&tenant.Config{Hosts: map[string]string{"acme.work-map.test": "acme"}})})
+	withTenant := func(name string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(TenantMetadata, name))
+	}
+
+	reply, err := s.ValidateToken(context.Background(), &pb.ValidateTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+	assert.False(t, reply.Active, "the token is unknown to the default tenant")
+
+	reply, err = s.ValidateToken(withTenant("acme"), &pb.ValidateTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+	assert.True(t, reply.Active)
+	p, err := s.GetPrincipal(withTenant("acme"), &pb.GetPrincipalRequest{Credential: &pb.GetPrincipalRequest_AccessToken{AccessToken: at}})
+	require.NoError(t, err)
+	assert.Equal(t, "user@email.com", p.Principal.Email)
+
+	_, err = s.ValidateToken(withTenant("Acme Inc"), &pb.ValidateTokenRequest{AccessToken: at})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.RevokeToken(withTenant("acme"), &pb.RevokeTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+	reply, err = s.ValidateToken(withTenant("acme"), &pb.ValidateTokenRequest{AccessToken: at})
+	require.NoError(t, err)
+	assert.False(t, reply.Active)
+}
@@ -0,0 +1,154 @@
+// Package lifecycle starts the components of the gateway in dependency order
+// and stops them in reverse, after draining the traffic.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Component is a part of the gateway with resources to release.
+type Component struct {
+	Name string
+	// Start starts the component, nil for nothing to start. Its context is
+	// cancelled once the component is stopped, so background work can keep
+	// it.
+	Start func(ctx context.Context) error
+	// Stop releases the component before the deadline of ctx, nil for nothing
+	// but cancelling the context of Start.
+	Stop func(ctx context.Context) error
+	// Err receives the error of a component failing once started, nil if it
+	// cannot fail.
+	Err <-chan error
+}
+
+// Drainer reports whether the gateway is draining before a shutdown.
+type Drainer interface {
+	Draining() bool
+}
+
+type Config struct {
+	Logger *zap.Logger
+}
+
+// Manager runs the components added to it. It is started and shut down once.
+type Manager struct {
+	logger *zap.Logger
+
+	mu         sync.Mutex
+	components []Component
+	cancels    []context.CancelFunc
+	started    int
+
+	draining atomic.Bool
+	failed   chan error
+}
+
+func New(cfg *Config) *Manager {
+	return &Manager{
+		logger: cfg.Logger,
+		failed: make(chan error, 1),
+	}
+}
+
+// Add appends c to the components, after the ones it depends on.
+func (m *Manager) Add(c Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.components = append(m.components, c)
+}
+
+// Start starts the components in the order they were added. If one fails,
+// the ones already started are stopped within the deadline of ctx, if any.
+// The cancellation of ctx is not passed on to the components.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.components[m.started:] {
+		cctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		if c.Start != nil {
+			if err := c.Start(cctx); err != nil {
+				cancel()
+				err = fmt.Errorf("start %s: %w", c.Name, err)
+				return errors.Join(err, m.stop(ctx))
+			}
+		}
+		m.cancels = append(m.cancels, cancel)
+		m.started++
+		m.logger.Debug("component started", zap.String("component", c.Name))
+
+		if c.Err != nil {
+			go m.watch(c)
+		}
+	}
+
+	return nil
+}
+
+// Failed receives the first error of a started component.
+func (m *Manager) Failed() <-chan error {
+	return m.failed
+}
+
+// Draining reports whether Shutdown was called.
+func (m *Manager) Draining() bool {
+	return m.draining.Load()
+}
+
+// Shutdown reports the gateway as draining for drain, so that load balancers
+// stop sending it traffic, then stops the components in reverse order within
+// timeout. Every component is stopped even if some fail to.
+func (m *Manager) Shutdown(drain, timeout time.Duration) error {
+	m.draining.Store(true)
+	if drain > 0 {
+		m.logger.Info("draining before shutdown", zap.Duration("period", drain))
+		time.Sleep(drain)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stop(ctx)
+}
+
+// stop must be called with mu held.
+func (m *Manager) stop(ctx context.Context) error {
+	var errs []error
+	for ; m.started > 0; m.started-- {
+		i := m.started - 1
+		c := m.components[i]
+		if c.Stop != nil {
+			if err := c.Stop(ctx); err != nil {
+				m.logger.Error("failed to stop component", zap.String("component", c.Name), zap.Error(err))
+				errs = append(errs, fmt.Errorf("stop %s: %w", c.Name, err))
+			}
+		}
+		m.cancels[i]()
+		m.logger.Debug("component stopped", zap.String("component", c.Name))
+	}
+	m.cancels = nil
+
+	return errors.Join(errs...)
+}
+
+func (m *Manager) watch(c Component) {
+	err, ok := <-c.Err
+	if !ok || err == nil {
+		return
+	}
+
+	select {
+	case m.failed <- fmt.Errorf("%s: %w", c.Name, err):
+	default:
+	}
+}
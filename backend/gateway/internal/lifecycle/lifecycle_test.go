@@ -0,0 +1,122 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// recorder records the starts and stops of its components.
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) component(name string, startErr, stopErr error) Component {
+	return Component{
+		Name: name,
+		Start: func(context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+func TestManager(t *testing.T) {
+	t.Run("stops in reverse order", func(t *testing.T) {
+		r := &recorder{}
+		m := New(&Config{Logger: zap.NewNop()})
+		m.Add(r.component("redis", nil, nil))
+		m.Add(r.component("worker", nil, nil))
+		m.Add(r.component("server", nil, nil))
+
+		require.NoError(t, m.Start(context.Background()))
+		require.NoError(t, m.Shutdown(0, time.Second))
+		assert.Equal(t, []string{
+			"start redis", "start worker", "start server",
+			"stop server", "stop worker", "stop redis",
+		}, r.events)
+	})
+
+	t.Run("stops the started components if one fails to start", func(t *testing.T) {
+		r := &recorder{}
+		m := New(&Config{Logger: zap.NewNop()})
+		m.Add(r.component("redis", nil, nil))
+		m.Add(r.component("server", errors.New("address already in use"), nil))
+		m.Add(r.component("never", nil, nil))
+
+		err := m.Start(context.Background())
+		assert.EqualError(t, err, "start server: address already in use")
+		assert.Equal(t, []string{"start redis", "start server", "stop redis"}, r.events)
+	})
+
+	t.Run("stops every component despite errors", func(t *testing.T) {
+		r := &recorder{}
+		m := New(&Config{Logger: zap.NewNop()})
+		m.Add(r.component("redis", nil, errors.New("already closed")))
+		m.Add(r.component("server", nil, context.DeadlineExceeded))
+
+		require.NoError(t, m.Start(context.Background()))
+		err := m.Shutdown(0, time.Second)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualError(t, err, "stop server: context deadline exceeded\nstop redis: already closed")
+		assert.Equal(t, []string{"start redis", "start server", "stop server", "stop redis"}, r.events)
+	})
+
+	t.Run("cancels the start context on stop", func(t *testing.T) {
+		m := New(&Config{Logger: zap.NewNop()})
+		var worker context.Context
+		m.Add(Component{Name: "worker", Start: func(ctx context.Context) error {
+			worker = ctx
+			return nil
+		}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, m.Start(ctx))
+		cancel()
+		assert.NoError(t, worker.Err(), "the start context outlives the context of Start")
+
+		require.NoError(t, m.Shutdown(0, time.Second))
+		assert.ErrorIs(t, worker.Err(), context.Canceled)
+	})
+
+	t.Run("drains before stopping", func(t *testing.T) {
+		m := New(&Config{Logger: zap.NewNop()})
+		var drained bool
+		m.Add(Component{Name: "server", Stop: func(context.Context) error {
+			drained = m.Draining()
+			return nil
+		}})
+
+		require.NoError(t, m.Start(context.Background()))
+		assert.False(t, m.Draining())
+
+		begin := time.Now()
+		require.NoError(t, m.Shutdown(50*time.Millisecond, time.Second))
+		assert.GreaterOrEqual(t, time.Since(begin), 50*time.Millisecond)
+		assert.True(t, drained)
+	})
+
+	t.Run("reports failures", func(t *testing.T) {
+		m := New(&Config{Logger: zap.NewNop()})
+		errc := make(chan error, 1)
+		m.Add(Component{Name: "server", Err: errc})
+
+		require.NoError(t, m.Start(context.Background()))
+		errc <- errors.New("listener closed")
+
+		select {
+		case err := <-m.Failed():
+			assert.EqualError(t, err, "server: listener closed")
+		case <-time.After(time.Second):
+			t.Fatal("the failure was not reported")
+		}
+	})
+}
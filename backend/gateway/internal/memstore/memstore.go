@@ -0,0 +1,176 @@
+// Package memstore is an in-process token store for a single gateway
+// instance and development. Tokens are lost on restart.
+package memstore
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+	"workmap/gateway/internal/redis"
+)
+
+const (
+	defaultShards = 32
+	defaultSweep  = time.Minute
+)
+
+func init() {
+	store.RegisterTokenBackend("memory", func(cfg *store.TokenBackendConfig) (store.SessionBackend, error) {
+		return New(&Config{
+			Shards: cfg.MemoryShards,
+			Sweep:  cfg.MemorySweep,
+		}), nil
+	})
+}
+
+type Config struct {
+	// Shards is the number of independently locked maps, 32 if zero.
+	Shards int
+	// Sweep is how often expired tokens are evicted, every minute if zero.
+	Sweep time.Duration
+}
+
+// Store keeps the sessions in maps sharded by the hash of their key, so that
+// concurrent requests rarely wait on the same lock. It is the backend of a
+// store.TokenStore, see store.NewTokenStore.
+type Store struct {
+	shards []*shard
+	now    func() time.Time
+	stop   chan struct{}
+	once   sync.Once
+}
+
+type shard struct {
+	mu       sync.RWMutex
+	sessions map[string]store.Session
+}
+
+// New returns a store and starts evicting its expired tokens until Close.
+func New(cfg *Config) *Store {
+	n := cfg.Shards
+	if n <= 0 {
+		n = defaultShards
+	}
+	sweep := cfg.Sweep
+	if sweep <= 0 {
+		sweep = defaultSweep
+	}
+
+	s := &Store{
+		shards: make([]*shard, n),
+		now:    time.Now,
+		stop:   make(chan struct{}),
+	}
+	for i := range s.shards {
+		s.shards[i] = &shard{sessions: make(map[string]store.Session)}
+	}
+
+	go s.evict(sweep)
+
+	return s
+}
+
+func (s *Store) GetSessions(ctx context.Context, keys []string) (map[string]store.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[string]store.Session)
+	for _, key := range keys {
+		if session, ok := s.get(key); ok {
+			sessions[key] = session
+		}
+	}
+
+	return sessions, nil
+}
+
+func (s *Store) SaveSession(ctx context.Context, key string, session store.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !s.now().Before(session.ExpiresAt) {
+		return nil
+	}
+
+	sh := s.shard(key)
+	sh.mu.Lock()
+	sh.sessions[key] = session
+	sh.mu.Unlock()
+
+	return nil
+}
+
+func (s *Store) DeleteSessions(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		sh := s.shard(key)
+		sh.mu.Lock()
+		delete(sh.sessions, key)
+		sh.mu.Unlock()
+	}
+
+	return nil
+}
+
+// get ignores expired sessions the sweep has not evicted yet.
+func (s *Store) get(key string) (store.Session, bool) {
+	sh := s.shard(key)
+
+	sh.mu.RLock()
+	session, ok := sh.sessions[key]
+	sh.mu.RUnlock()
+
+	if !ok || !s.now().Before(session.ExpiresAt) {
+		return store.Session{}, false
+	}
+
+	return session, true
+}
+
+// Close stops the eviction of expired tokens.
+func (s *Store) Close() error {
+	s.once.Do(func() { close(s.stop) })
+
+	return nil
+}
+
+func (s *Store) shard(key string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *Store) evict(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+// sweep deletes the expired tokens one shard at a time.
+func (s *Store) sweep() {
+	now := s.now()
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		for k, session := range sh.sessions {
+			if !now.Before(session.ExpiresAt) {
+				delete(sh.sessions, k)
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
@@ -0,0 +1,88 @@
+package memstore
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync"
+	"testing"
+	"time"
+	store "workmap/gateway/internal/redis"
+	"workmap/gateway/internal/redis/storetest"
+)
+
+// fakeClock lets the suite move time forward without waiting.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newTestStore(t *testing.T) (*Store, *fakeClock) {
+	clock := &fakeClock{now: time.Now()}
+	s := New(&Config{Shards: 4, Sweep: time.Hour})
+	s.now = clock.Now
+	t.Cleanup(func() { s.Close() })
+
+	return s, clock
+}
+
+func TestConformance(t *testing.T) {
+	storetest.TestTokenStore(t, func(t *testing.T) (store.SessionBackend, func(time.Duration)) {
+		s, clock := newTestStore(t)
+
+		return s, clock.Add
+	})
+}
+
+func TestSweep(t *testing.T) {
+	s, clock := newTestStore(t)
+
+	ctx := context.Background()
+	_, shortSession := storetest.Session(t, "short@email.com", time.Now().Add(time.Minute))
+	_, longSession := storetest.Session(t, "long@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, s.SaveSession(ctx, "short", shortSession))
+	require.NoError(t, s.SaveSession(ctx, "long", longSession))
+
+	clock.Add(2 * time.Minute)
+	s.sweep()
+
+	var n int
+	for _, sh := range s.shards {
+		n += len(sh.sessions)
+	}
+	assert.Equal(t, 1, n)
+	sessions, err := s.GetSessions(ctx, []string{"long"})
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
+
+func TestRegistered(t *testing.T) {
+	assert.Contains(t, store.TokenBackends(), "memory")
+
+	s, err := store.OpenTokenStore("memory", &store.TokenBackendConfig{})
+	require.NoError(t, err)
+	defer s.(io.Closer).Close()
+
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, s.SaveAccessToken(context.Background(), at, session))
+	_, err = s.GetAccessToken(context.Background(), at)
+	assert.NoError(t, err)
+
+	m := New(&Config{})
+	defer m.Close()
+	assert.Len(t, m.shards, defaultShards)
+}
@@ -0,0 +1,75 @@
+// Package metricsserver serves the Prometheus metrics on their own port, out
+// of reach of the clients of the API.
+package metricsserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Port   string
+	Logger *zap.Logger
+}
+
+type Server struct {
+	httpServer *http.Server
+	logger     *zap.Logger
+	errc       chan error
+}
+
+func New(cfg *Config) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              fmt.Sprintf(":%s", cfg.Port),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		logger: cfg.Logger,
+		errc:   make(chan error, 1),
+	}
+}
+
+// Start listens on the port and serves in the background. Errors of the
+// listener after that are sent to Err.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			s.errc <- err
+		}
+	}()
+	s.logger.Info("metrics server is ready to handle requests", zap.String("address", s.httpServer.Addr))
+
+	return nil
+}
+
+// Err receives the error the server stopped serving with, if not stopped by
+// Stop.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
+// Stop waits for the scrapes in flight until ctx is done, then closes their
+// connections.
+func (s *Server) Stop(ctx context.Context) error {
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		s.httpServer.Close()
+		return err
+	}
+
+	return nil
+}
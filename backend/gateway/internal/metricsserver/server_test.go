@@ -0,0 +1,69 @@
+package metricsserver
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func newServer(t *testing.T) (*Server, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, ln.Close())
+
+	return New(&Config{Port: port, Logger: zap.NewNop()}), port
+}
+
+func TestServer(t *testing.T) {
+	t.Run("serves the metrics", func(t *testing.T) {
+		s, port := newServer(t)
+		require.NoError(t, s.Start(context.Background()))
+		defer s.Stop(context.Background())
+
+		resp, err := http.Get("http://127.0.0.1:" + port + "/metrics")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), "go_goroutines")
+	})
+
+	t.Run("serves nothing else", func(t *testing.T) {
+		s, port := newServer(t)
+		require.NoError(t, s.Start(context.Background()))
+		defer s.Stop(context.Background())
+
+		resp, err := http.Get("http://127.0.0.1:" + port + "/readyz")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		s, port := newServer(t)
+		require.NoError(t, s.Start(context.Background()))
+		require.NoError(t, s.Stop(context.Background()))
+
+		_, err := http.Get("http://127.0.0.1:" + port + "/metrics")
+		assert.Error(t, err)
+		assert.Empty(t, s.Err())
+	})
+
+	t.Run("fails to start on a taken port", func(t *testing.T) {
+		s, port := newServer(t)
+		require.NoError(t, s.Start(context.Background()))
+		defer s.Stop(context.Background())
+
+		other := New(&Config{Port: port, Logger: zap.NewNop()})
+		assert.Error(t, other.Start(context.Background()))
+	})
+}
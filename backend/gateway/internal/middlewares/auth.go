@@ -1,29 +1,161 @@
 package middlewares
 
 import (
+	"errors"
 	"go.uber.org/zap"
 	"net/http"
 	"strings"
+	"time"
+	"workmap/gateway/internal/pkg/apikey"
+	"workmap/gateway/internal/pkg/token"
+	"workmap/gateway/internal/principal"
+	"workmap/gateway/internal/redis"
 )
 
+// errDegradedRefused rejects a request that cannot be authenticated while the
+// token store is unavailable.
+var errDegradedRefused = errors.New("token store unavailable")
+
+// CheckAuth authenticates the request with either an access token in the
+// Authorization header or an API key in X-API-Key, and passes the resulting
+// principal to next in the request context.
 func (m *Middleware) CheckAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			m.logger.Error("token not found")
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		p, err := m.authenticate(r)
+		if se := (*storeError)(nil); errors.As(err, &se) {
+			m.logger.Error("failed to get access token from token store", zap.Error(err))
+			p, err = m.authenticateDegraded(r)
+		}
+		if errors.Is(err, errDegradedRefused) {
+			degradedAuth.WithLabelValues("refused").Inc()
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
 			return
 		}
-		m.logger.Debug(tokenString)
-		token := strings.TrimPrefix(tokenString, "Bearer ")
-
-		err := m.redis.GetAccessToken(token)
 		if err != nil {
 			m.logger.Error("token not found", zap.Error(err))
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
+		next.ServeHTTP(w, r.WithContext(principal.NewContext(r.Context(), p)))
+	}
+}
+
+// CheckAuthOptional is CheckAuth for the requests sending an access token or
+// an API key, so that next knows their principal, and lets the others
+// through without one.
+func (m *Middleware) CheckAuthOptional(next http.HandlerFunc) http.HandlerFunc {
+	checked := m.CheckAuth(next)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && r.Header.Get("X-API-Key") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		checked.ServeHTTP(w, r)
+	}
+}
+
+// RequireScope rejects principals without scope. It must be wrapped by CheckAuth.
+func (m *Middleware) RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, ok := principal.FromContext(r.Context())
+		if !ok || !p.HasScope(scope) {
+			m.logger.Error("principal lacks scope", zap.String("scope", scope))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
 		next.ServeHTTP(w, r)
 	}
 }
+
+func (m *Middleware) authenticate(r *http.Request) (*principal.Principal, error) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		k, err := m.apiKeys.GetAPIKey(apikey.Hash(key))
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, err
+			}
+
+			return nil, &storeError{err: err}
+		}
+		if !k.ExpiresAt.After(time.Now()) {
+			return nil, errors.New("api key expired")
+		}
+
+		return &principal.Principal{
+			Kind:     principal.KindAPIKey,
+			Email:    k.Email,
+			APIKeyID: k.ID,
+			Scopes:   k.Scopes,
+		}, nil
+	}
+
+	tokenString := r.Header.Get("Authorization")
+	if tokenString == "" {
+		return nil, errors.New("token not found")
+	}
+	at := strings.TrimPrefix(tokenString, "Bearer ")
+
+	s, err := m.redis.GetAccessToken(r.Context(), at)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+
+		return nil, &storeError{err: err}
+	}
+	if s.Email == "" {
+		return nil, errors.New("session without email")
+	}
+
+	return &principal.Principal{
+		Kind:   principal.KindUser,
+		UserID: s.UserID,
+		Email:  s.Email,
+	}, nil
+}
+
+// authenticateDegraded accepts the access token of a safe request if its
+// signature and expiry verify locally. Writes are refused, as the token may
+// have been logged out, and so is everything without DegradedJWTSecret and
+// every API key, which cannot be verified without the store.
+func (m *Middleware) authenticateDegraded(r *http.Request) (*principal.Principal, error) {
+	if m.degradedJWTSecret == nil || r.Header.Get("X-API-Key") != "" {
+		return nil, errDegradedRefused
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	default:
+		return nil, errDegradedRefused
+	}
+
+	at := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	c, err := token.Verify(at, m.degradedJWTSecret)
+	if err != nil {
+		degradedAuth.WithLabelValues("rejected").Inc()
+		return nil, err
+	}
+	degradedAuth.WithLabelValues("accepted").Inc()
+
+	return &principal.Principal{
+		Kind:   principal.KindUser,
+		UserID: c.Subject,
+		Email:  c.Email,
+	}, nil
+}
+
+// storeError is a failure of the token store rather than of the credentials.
+type storeError struct {
+	err error
+}
+
+func (e *storeError) Error() string {
+	return "token store: " + e.err.Error()
+}
+
+func (e *storeError) Unwrap() error {
+	return e.err
+}
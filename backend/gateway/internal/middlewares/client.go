@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// ClientScopeRevoke lets an OAuth client revoke tokens.
+const ClientScopeRevoke = "revoke"
+
+// CheckClient protects the OAuth endpoints of the resource servers, like
+// token introspection. The client authenticates with HTTP Basic, its ID and
+// secret form-encoded as in RFC 6749 2.3.1, or with the client_id and
+// client_secret form parameters. Unless scope is empty, the client must have
+// been granted it too.
+func (m *Middleware) CheckClient(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := clientCredentials(r)
+		want, found := m.oauthClients[id]
+		// Compared even for unknown clients, not to tell them apart by timing.
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(want)) != 1 || !ok || !found {
+			m.logger.Error("oauth client authentication failed", zap.String("client_id", id))
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, `{"error":"invalid_client"}`)
+			return
+		}
+		if scope != "" && !slices.Contains(m.oauthClientScopes[id], scope) {
+			m.logger.Error("oauth client lacks the scope of the route", zap.String("client_id", id), zap.String("scope", scope))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"error":"unauthorized_client"}`)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+func clientCredentials(r *http.Request) (id, secret string, ok bool) {
+	if id, secret, ok = r.BasicAuth(); ok {
+		var err1, err2 error
+		id, err1 = url.QueryUnescape(id)
+		secret, err2 = url.QueryUnescape(secret)
+		return id, secret, err1 == nil && err2 == nil
+	}
+
+	id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+
+	return id, secret, id != "" && secret != ""
+}
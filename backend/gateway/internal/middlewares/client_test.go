@@ -0,0 +1,117 @@
+package middlewares
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckClient(t *testing.T) {
+	m := New(&Config{
+		Logger:            zap.NewNop(),
+		OAuthClients:      map[string]string{"billing": "s3cret", "search": "p@ss:word"},
+		OAuthClientScopes: map[string][]string{"billing": {ClientScopeRevoke}},
+	})
+
+	tests := []struct {
+		name          string
+		scope         string
+		basicID       string
+		basicSecret   string
+		form          url.Values
+		expectedCode  int
+		handlerCalled bool
+	}{
+		{
+			name:          "basic auth",
+			basicID:       "billing",
+			basicSecret:   "s3cret",
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:          "basic auth form-encoded",
+			basicID:       "search",
+			basicSecret:   url.QueryEscape("p@ss:word"),
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:          "form parameters",
+			form:          url.Values{"client_id": {"billing"}, "client_secret": {"s3cret"}},
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:         "wrong secret",
+			basicID:      "billing",
+			basicSecret:  "wrong",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "secret of another client",
+			form:         url.Values{"client_id": {"billing"}, "client_secret": {"p@ss:word"}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "unknown client",
+			form:         url.Values{"client_id": {"other"}, "client_secret": {""}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "no credentials",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "scope granted",
+			scope:         ClientScopeRevoke,
+			basicID:       "billing",
+			basicSecret:   "s3cret",
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:         "scope not granted",
+			scope:        ClientScopeRevoke,
+			form:         url.Values{"client_id": {"search"}, "client_secret": {"p@ss:word"}},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlerCalled := false
+			next := func(w http.ResponseWriter, r *http.Request) {
+				handlerCalled = true
+				w.WriteHeader(http.StatusOK)
+			}
+
+			form := tt.form
+			if form == nil {
+				form = url.Values{}
+			}
+			form.Set("token", "access_token")
+			req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.basicID != "" {
+				req.SetBasicAuth(tt.basicID, tt.basicSecret)
+			}
+			rr := httptest.NewRecorder()
+			m.CheckClient(tt.scope, next).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, tt.handlerCalled, handlerCalled)
+			if tt.expectedCode == http.StatusBadRequest {
+				assert.JSONEq(t, `{"error":"unauthorized_client"}`, rr.Body.String())
+			}
+			if tt.expectedCode == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="oauth"`, rr.Header().Get("WWW-Authenticate"))
+				assert.JSONEq(t, `{"error":"invalid_client"}`, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
+	EncodingGzip   = "gzip"
+)
+
+// encoder is a compressing writer that can be reused once closed.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoders = map[string]*sync.Pool{
+	EncodingBrotli: {New: func() any { return brotli.NewWriterLevel(nil, brotli.DefaultCompression) }},
+	EncodingZstd: {New: func() any {
+		e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		return e
+	}},
+	EncodingGzip: {New: func() any { return gzip.NewWriter(nil) }},
+}
+
+// Compress compresses the responses with the encoding the request accepts
+// most, among the enabled ones in order of preference. Responses smaller than
+// the minimum size, of other content types or already encoded are sent as
+// they are.
+func (m *Middleware) Compress(next http.HandlerFunc) http.HandlerFunc {
+	if len(m.compressionEncodings) == 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding, ok := negotiate(r.Header.Values("Accept-Encoding"), m.compressionEncodings, func(rng, offer string) bool {
+			return rng == offer || rng == "*"
+		})
+		if !ok || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, m: m, encoding: encoding}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	m        *Middleware
+	encoding string
+
+	code    int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.code != 0 {
+		return
+	}
+	cw.code = code
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.code == 0 {
+		cw.code = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) >= cw.m.compressionMinSize {
+			if err := cw.decide(); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far, compressed or not.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.code == 0 {
+			cw.code = http.StatusOK
+		}
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.compress() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		cw.enc = encoders[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+		cw.ResponseWriter.WriteHeader(cw.code)
+		_, err := cw.enc.Write(cw.buf)
+		cw.buf = nil
+		return err
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.code)
+	_, err := cw.ResponseWriter.Write(cw.buf)
+	cw.buf = nil
+
+	return err
+}
+
+func (cw *compressWriter) compress() bool {
+	if len(cw.buf) == 0 || len(cw.buf) < cw.m.compressionMinSize || cw.code == http.StatusNoContent || cw.code == http.StatusNotModified {
+		return false
+	}
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType, _, _ := strings.Cut(h.Get("Content-Type"), ";")
+	contentType = strings.TrimSpace(contentType)
+	for _, allowed := range cw.m.compressionContentTypes {
+		if strings.HasSuffix(allowed, "/") && strings.HasPrefix(contentType, allowed) || contentType == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.code == 0 {
+			// The handler wrote nothing, let the server answer 200.
+			return
+		}
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		encoders[cw.encoding].Put(cw.enc)
+	}
+}
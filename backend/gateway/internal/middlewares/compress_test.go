@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	exact := func(rng, offer string) bool { return rng == offer || rng == "*" }
+	offers := []string{"br", "zstd", "gzip"}
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "none", header: ""},
+		{name: "single", header: "gzip", expected: "gzip"},
+		{name: "preference breaks ties", header: "gzip, zstd, br", expected: "br"},
+		{name: "weights", header: "br;q=0.5, gzip;q=0.8", expected: "gzip"},
+		{name: "wildcard", header: "*;q=0.5, br;q=0", expected: "zstd"},
+		{name: "refused", header: "br;q=0, identity", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := negotiate([]string{tt.header}, offers, exact)
+			assert.Equal(t, tt.expected, got)
+			assert.Equal(t, tt.expected != "", ok)
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := `{"items":"` + strings.Repeat("work map ", 200) + `"}`
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		contentType      string
+		body             string
+		expectedEncoding string
+	}{
+		{
+			name:             "brotli",
+			acceptEncoding:   "gzip, deflate, br, zstd",
+			contentType:      "application/json",
+			body:             large,
+			expectedEncoding: "br",
+		},
+		{
+			name:             "zstd",
+			acceptEncoding:   "zstd",
+			contentType:      "application/json; charset=utf-8",
+			body:             large,
+			expectedEncoding: "zstd",
+		},
+		{
+			name:             "gzip",
+			acceptEncoding:   "gzip",
+			contentType:      "text/plain",
+			body:             large,
+			expectedEncoding: "gzip",
+		},
+		{
+			name:           "under the minimum size",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			body:           `{"id":"1"}`,
+		},
+		{
+			name:           "other content type",
+			acceptEncoding: "gzip",
+			contentType:    "image/png",
+			body:           large,
+		},
+		{
+			name:        "not accepted",
+			contentType: "application/json",
+			body:        large,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{
+				logger:                  zap.NewNop(),
+				compressionEncodings:    []string{EncodingBrotli, EncodingZstd, EncodingGzip},
+				compressionMinSize:      1024,
+				compressionContentTypes: []string{"application/json", "text/"},
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			w := httptest.NewRecorder()
+			middleware.Compress(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusOK)
+				// Written in pieces, under the minimum size at first.
+				for body := tt.body; body != ""; {
+					n := min(len(body), 100)
+					w.Write([]byte(body[:n]))
+					body = body[n:]
+				}
+			})(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			assert.Equal(t, tt.expectedEncoding, w.Header().Get("Content-Encoding"))
+
+			body := io.Reader(w.Body)
+			if tt.expectedEncoding != "" {
+				assert.Less(t, w.Body.Len(), len(tt.body))
+				var err error
+				body, err = decoders[tt.expectedEncoding](body)
+				require.NoError(t, err)
+			}
+			got, err := io.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(got))
+		})
+	}
+}
+
+func TestCompress_AlreadyEncoded(t *testing.T) {
+	middleware := &Middleware{
+		logger:                  zap.NewNop(),
+		compressionEncodings:    []string{EncodingGzip},
+		compressionContentTypes: []string{"text/"},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	middleware.Compress(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte("already compressed"))
+	})(w, req)
+
+	assert.Equal(t, "already compressed", w.Body.String())
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEnableCORS(t *testing.T) {
+	tests := []struct {
+		name           string
+		allowedOrigins []string
+		origin         string
+		expectedOrigin string
+	}{
+		{
+			name:           "any origin",
+			allowedOrigins: []string{"*"},
+			origin:         "https://work-map.test",
+			expectedOrigin: "*",
+		},
+		{
+			name:           "allowed origin",
+			allowedOrigins: []string{"http://localhost:3000", "https://work-map.test"},
+			origin:         "https://work-map.test",
+			expectedOrigin: "https://work-map.test",
+		},
+		{
+			name:           "other origin",
+			allowedOrigins: []string{"http://localhost:3000"},
+			origin:         "https://evil.test",
+			expectedOrigin: "",
+		},
+		{
+			name:           "no origins",
+			origin:         "https://work-map.test",
+			expectedOrigin: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{logger: zap.NewNop(), corsAllowedOrigins: tt.allowedOrigins}
+
+			req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			req.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+
+			handler := &mockHandler{}
+			middleware.EnableCORS(handler.ServeHTTP)(w, req)
+
+			assert.True(t, handler.called)
+			assert.Equal(t, tt.expectedOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"slices"
+	"workmap/gateway/internal/pkg/csrf"
+)
+
+// CheckCSRF protects routes that act on cookies alone. Unsafe requests must
+// come from the gateway's own or a trusted origin and carry the token issued
+// by GET /csrf for their refresh token cookie in both the cookie and the
+// X-CSRF-Token header.
+func (m *Middleware) CheckCSRF(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if slices.Contains(m.csrfExemptPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := m.checkOrigin(r); err != nil {
+			m.logger.Error("csrf origin check failed", zap.Error(err))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		if err := m.checkCSRFToken(r); err != nil {
+			m.logger.Error("csrf token check failed", zap.Error(err))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// checkOrigin accepts requests without Origin and Referer, which browsers
+// always send on cross-site POSTs, so non-browser clients only need the token.
+func (m *Middleware) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	if origin == "" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid origin %q", origin)
+	}
+
+	if u.Host == r.Host || slices.Contains(m.csrfTrustedOrigins, u.Scheme+"://"+u.Host) {
+		return nil
+	}
+
+	return fmt.Errorf("untrusted origin %q", origin)
+}
+
+func (m *Middleware) checkCSRFToken(r *http.Request) error {
+	cookie, err := r.Cookie(csrf.CookieName)
+	if err != nil {
+		return err
+	}
+
+	header := r.Header.Get(csrf.HeaderName)
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+		return errors.New("csrf header does not match cookie")
+	}
+
+	var session string
+	if c, err := r.Cookie(csrf.SessionCookieName); err == nil {
+		session = c.Value
+	}
+
+	if !csrf.Valid(m.csrfSecret, header, session) {
+		return errors.New("invalid csrf token signature")
+	}
+
+	return nil
+}
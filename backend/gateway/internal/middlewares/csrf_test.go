@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"workmap/gateway/internal/pkg/csrf"
+)
+
+func TestCheckCSRF(t *testing.T) {
+	logger := zap.NewNop()
+	secret := []byte("secret")
+
+	token, err := csrf.Generate(secret, "refresh-token")
+	require.NoError(t, err)
+	foreign, err := csrf.Generate([]byte("other-secret"), "refresh-token")
+	require.NoError(t, err)
+	planted, err := csrf.Generate(secret, "attacker-refresh-token")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		origin        string
+		referer       string
+		cookie        string
+		header        string
+		expectedCode  int
+		handlerCalled bool
+	}{
+		{
+			name:          "safe method",
+			method:        http.MethodGet,
+			path:          "/user/logout",
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:          "exempt path",
+			method:        http.MethodPost,
+			path:          "/webhook",
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:          "valid token",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			cookie:        token,
+			header:        token,
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:          "valid token from trusted origin",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			origin:        "http://localhost:3000",
+			cookie:        token,
+			header:        token,
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:          "valid token from same host",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			referer:       "http://gateway.test/login",
+			cookie:        token,
+			header:        token,
+			expectedCode:  http.StatusOK,
+			handlerCalled: true,
+		},
+		{
+			name:          "untrusted origin",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			origin:        "https://evil.test",
+			cookie:        token,
+			header:        token,
+			expectedCode:  http.StatusForbidden,
+			handlerCalled: false,
+		},
+		{
+			name:          "untrusted referer",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			referer:       "https://evil.test/page",
+			cookie:        token,
+			header:        token,
+			expectedCode:  http.StatusForbidden,
+			handlerCalled: false,
+		},
+		{
+			name:          "missing header",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			cookie:        token,
+			expectedCode:  http.StatusForbidden,
+			handlerCalled: false,
+		},
+		{
+			name:          "missing cookie",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			header:        token,
+			expectedCode:  http.StatusForbidden,
+			handlerCalled: false,
+		},
+		{
+			name:          "header does not match cookie",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			cookie:        token,
+			header:        token + "x",
+			expectedCode:  http.StatusForbidden,
+			handlerCalled: false,
+		},
+		{
+			name:          "token signed with another secret",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			cookie:        foreign,
+			header:        foreign,
+			expectedCode:  http.StatusForbidden,
+			handlerCalled: false,
+		},
+		{
+			name:          "token issued for another session",
+			method:        http.MethodPost,
+			path:          "/user/logout",
+			cookie:        planted,
+			header:        planted,
+			expectedCode:  http.StatusForbidden,
+			handlerCalled: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{
+				logger:             logger,
+				csrfSecret:         secret,
+				csrfTrustedOrigins: []string{"http://localhost:3000"},
+				csrfExemptPaths:    []string{"/webhook"},
+			}
+
+			req := httptest.NewRequest(tt.method, "http://gateway.test"+tt.path, nil)
+			req.AddCookie(&http.Cookie{Name: csrf.SessionCookieName, Value: "refresh-token"})
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: csrf.CookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(csrf.HeaderName, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			handler := &mockHandler{}
+			middleware.CheckCSRF(handler.ServeHTTP)(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.handlerCalled, handler.called)
+		})
+	}
+}
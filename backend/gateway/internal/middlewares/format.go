@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeMsgPack  = "application/msgpack"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// formats are the media types JSON responses can be sent as, JSON first so
+// that it wins ties.
+var formats = []string{ContentTypeJSON, ContentTypeMsgPack, ContentTypeProtobuf}
+
+// NegotiateFormat sends the JSON responses as MessagePack or Protocol Buffers
+// if the request accepts them more than JSON. A protobuf body is a
+// google.protobuf.Value holding the JSON value. Other responses are sent as
+// they are.
+func (m *Middleware) NegotiateFormat(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+
+		format, _ := negotiate(r.Header.Values("Accept"), formats, func(rng, offer string) bool {
+			if rng == "*/*" || rng == offer {
+				return true
+			}
+			// The older names of the formats.
+			if rng == "application/x-msgpack" && offer == ContentTypeMsgPack || rng == "application/protobuf" && offer == ContentTypeProtobuf {
+				return true
+			}
+			prefix, ok := strings.CutSuffix(rng, "*")
+			return ok && strings.HasPrefix(offer, prefix)
+		})
+		if format == "" || format == ContentTypeJSON {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fw := &formatWriter{ResponseWriter: w}
+		next.ServeHTTP(fw, r)
+
+		if err := m.transcode(fw, format); err != nil {
+			m.logger.Error("failed to transcode response", zap.String("format", format), zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// transcode writes the response buffered by fw in format if it is JSON.
+func (m *Middleware) transcode(fw *formatWriter, format string) error {
+	w := fw.ResponseWriter
+	if fw.code == 0 {
+		fw.code = http.StatusOK
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType != ContentTypeJSON || fw.buf.Len() == 0 {
+		w.WriteHeader(fw.code)
+		_, err := w.Write(fw.buf.Bytes())
+		return err
+	}
+
+	dec := json.NewDecoder(&fw.buf)
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	v = numbers(v)
+
+	var body []byte
+	var err error
+	if format == ContentTypeMsgPack {
+		body, err = msgpack.Marshal(v)
+	} else {
+		var pv *structpb.Value
+		if pv, err = structpb.NewValue(v); err == nil {
+			body, err = proto.Marshal(pv)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", format)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(fw.code)
+	_, err = w.Write(body)
+
+	return err
+}
+
+// numbers replaces the json.Numbers of v by integers when they are, floats
+// otherwise.
+func numbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = numbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = numbers(e)
+		}
+	}
+
+	return v
+}
+
+// formatWriter buffers a response to transcode or validate it.
+type formatWriter struct {
+	http.ResponseWriter
+	code int
+	buf  bytes.Buffer
+}
+
+func (fw *formatWriter) WriteHeader(code int) {
+	if fw.code == 0 {
+		fw.code = code
+	}
+}
+
+func (fw *formatWriter) Write(p []byte) (int, error) {
+	if fw.code == 0 {
+		fw.code = http.StatusOK
+	}
+
+	return fw.buf.Write(p)
+}
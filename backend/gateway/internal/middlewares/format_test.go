@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	profile := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"email":"user@email.com","mfa":true,"passkeys":2,"ratio":0.5}`))
+	}
+	failure := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}
+	expected := map[string]any{"email": "user@email.com", "mfa": true, "passkeys": int64(2), "ratio": 0.5}
+
+	tests := []struct {
+		name                string
+		accept              string
+		handler             http.HandlerFunc
+		expectedContentType string
+	}{
+		{
+			name:                "no accept",
+			handler:             profile,
+			expectedContentType: "application/json",
+		},
+		{
+			name:                "json preferred",
+			accept:              "application/json, application/msgpack;q=0.9",
+			handler:             profile,
+			expectedContentType: "application/json",
+		},
+		{
+			name:                "msgpack",
+			accept:              "application/msgpack, application/json;q=0.5",
+			handler:             profile,
+			expectedContentType: ContentTypeMsgPack,
+		},
+		{
+			name:                "protobuf",
+			accept:              "application/x-protobuf",
+			handler:             profile,
+			expectedContentType: ContentTypeProtobuf,
+		},
+		{
+			name:                "not json",
+			accept:              "application/msgpack",
+			handler:             failure,
+			expectedContentType: "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{logger: zap.NewNop()}
+
+			req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			middleware.NegotiateFormat(tt.handler)(w, req)
+
+			assert.Equal(t, tt.expectedContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+			switch tt.expectedContentType {
+			case ContentTypeMsgPack:
+				assert.Equal(t, http.StatusCreated, w.Code)
+				var got map[string]any
+				require.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &got))
+				assert.EqualValues(t, expected["email"], got["email"])
+				assert.EqualValues(t, expected["passkeys"], got["passkeys"])
+				assert.EqualValues(t, expected["ratio"], got["ratio"])
+			case ContentTypeProtobuf:
+				assert.Equal(t, http.StatusCreated, w.Code)
+				var v structpb.Value
+				require.NoError(t, proto.Unmarshal(w.Body.Bytes(), &v))
+				got := v.GetStructValue().AsMap()
+				assert.Equal(t, "user@email.com", got["email"])
+				assert.Equal(t, true, got["mfa"])
+				assert.Equal(t, 2.0, got["passkeys"])
+			case "application/json":
+				assert.JSONEq(t, `{"email":"user@email.com","mfa":true,"passkeys":2,"ratio":0.5}`, w.Body.String())
+			default:
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				assert.Equal(t, "unauthorized\n", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"workmap/gateway/internal/route"
+)
+
+// Instrument counts the requests and observes their duration by route name
+// and status code. Requests outside the router are counted as "unknown".
+func (m *Middleware) Instrument(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := "unknown"
+		if rt, ok := route.FromContext(r.Context()); ok {
+			name = rt.Name
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.code == 0 {
+			sw.code = http.StatusOK
+		}
+
+		code := strconv.Itoa(sw.code)
+		requests.WithLabelValues(name, code).Inc()
+		requestDuration.WithLabelValues(name, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if sw.code == 0 {
+		sw.code = code
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.code == 0 {
+		sw.code = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
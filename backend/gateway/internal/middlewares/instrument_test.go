@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"workmap/gateway/internal/route"
+)
+
+func TestInstrument(t *testing.T) {
+	tests := []struct {
+		name          string
+		route         *route.Route
+		handler       http.HandlerFunc
+		expectedRoute string
+		expectedCode  string
+	}{
+		{
+			name:          "route",
+			route:         &route.Route{Name: "user.profile"},
+			handler:       func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+			expectedRoute: "user.profile",
+			expectedCode:  "200",
+		},
+		{
+			name:          "status code",
+			route:         &route.Route{Name: "user.login"},
+			handler:       func(w http.ResponseWriter, r *http.Request) { http.Error(w, "unauthorized", http.StatusUnauthorized) },
+			expectedRoute: "user.login",
+			expectedCode:  "401",
+		},
+		{
+			name:          "no route",
+			handler:       func(w http.ResponseWriter, r *http.Request) {},
+			expectedRoute: "unknown",
+			expectedCode:  "200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := requests.WithLabelValues(tt.expectedRoute, tt.expectedCode)
+			before := testutil.ToFloat64(counter)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.route != nil {
+				req = req.WithContext(route.NewContext(req.Context(), tt.route))
+			}
+			middleware := &Middleware{logger: zap.NewNop()}
+			middleware.Instrument(tt.handler)(httptest.NewRecorder(), req)
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(counter)-before)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"go.uber.org/zap"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LimitBody fails the reads of request bodies over n bytes, none if n is not
+// positive.
+func (m *Middleware) LimitBody(n int64, next http.HandlerFunc) http.HandlerFunc {
+	if n <= 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, n)
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// RateLimiter counts the requests of each client IP, allowing a number of
+// them per window. The windows are fixed, and shared by every client so that
+// the counts are dropped together.
+type RateLimiter struct {
+	requests int
+	per      time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+// NewRateLimiter allows requests per window of length per, both positive.
+func NewRateLimiter(requests int, per time.Duration) *RateLimiter {
+	return &RateLimiter{
+		requests: requests,
+		per:      per,
+		now:      time.Now,
+		counts:   make(map[string]int),
+	}
+}
+
+// allow counts a request of ip, and returns false with the time left in the
+// window if ip has made all those allowed.
+func (l *RateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.start) >= l.per {
+		l.start = now
+		clear(l.counts)
+	}
+	if l.counts[ip] >= l.requests {
+		return false, l.start.Add(l.per).Sub(now)
+	}
+	l.counts[ip]++
+
+	return true, 0
+}
+
+// RateLimit answers 429 to the clients over the limit of l, by IP, with the
+// seconds left in the window in Retry-After, none if l is nil. The routes
+// sharing l share the limit.
+func (m *Middleware) RateLimit(l *RateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		if ok, left := l.allow(ip); !ok {
+			m.logger.Debug("rate limited", zap.String("path", r.URL.Path), zap.String("ip", ip))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(left.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// Timeout answers 504 if next has not answered within d, none if d is not
+// positive. The request context is cancelled at the same time, which
+// cancels the calls to the Auth service and the stores. Like
+// http.TimeoutHandler, the response of next is buffered until it returns.
+// Its panics are passed on with its stack, which Recover logs.
+func (m *Middleware) Timeout(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	if d <= 0 {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		tw := &timeoutWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					if p != http.ErrAbortHandler {
+						p = &handlerPanic{value: p, stack: debug.Stack()}
+					}
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			dst := w.Header()
+			for k, v := range tw.header {
+				dst[k] = v
+			}
+			if tw.code == 0 {
+				tw.code = http.StatusOK
+			}
+			w.WriteHeader(tw.code)
+			w.Write(tw.buf.Bytes())
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			tw.timedOut = true
+			if ctx.Err() == context.DeadlineExceeded {
+				m.logger.Warn("handler timed out", zap.String("path", r.URL.Path), zap.Duration("timeout", d))
+				http.Error(w, "Gateway timeout", http.StatusGatewayTimeout)
+			}
+		}
+	}
+}
+
+// timeoutWriter buffers the response of a handler run by Timeout.
+type timeoutWriter struct {
+	header http.Header
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
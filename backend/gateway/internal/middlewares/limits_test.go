@@ -0,0 +1,145 @@
+package middlewares
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       int64
+		body        string
+		expectedErr bool
+	}{
+		{
+			name:  "under the limit",
+			limit: 16,
+			body:  `{"code":"123"}`,
+		},
+		{
+			name:        "over the limit",
+			limit:       4,
+			body:        `{"code":"123"}`,
+			expectedErr: true,
+		},
+		{
+			name: "no limit",
+			body: strings.Repeat("a", 1<<16),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{logger: zap.NewNop()}
+
+			req := httptest.NewRequest(http.MethodPost, "/user/2fa/confirm", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			var err error
+			middleware.LimitBody(tt.limit, func(w http.ResponseWriter, r *http.Request) {
+				_, err = io.ReadAll(r.Body)
+			})(w, req)
+
+			var tooLarge *http.MaxBytesError
+			assert.Equal(t, tt.expectedErr, errors.As(err, &tooLarge))
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	middleware := &Middleware{logger: zap.NewNop()}
+	limiter := NewRateLimiter(2, time.Minute)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	handler := middleware.RateLimit(limiter, func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/user/login", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:1234").Code)
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:5678").Code, "the port is not part of the client")
+	now = now.Add(20 * time.Second)
+	w := serve("192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "40", w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, serve("192.0.2.2:1234").Code)
+
+	now = now.Add(40 * time.Second)
+	assert.Equal(t, http.StatusOK, serve("192.0.2.1:1234").Code, "the count is dropped with the window")
+
+	t.Run("no limiter", func(t *testing.T) {
+		called := false
+		middleware.RateLimit(nil, func(w http.ResponseWriter, r *http.Request) { called = true })(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.True(t, called)
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	t.Run("passes the response through", func(t *testing.T) {
+		middleware := &Middleware{logger: zap.NewNop()}
+
+		req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+		w := httptest.NewRecorder()
+		middleware.Timeout(time.Second, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		})(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Equal(t, `{}`, w.Body.String())
+	})
+
+	t.Run("answers 504 and cancels the handler", func(t *testing.T) {
+		middleware := &Middleware{logger: zap.NewNop()}
+
+		req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+		w := httptest.NewRecorder()
+		cancelled := make(chan struct{})
+		middleware.Timeout(20*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			close(cancelled)
+			w.WriteHeader(http.StatusOK)
+		})(w, req)
+
+		assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("the handler context was not cancelled")
+		}
+	})
+
+	t.Run("propagates panics", func(t *testing.T) {
+		middleware := &Middleware{logger: zap.NewNop()}
+
+		req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+		w := httptest.NewRecorder()
+		defer func() {
+			p, ok := recover().(*handlerPanic)
+			require.True(t, ok)
+			assert.Equal(t, "boom", p.value)
+			assert.Contains(t, string(p.stack), "limits_test.go")
+		}()
+		middleware.Timeout(time.Second, func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})(w, req)
+		t.Error("the panic was not propagated")
+	})
+}
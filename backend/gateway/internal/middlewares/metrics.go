@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var degradedAuth = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_degraded_auth_total",
+	Help: "Access tokens checked while the token store was unavailable, by result: accepted, rejected or refused.",
+}, []string{"result"})
+
+var requests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_http_requests_total",
+	Help: "Requests by route name and status code.",
+}, []string{"route", "code"})
+
+var requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gateway_http_request_duration_seconds",
+	Help:    "Duration of the requests by route name and status code.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"route", "code"})
+
+var panics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_panics_total",
+	Help: "Handler panics recovered, by route name.",
+}, []string{"route"})
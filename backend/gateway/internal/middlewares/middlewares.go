@@ -1,27 +1,108 @@
 package middlewares
 
 import (
+	"github.com/getkin/kin-openapi/routers"
 	"go.uber.org/zap"
+	"workmap/gateway/internal/crash"
 	pb "workmap/gateway/internal/gapi/proto_gen"
 	"workmap/gateway/internal/redis"
+	"workmap/gateway/internal/tenant"
 )
 
 type Config struct {
-	Logger *zap.Logger
-	Auth   pb.AuthServiceClient
-	Redis  store.TokenGetter
+	Logger  *zap.Logger
+	Auth    pb.AuthServiceClient
+	Redis   store.TokenGetter
+	APIKeys store.APIKeyGetter
+	// CORSAllowedOrigins may contain "*" to allow any origin.
+	CORSAllowedOrigins []string
+	// CSRFSecret signs the tokens checked by CheckCSRF.
+	CSRFSecret         []byte
+	CSRFTrustedOrigins []string
+	CSRFExemptPaths    []string
+	Tenants            *tenant.Resolver
+	// DegradedJWTSecret verifies the access tokens of safe requests while
+	// the token store is unavailable, nil to refuse them.
+	DegradedJWTSecret []byte
+	// CompressionEncodings are the Encoding constants enabled, by
+	// preference, none to disable Compress.
+	CompressionEncodings    []string
+	CompressionMinSize      int
+	CompressionContentTypes []string
+	// OpenAPI is the description ValidateOpenAPI checks against, see
+	// NewOpenAPIRouter. OpenAPIResponses is one of the ResponseValidation
+	// constants, empty to send the responses unchecked.
+	OpenAPI          routers.Router
+	OpenAPIRequests  bool
+	OpenAPIResponses string
+	// OAuthClients are the secrets of the clients CheckClient accepts, by
+	// client ID.
+	OAuthClients map[string]string
+	// OAuthClientScopes are the scopes granted to the clients, by client ID,
+	// like ClientScopeRevoke.
+	OAuthClientScopes map[string][]string
+	// CrashSinks receive the reports of the panics recovered by Recover.
+	CrashSinks []crash.Sink
 }
 
 type Middleware struct {
-	logger *zap.Logger
-	auth   pb.AuthServiceClient
-	redis  store.TokenGetter
+	logger  *zap.Logger
+	auth    pb.AuthServiceClient
+	redis   store.TokenGetter
+	apiKeys store.APIKeyGetter
+
+	corsAllowedOrigins []string
+
+	csrfSecret         []byte
+	csrfTrustedOrigins []string
+	csrfExemptPaths    []string
+
+	degradedJWTSecret []byte
+	tenants           *tenant.Resolver
+
+	compressionEncodings    []string
+	compressionMinSize      int
+	compressionContentTypes []string
+
+	openAPI          routers.Router
+	openAPIRequests  bool
+	openAPIResponses string
+
+	oauthClients      map[string]string
+	oauthClientScopes map[string][]string
+
+	crashSinks   []crash.Sink
+	crashReports chan struct{}
 }
 
 func New(cfg *Config) *Middleware {
 	return &Middleware{
-		logger: cfg.Logger,
-		auth:   cfg.Auth,
-		redis:  cfg.Redis,
+		logger:  cfg.Logger,
+		auth:    cfg.Auth,
+		redis:   cfg.Redis,
+		apiKeys: cfg.APIKeys,
+
+		corsAllowedOrigins: cfg.CORSAllowedOrigins,
+
+		csrfSecret:         cfg.CSRFSecret,
+		csrfTrustedOrigins: cfg.CSRFTrustedOrigins,
+		csrfExemptPaths:    cfg.CSRFExemptPaths,
+
+		degradedJWTSecret: cfg.DegradedJWTSecret,
+		tenants:           cfg.Tenants,
+
+		compressionEncodings:    cfg.CompressionEncodings,
+		compressionMinSize:      cfg.CompressionMinSize,
+		compressionContentTypes: cfg.CompressionContentTypes,
+
+		openAPI:          cfg.OpenAPI,
+		openAPIRequests:  cfg.OpenAPIRequests,
+		openAPIResponses: cfg.OpenAPIResponses,
+
+		oauthClients:      cfg.OAuthClients,
+		oauthClientScopes: cfg.OAuthClientScopes,
+
+		crashSinks:   cfg.CrashSinks,
+		crashReports: make(chan struct{}, maxPendingCrashReports),
 	}
 }
@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the offer the header values of an Accept-style header
+// weigh most, ties going to the earlier offer, and false if it accepts none.
+// match reports whether a range of the header, like "*" or "application/*",
+// covers an offer.
+func negotiate(values []string, offers []string, match func(rng, offer string) bool) (string, bool) {
+	type weighted struct {
+		rng string
+		q   float64
+	}
+
+	var ranges []weighted
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			rng, params, _ := strings.Cut(part, ";")
+			rng = strings.ToLower(strings.TrimSpace(rng))
+			if rng == "" {
+				continue
+			}
+
+			q := 1.0
+			for _, p := range strings.Split(params, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+				if k == "q" {
+					if f, err := strconv.ParseFloat(v, 64); err == nil {
+						q = f
+					}
+				}
+			}
+			ranges = append(ranges, weighted{rng: rng, q: q})
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		// The most specific range matching the offer sets its weight.
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if !match(r.rng, offer) {
+				continue
+			}
+			s := strings.Count(r.rng, "*")
+			if specificity == -1 || s < specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best, bestQ > 0
+}
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// What ValidateOpenAPI does with the responses violating the description.
+const (
+	// ResponseValidationLog logs the violations and sends the responses.
+	ResponseValidationLog = "log"
+	// ResponseValidationFail logs the violations and answers 500 instead.
+	ResponseValidationFail = "fail"
+)
+
+// NewOpenAPIRouter loads the OpenAPI description in spec for ValidateOpenAPI.
+// Its paths match whatever the host, the servers it lists being ignored: the
+// routes strip their version prefix before validating.
+func NewOpenAPIRouter(spec []byte) (routers.Router, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	doc.Servers = nil
+	for _, item := range doc.Paths.Map() {
+		item.Servers = nil
+	}
+
+	return gorillamux.NewRouter(doc)
+}
+
+// ValidateOpenAPI checks the requests against the OpenAPI description,
+// answering 400 to those violating it, and the responses if response
+// validation is enabled. Requests to paths the description does not list are
+// passed on unchecked. Authentication is left to CheckAuth, CheckCSRF and
+// CheckClient.
+func (m *Middleware) ValidateOpenAPI(next http.HandlerFunc) http.HandlerFunc {
+	if m.openAPI == nil || !m.openAPIRequests && m.openAPIResponses == "" {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		route, params, err := m.openAPI.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: params,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+				IncludeResponseStatus: true,
+				SkipSettingDefaults:   true,
+			},
+		}
+
+		if m.openAPIRequests {
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				if errors.As(err, new(*http.MaxBytesError)) {
+					http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				m.logger.Info("request violates the openapi description", zap.String("path", r.URL.Path), zap.Error(err))
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if m.openAPIResponses == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fw := &formatWriter{ResponseWriter: w}
+		next.ServeHTTP(fw, r)
+		if fw.code == 0 {
+			fw.code = http.StatusOK
+		}
+
+		options := *input.Options
+		mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		if openapi3filter.RegisteredBodyDecoder(mediaType) == nil {
+			// Pages and other bodies the validator cannot decode.
+			options.ExcludeResponseBody = true
+		}
+		err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 fw.code,
+			Header:                 w.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(fw.buf.Bytes())),
+			Options:                &options,
+		})
+		if err != nil && m.openAPIResponses == ResponseValidationFail {
+			m.logger.Error("response violates the openapi description", zap.String("path", r.URL.Path), zap.Int("status", fw.code), zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			m.logger.Warn("response violates the openapi description", zap.String("path", r.URL.Path), zap.Int("status", fw.code), zap.Error(err))
+		}
+
+		w.WriteHeader(fw.code)
+		w.Write(fw.buf.Bytes())
+	}
+}
@@ -0,0 +1,222 @@
+package middlewares
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"workmap/gateway/docs"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info:
+  title: Test
+  version: 0.0.1
+servers:
+  - url: https://gateway.example.com/
+paths:
+  /user/2fa/confirm:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [code]
+              properties:
+                code:
+                  type: string
+                  pattern: '^[0-9]{6}$'
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [recovery_codes]
+                properties:
+                  recovery_codes:
+                    type: array
+                    items:
+                      type: string
+        '400':
+          description: Invalid code
+          content:
+            text/plain:
+              schema:
+                type: string
+`
+
+func TestNewOpenAPIRouter(t *testing.T) {
+	_, err := NewOpenAPIRouter(docs.Swagger)
+	assert.NoError(t, err)
+}
+
+func TestValidateOpenAPI(t *testing.T) {
+	router, err := NewOpenAPIRouter([]byte(testSpec))
+	require.NoError(t, err)
+
+	confirm := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"recovery_codes":["a1b2c3"]}`))
+	}
+	invalid := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"codes":["a1b2c3"]}`))
+	}
+	undocumented := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Conflict", http.StatusConflict)
+	}
+
+	tests := []struct {
+		name         string
+		requests     bool
+		responses    string
+		path         string
+		body         string
+		handler      http.HandlerFunc
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "valid request",
+			requests:     true,
+			path:         "/user/2fa/confirm",
+			body:         `{"code":"123456"}`,
+			handler:      confirm,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"recovery_codes":["a1b2c3"]}`,
+		},
+		{
+			name:         "invalid body",
+			requests:     true,
+			path:         "/user/2fa/confirm",
+			body:         `{"code":"abc"}`,
+			handler:      confirm,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request\n",
+		},
+		{
+			name:         "missing body",
+			requests:     true,
+			path:         "/user/2fa/confirm",
+			handler:      confirm,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request\n",
+		},
+		{
+			name:         "request validation disabled",
+			responses:    ResponseValidationLog,
+			path:         "/user/2fa/confirm",
+			body:         `{"code":"abc"}`,
+			handler:      confirm,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"recovery_codes":["a1b2c3"]}`,
+		},
+		{
+			name:         "undescribed path",
+			requests:     true,
+			path:         "/metrics",
+			handler:      confirm,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"recovery_codes":["a1b2c3"]}`,
+		},
+		{
+			name:         "invalid response logged",
+			responses:    ResponseValidationLog,
+			path:         "/user/2fa/confirm",
+			body:         `{"code":"123456"}`,
+			handler:      invalid,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"codes":["a1b2c3"]}`,
+		},
+		{
+			name:         "invalid response failed",
+			responses:    ResponseValidationFail,
+			path:         "/user/2fa/confirm",
+			body:         `{"code":"123456"}`,
+			handler:      invalid,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "Internal server error\n",
+		},
+		{
+			name:         "undocumented status failed",
+			responses:    ResponseValidationFail,
+			path:         "/user/2fa/confirm",
+			body:         `{"code":"123456"}`,
+			handler:      undocumented,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "Internal server error\n",
+		},
+		{
+			name:         "valid response",
+			responses:    ResponseValidationFail,
+			path:         "/user/2fa/confirm",
+			body:         `{"code":"123456"}`,
+			handler:      confirm,
+			expectedCode: http.StatusOK,
+			expectedBody: `{"recovery_codes":["a1b2c3"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{
+				logger:           zap.NewNop(),
+				openAPI:          router,
+				openAPIRequests:  tt.requests,
+				openAPIResponses: tt.responses,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			middleware.ValidateOpenAPI(tt.handler)(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestValidateOpenAPI_BodyTooLarge(t *testing.T) {
+	router, err := NewOpenAPIRouter([]byte(testSpec))
+	require.NoError(t, err)
+	middleware := &Middleware{logger: zap.NewNop(), openAPI: router, openAPIRequests: true}
+
+	req := httptest.NewRequest(http.MethodPost, "/user/2fa/confirm", strings.NewReader(`{"code":"123456"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	middleware.LimitBody(4, middleware.ValidateOpenAPI(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler called")
+	}))(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestValidateOpenAPI_PassesBody(t *testing.T) {
+	router, err := NewOpenAPIRouter([]byte(testSpec))
+	require.NoError(t, err)
+	middleware := &Middleware{logger: zap.NewNop(), openAPI: router, openAPIRequests: true}
+
+	req := httptest.NewRequest(http.MethodPost, "/user/2fa/confirm", strings.NewReader(`{"code":"123456"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	var body strings.Builder
+	middleware.ValidateOpenAPI(func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.Copy(&body, r.Body)
+		assert.NoError(t, err)
+	})(w, req)
+
+	assert.Equal(t, `{"code":"123456"}`, body.String())
+}
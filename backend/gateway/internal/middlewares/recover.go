@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"runtime/debug"
+	"time"
+	"workmap/gateway/internal/crash"
+	"workmap/gateway/internal/requestid"
+	"workmap/gateway/internal/route"
+	"workmap/gateway/internal/tenant"
+)
+
+// RequestID passes the ID of the request to next in the request context, and
+// sends it back in the requestid.Header of the response. The ID sent by the
+// client is kept if valid, a new one is generated otherwise.
+func (m *Middleware) RequestID(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	}
+}
+
+// Recover answers 500 with the request ID to the requests whose handler
+// panics, unless the response was started, logs the panic with its stack,
+// and sends a crash report to each sink once the response is done.
+// http.ErrAbortHandler is passed on.
+func (m *Middleware) Recover(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			var stack []byte
+			if hp, ok := p.(*handlerPanic); ok {
+				p, stack = hp.value, hp.stack
+			} else {
+				stack = debug.Stack()
+			}
+
+			report := &crash.Report{
+				Time:      time.Now(),
+				RequestID: requestid.FromContext(r.Context()),
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Panic:     fmt.Sprint(p),
+				Stack:     string(stack),
+			}
+			if rt, ok := route.FromContext(r.Context()); ok {
+				report.Route = rt.Name
+			}
+			report.Tenant, _ = tenant.FromContext(r.Context())
+			panics.WithLabelValues(report.Route).Inc()
+			m.logger.Error("handler panicked",
+				zap.String("request_id", report.RequestID),
+				zap.String("method", report.Method),
+				zap.String("path", report.Path),
+				zap.String("route", report.Route),
+				zap.String("tenant", report.Tenant),
+				zap.Any("panic", p),
+				zap.ByteString("stack", stack),
+			)
+
+			if sw.code == 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprintf(w, "{\"error\":\"Internal server error\",\"request_id\":%q}\n", report.RequestID)
+			}
+
+			m.sendCrashReport(report)
+		}()
+
+		next.ServeHTTP(sw, r)
+	}
+}
+
+// sendCrashReport sends report to every sink in the background, whatever the
+// request context became, so that a slow sink does not hold the response,
+// logging their failures. The report is dropped while maxPendingCrashReports
+// others are being sent.
+func (m *Middleware) sendCrashReport(report *crash.Report) {
+	if len(m.crashSinks) == 0 {
+		return
+	}
+
+	select {
+	case m.crashReports <- struct{}{}:
+	default:
+		m.logger.Error("too many crash reports pending, dropping one", zap.String("request_id", report.RequestID))
+		return
+	}
+
+	go func() {
+		defer func() { <-m.crashReports }()
+
+		for _, sink := range m.crashSinks {
+			ctx, cancel := context.WithTimeout(context.Background(), crashReportTimeout)
+			if err := sink.Send(ctx, report); err != nil {
+				m.logger.Error("failed to send crash report", zap.String("request_id", report.RequestID), zap.Error(err))
+			}
+			cancel()
+		}
+	}()
+}
+
+const (
+	// crashReportTimeout bounds the sinks lacking their own timeout.
+	crashReportTimeout = 10 * time.Second
+	// maxPendingCrashReports bounds the crash reports being sent, so that a
+	// panic in every request cannot pile up goroutines behind a slow sink.
+	maxPendingCrashReports = 16
+)
+
+// handlerPanic is a panic of a handler run by Timeout, passed on to the
+// goroutine serving the request with the stack of the handler.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+func (p *handlerPanic) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
@@ -0,0 +1,192 @@
+package middlewares
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"workmap/gateway/internal/crash"
+	"workmap/gateway/internal/requestid"
+	"workmap/gateway/internal/route"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "kept", header: "abc-123", expected: "abc-123"},
+		{name: "generated", header: ""},
+		{name: "invalid replaced", header: "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{logger: zap.NewNop()}
+
+			req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			var got string
+			middleware.RequestID(func(w http.ResponseWriter, r *http.Request) {
+				got = requestid.FromContext(r.Context())
+			})(w, req)
+
+			assert.True(t, requestid.Valid(got))
+			if tt.expected != "" {
+				assert.Equal(t, tt.expected, got)
+			} else {
+				assert.NotEqual(t, tt.header, got)
+			}
+			assert.Equal(t, got, w.Header().Get(requestid.Header))
+		})
+	}
+}
+
+// recordSink passes the reports to the test, blocking until it reads them
+// if unblocked is not closed.
+type recordSink struct {
+	reports   chan *crash.Report
+	unblocked chan struct{}
+}
+
+func newRecordSink(blocked bool) *recordSink {
+	s := &recordSink{reports: make(chan *crash.Report, 1), unblocked: make(chan struct{})}
+	if !blocked {
+		close(s.unblocked)
+	}
+
+	return s
+}
+
+func (s *recordSink) Send(ctx context.Context, r *crash.Report) error {
+	<-s.unblocked
+	s.reports <- r
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		timeout       time.Duration
+		expectedCode  int
+		expectedBody  string
+		expectedPanic bool
+	}{
+		{
+			name:         "no panic",
+			handler:      func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+			expectedCode: http.StatusOK,
+			expectedBody: "ok",
+		},
+		{
+			name:          "panic",
+			handler:       func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			expectedCode:  http.StatusInternalServerError,
+			expectedBody:  `{"error":"Internal server error","request_id":"r1"}` + "\n",
+			expectedPanic: true,
+		},
+		{
+			name:          "panic behind a timeout",
+			handler:       func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			timeout:       time.Second,
+			expectedCode:  http.StatusInternalServerError,
+			expectedBody:  `{"error":"Internal server error","request_id":"r1"}` + "\n",
+			expectedPanic: true,
+		},
+		{
+			name: "panic after the response started",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("partial"))
+				panic("boom")
+			},
+			expectedCode:  http.StatusOK,
+			expectedBody:  "partial",
+			expectedPanic: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := newRecordSink(false)
+			middleware := New(&Config{Logger: zap.NewNop(), CrashSinks: []crash.Sink{sink}})
+			counter := panics.WithLabelValues("user.profile")
+			before := testutil.ToFloat64(counter)
+
+			req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			ctx := requestid.NewContext(req.Context(), "r1")
+			ctx = route.NewContext(ctx, &route.Route{Name: "user.profile"})
+			w := httptest.NewRecorder()
+			middleware.Recover(middleware.Timeout(tt.timeout, tt.handler))(w, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			if !tt.expectedPanic {
+				assert.Empty(t, sink.reports)
+				assert.Equal(t, 0.0, testutil.ToFloat64(counter)-before)
+				return
+			}
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(counter)-before)
+			var report *crash.Report
+			select {
+			case report = <-sink.reports:
+			case <-time.After(time.Second):
+				require.FailNow(t, "no crash report sent")
+			}
+			assert.Equal(t, "r1", report.RequestID)
+			assert.Equal(t, "user.profile", report.Route)
+			assert.Equal(t, "/user/profile", report.Path)
+			assert.Equal(t, "boom", report.Panic)
+			assert.Contains(t, report.Stack, "recover_test.go")
+		})
+	}
+}
+
+func TestRecover_SlowSink(t *testing.T) {
+	sink := newRecordSink(true)
+	middleware := New(&Config{Logger: zap.NewNop(), CrashSinks: []crash.Sink{sink}})
+	handler := middleware.Recover(func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	// The responses are not held by the sink, and the reports beyond the
+	// pending ones are dropped.
+	for range maxPendingCrashReports + 1 {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/user/profile", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+
+	close(sink.unblocked)
+	for range maxPendingCrashReports {
+		select {
+		case <-sink.reports:
+		case <-time.After(time.Second):
+			require.FailNow(t, "crash report not sent")
+		}
+	}
+	select {
+	case <-sink.reports:
+		assert.Fail(t, "crash report not dropped")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRecover_AbortHandler(t *testing.T) {
+	middleware := &Middleware{logger: zap.NewNop()}
+
+	req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		middleware.Recover(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})(httptest.NewRecorder(), req)
+	})
+}
@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"net/http"
+	"workmap/gateway/internal/tenant"
+)
+
+// ResolveTenant puts the tenant of the request, if any, in its context,
+// which scopes what the token store sees.
+func (m *Middleware) ResolveTenant(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if t, ok := m.tenants.Resolve(r); ok {
+			r = r.WithContext(tenant.NewContext(r.Context(), t))
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
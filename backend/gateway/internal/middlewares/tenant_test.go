@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"workmap/gateway/internal/tenant"
+)
+
+func TestResolveTenant(t *testing.T) {
+	tests := []struct {
+		name           string
+		host           string
+		expectedTenant string
+		expectedOK     bool
+	}{
+		{
+			name:           "tenant host",
+			host:           "acme.work-map.test",
+			expectedTenant: "acme",
+			expectedOK:     true,
+		},
+		{
+			name: "other host",
+			host: "work-map.test",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := &Middleware{
+				logger:  zap.NewNop(),
+				tenants: tenant.NewResolver(&tenant.Config{Hosts: map[string]string{"acme.work-map.test": "acme"}}),
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+
+			var name string
+			var ok bool
+			middleware.ResolveTenant(func(w http.ResponseWriter, r *http.Request) {
+				name, ok = tenant.FromContext(r.Context())
+			})(w, req)
+
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedTenant, name)
+		})
+	}
+}
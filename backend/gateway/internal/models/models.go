@@ -2,7 +2,10 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"net/mail"
+	"slices"
+	"workmap/gateway/internal/principal"
 )
 
 type User struct {
@@ -28,3 +31,80 @@ func (u *User) Validate() error {
 
 	return nil
 }
+
+type MFAConfirm struct {
+	Code string `json:"code"`
+}
+
+func (c *MFAConfirm) Validate() error {
+	if c.Code == "" {
+		return errors.New("invalid request")
+	}
+
+	return nil
+}
+
+// MFALogin redeems a login challenge with either a TOTP code or a recovery code.
+type MFALogin struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (l *MFALogin) Validate() error {
+	if l.ChallengeToken == "" {
+		return errors.New("invalid request")
+	}
+
+	if (l.Code == "") == (l.RecoveryCode == "") {
+		return errors.New("either code or recovery code is required")
+	}
+
+	return nil
+}
+
+type PasskeyLogin struct {
+	Email string `json:"email"`
+}
+
+func (l *PasskeyLogin) Validate() error {
+	if l.Email == "" {
+		return errors.New("invalid request")
+	}
+
+	_, err := mail.ParseAddress(l.Email)
+	if err != nil {
+		return errors.New("invalid email")
+	}
+
+	return nil
+}
+
+// APIKeyCreate issues an API key that expires after ExpiresInDays days.
+type APIKeyCreate struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+func (c *APIKeyCreate) Validate() error {
+	if c.Name == "" || len(c.Name) > 64 {
+		return errors.New("invalid name")
+	}
+
+	if len(c.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+
+	for _, s := range c.Scopes {
+		if !slices.Contains(principal.APIKeyScopes, s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+
+	if c.ExpiresInDays < 1 || c.ExpiresInDays > 365 {
+		return errors.New("expiry must be between 1 and 365 days")
+	}
+
+	return nil
+}
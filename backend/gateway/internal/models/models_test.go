@@ -50,3 +50,113 @@ func TestUser_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestMFALogin_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       MFALogin
+		expectedErr error
+	}{
+		{
+			name:        "totp code",
+			input:       MFALogin{ChallengeToken: "challenge", Code: "123456"},
+			expectedErr: nil,
+		},
+		{
+			name:        "recovery code",
+			input:       MFALogin{ChallengeToken: "challenge", RecoveryCode: "abcd-efgh"},
+			expectedErr: nil,
+		},
+		{
+			name:        "missing challenge token",
+			input:       MFALogin{Code: "123456"},
+			expectedErr: errors.New("invalid request"),
+		},
+		{
+			name:        "missing both codes",
+			input:       MFALogin{ChallengeToken: "challenge"},
+			expectedErr: errors.New("either code or recovery code is required"),
+		},
+		{
+			name:        "both codes",
+			input:       MFALogin{ChallengeToken: "challenge", Code: "123456", RecoveryCode: "abcd-efgh"},
+			expectedErr: errors.New("either code or recovery code is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestPasskeyLogin_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       PasskeyLogin
+		expectedErr error
+	}{
+		{
+			name:        "valid email",
+			input:       PasskeyLogin{Email: "user@email.com"},
+			expectedErr: nil,
+		},
+		{
+			name:        "missing email",
+			input:       PasskeyLogin{},
+			expectedErr: errors.New("invalid request"),
+		},
+		{
+			name:        "invalid email",
+			input:       PasskeyLogin{Email: "user"},
+			expectedErr: errors.New("invalid email"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestAPIKeyCreate_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       APIKeyCreate
+		expectedErr error
+	}{
+		{
+			name:        "valid key",
+			input:       APIKeyCreate{Name: "CI", Scopes: []string{"profile:read"}, ExpiresInDays: 90},
+			expectedErr: nil,
+		},
+		{
+			name:        "missing name",
+			input:       APIKeyCreate{Scopes: []string{"profile:read"}, ExpiresInDays: 90},
+			expectedErr: errors.New("invalid name"),
+		},
+		{
+			name:        "missing scopes",
+			input:       APIKeyCreate{Name: "CI", ExpiresInDays: 90},
+			expectedErr: errors.New("at least one scope is required"),
+		},
+		{
+			name:        "scope that cannot be granted",
+			input:       APIKeyCreate{Name: "CI", Scopes: []string{"account"}, ExpiresInDays: 90},
+			expectedErr: errors.New(`unknown scope "account"`),
+		},
+		{
+			name:        "no expiry",
+			input:       APIKeyCreate{Name: "CI", Scopes: []string{"profile:read"}},
+			expectedErr: errors.New("expiry must be between 1 and 365 days"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.input.Validate()
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
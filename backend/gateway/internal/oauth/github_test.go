@@ -0,0 +1,77 @@
+package oauth
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubProvider_Exchange(t *testing.T) {
+	tests := []struct {
+		name             string
+		emails           []map[string]any
+		expectedIdentity *Identity
+	}{
+		{
+			name: "primary verified email",
+			emails: []map[string]any{
+				{"email": "other@email.com", "primary": false, "verified": true},
+				{"email": "user@email.com", "primary": true, "verified": true},
+			},
+			expectedIdentity: &Identity{
+				Provider:      "github",
+				Subject:       "42",
+				Email:         "user@email.com",
+				EmailVerified: true,
+			},
+		},
+		{
+			name: "primary email not verified",
+			emails: []map[string]any{
+				{"email": "user@email.com", "primary": true, "verified": false},
+			},
+			expectedIdentity: &Identity{
+				Provider: "github",
+				Subject:  "42",
+				Email:    "user@email.com",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+				if r.FormValue("code") != "auth-code" || s256(r.FormValue("code_verifier")) != s256("verifier") {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bad_verification_code"})
+					return
+				}
+				writeJSON(w, http.StatusOK, map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+			})
+			mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+				writeJSON(w, http.StatusOK, map[string]any{"id": 42, "login": "octocat"})
+			})
+			mux.HandleFunc("GET /api/v3/user/emails", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, tt.emails)
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			p := NewGitHub(&ProviderConfig{
+				Name:     "github",
+				Issuer:   server.URL,
+				ClientID: "client-id",
+			})
+
+			identity, err := p.Exchange(context.Background(), "auth-code", "verifier", "")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedIdentity, identity)
+
+			_, err = p.Exchange(context.Background(), "auth-code", "wrong-verifier", "")
+			assert.Error(t, err)
+		})
+	}
+}
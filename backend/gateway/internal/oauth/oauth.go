@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+const (
+	KindOIDC   = "oidc"
+	KindGitHub = "github"
+)
+
+type ProviderConfig struct {
+	Name         string
+	Kind         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Identity is the user identity confirmed by an external provider.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type Provider interface {
+	AuthCodeURL(state, nonce, verifier string) string
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+func NewProvider(ctx context.Context, cfg *ProviderConfig) (Provider, error) {
+	switch cfg.Kind {
+	case KindOIDC:
+		return NewOIDC(ctx, cfg)
+	case KindGitHub:
+		return NewGitHub(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown oauth provider kind %q", cfg.Kind)
+	}
+}
+
+// RandomString returns a URL-safe random string suitable for state and nonce values.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
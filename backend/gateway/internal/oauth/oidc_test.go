@@ -0,0 +1,144 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestOIDCProvider_AuthCodeURL(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+
+	p, err := NewOIDC(context.Background(), &ProviderConfig{
+		Name:        "google",
+		Issuer:      fake.Issuer(),
+		ClientID:    "client-id",
+		RedirectURL: "http://localhost:4001/auth/google/callback",
+	})
+	require.NoError(t, err)
+
+	verifier := oauth2.GenerateVerifier()
+	u, err := url.Parse(p.AuthCodeURL("state-value", "nonce-value", verifier))
+	require.NoError(t, err)
+
+	q := u.Query()
+	assert.Equal(t, fake.Issuer()+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, "client-id", q.Get("client_id"))
+	assert.Equal(t, "state-value", q.Get("state"))
+	assert.Equal(t, "nonce-value", q.Get("nonce"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, s256(verifier), q.Get("code_challenge"))
+	assert.Equal(t, "openid email profile", q.Get("scope"))
+}
+
+func TestOIDCProvider_Exchange(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	validClaims := map[string]any{
+		"sub":            "google-user-1",
+		"aud":            "client-id",
+		"nonce":          "nonce-value",
+		"email":          "user@email.com",
+		"email_verified": true,
+	}
+	withClaims := func(overrides map[string]any) map[string]any {
+		claims := map[string]any{}
+		for k, v := range validClaims {
+			claims[k] = v
+		}
+		for k, v := range overrides {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name             string
+		idToken          string
+		verifier         string
+		expectedIdentity *Identity
+		expectError      bool
+	}{
+		{
+			name:     "valid id token",
+			idToken:  fake.IDToken(validClaims, fake.key),
+			verifier: "correct-verifier",
+			expectedIdentity: &Identity{
+				Provider:      "google",
+				Subject:       "google-user-1",
+				Email:         "user@email.com",
+				EmailVerified: true,
+			},
+		},
+		{
+			name:        "wrong code verifier",
+			idToken:     fake.IDToken(validClaims, fake.key),
+			verifier:    "wrong-verifier",
+			expectError: true,
+		},
+		{
+			name:        "nonce mismatch",
+			idToken:     fake.IDToken(withClaims(map[string]any{"nonce": "other-nonce"}), fake.key),
+			verifier:    "correct-verifier",
+			expectError: true,
+		},
+		{
+			name:        "wrong audience",
+			idToken:     fake.IDToken(withClaims(map[string]any{"aud": "other-client"}), fake.key),
+			verifier:    "correct-verifier",
+			expectError: true,
+		},
+		{
+			name:        "expired id token",
+			idToken:     fake.IDToken(withClaims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}), fake.key),
+			verifier:    "correct-verifier",
+			expectError: true,
+		},
+		{
+			name:        "signed by unknown key",
+			idToken:     fake.IDToken(validClaims, otherKey),
+			verifier:    "correct-verifier",
+			expectError: true,
+		},
+	}
+
+	p, err := NewOIDC(context.Background(), &ProviderConfig{
+		Name:     "google",
+		Issuer:   fake.Issuer(),
+		ClientID: "client-id",
+	})
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Grant("auth-code", s256("correct-verifier"), tt.idToken)
+
+			identity, err := p.Exchange(context.Background(), "auth-code", tt.verifier, "nonce-value")
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedIdentity, identity)
+		})
+	}
+}
+
+func TestNewOIDC_DiscoveryFailure(t *testing.T) {
+	_, err := NewOIDC(context.Background(), &ProviderConfig{
+		Name:   "google",
+		Issuer: "http://127.0.0.1:0",
+	})
+	assert.Error(t, err)
+}
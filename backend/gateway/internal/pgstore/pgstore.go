@@ -0,0 +1,187 @@
+// Package pgstore keeps the access tokens in PostgreSQL, for deployments that
+// already run it and would rather not operate Redis for sessions.
+package pgstore
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
+	"workmap/gateway/internal/redis"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// The token_key column was the token column, holding the raw tokens, before
+// the keys were derived.
+const schema = `
+CREATE TABLE IF NOT EXISTS access_tokens (
+	token_key  text PRIMARY KEY,
+	email      text NOT NULL,
+	expires_at timestamptz NOT NULL
+);
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'access_tokens' AND column_name = 'token') THEN
+		ALTER TABLE access_tokens RENAME COLUMN token TO token_key;
+	END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS access_tokens_expires_at ON access_tokens (expires_at);
+ALTER TABLE access_tokens
+	ADD COLUMN IF NOT EXISTS user_id text NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS issued_at timestamptz,
+	ADD COLUMN IF NOT EXISTS client_ip text NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS user_agent text NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS id text NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS refresh_token_hash text NOT NULL DEFAULT '';
+`
+
+const selectSession = `SELECT token_key, id, user_id, email, issued_at, expires_at, client_ip, user_agent, refresh_token_hash FROM access_tokens`
+
+func init() {
+	store.RegisterTokenBackend("postgres", func(cfg *store.TokenBackendConfig) (store.SessionBackend, error) {
+		return New(&Config{
+			DSN:     cfg.PostgresDSN,
+			Timeout: cfg.PostgresTimeout,
+		})
+	})
+}
+
+type Config struct {
+	DSN string
+	// Timeout bounds every query, 5 seconds if zero.
+	Timeout time.Duration
+}
+
+// Store keeps one row per session, at the key of its token. Expired rows are
+// ignored by lookups and deleted by every save. It is the backend of a
+// store.TokenStore, see store.NewTokenStore.
+type Store struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// New connects to the database and creates the access_tokens table if it
+// does not exist.
+func New(cfg *Config) (*Store, error) {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = pool.Exec(ctx, schema); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return &Store{
+		pool:    pool,
+		timeout: timeout,
+		now:     time.Now,
+	}, nil
+}
+
+func (s *Store) GetSessions(ctx context.Context, keys []string) (map[string]store.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, selectSession+` WHERE token_key = ANY($1) AND expires_at > $2`, keys, s.now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make(map[string]store.Session)
+	for rows.Next() {
+		var (
+			key      string
+			session  store.Session
+			issuedAt *time.Time
+		)
+		err = rows.Scan(
+			&key, &session.ID, &session.UserID, &session.Email, &issuedAt, &session.ExpiresAt,
+			&session.Client.IP, &session.Client.UserAgent, &session.RefreshTokenHash,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if issuedAt != nil {
+			session.IssuedAt = *issuedAt
+		}
+
+		sessions[key] = session
+	}
+
+	return sessions, rows.Err()
+}
+
+func (s *Store) SaveSession(ctx context.Context, key string, session store.Session) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := s.now()
+	if !now.Before(session.ExpiresAt) {
+		return nil
+	}
+
+	var issuedAt *time.Time
+	if !session.IssuedAt.IsZero() {
+		issuedAt = &session.IssuedAt
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	batch := &pgx.Batch{}
+	batch.Queue(`DELETE FROM access_tokens WHERE expires_at <= $1`, now)
+	batch.Queue(
+		`INSERT INTO access_tokens (token_key, id, user_id, email, issued_at, expires_at, client_ip, user_agent, refresh_token_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (token_key) DO UPDATE SET
+			id = excluded.id,
+			user_id = excluded.user_id,
+			email = excluded.email,
+			issued_at = excluded.issued_at,
+			expires_at = excluded.expires_at,
+			client_ip = excluded.client_ip,
+			user_agent = excluded.user_agent,
+			refresh_token_hash = excluded.refresh_token_hash`,
+		key, session.ID, session.UserID, session.Email, issuedAt, session.ExpiresAt,
+		session.Client.IP, session.Client.UserAgent, session.RefreshTokenHash,
+	)
+
+	return s.pool.SendBatch(ctx, batch).Close()
+}
+
+func (s *Store) DeleteSessions(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, `DELETE FROM access_tokens WHERE token_key = ANY($1)`, keys)
+
+	return err
+}
+
+func (s *Store) Close() error {
+	s.pool.Close()
+
+	return nil
+}
@@ -0,0 +1,32 @@
+// Package apikey generates API keys. Only the hash of a key is stored, the
+// key itself is shown to its owner once.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix makes keys recognizable, e.g. by secret scanners.
+const Prefix = "wmk_"
+
+// Generate returns a new key and its public ID.
+func Generate() (key, id string, err error) {
+	b := make([]byte, 40)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	return Prefix + base64.RawURLEncoding.EncodeToString(b[:32]), hex.EncodeToString(b[32:]), nil
+}
+
+// Hash returns the hex encoded SHA-256 of key. Keys are random, so unlike
+// passwords they need no salt or slow hash.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,27 @@
+package apikey
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	key, id, err := Generate()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key, Prefix))
+	assert.Len(t, key, len(Prefix)+43)
+	assert.Len(t, id, 16)
+
+	other, otherID, err := Generate()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+	assert.NotEqual(t, id, otherID)
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, "ef797c8118f02dfb649607dd5d3f8c7623048c9c063d532cc95c5ed7a898a64f", Hash("12345678"))
+	assert.Equal(t, Hash("wmk_key"), Hash(" wmk_key\n"))
+}
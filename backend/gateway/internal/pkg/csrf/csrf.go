@@ -0,0 +1,53 @@
+// Package csrf issues and checks signed double-submit tokens. The token is
+// sent both as a cookie and in a header, and its signature covers the session
+// cookie it was issued for, so a token obtained by an attacker, who can plant
+// cookies e.g. from a sibling subdomain, is not valid for the victim's session.
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	CookieName = "csrf_token"
+	HeaderName = "X-CSRF-Token"
+
+	// SessionCookieName is the cookie whose value a token is bound to.
+	SessionCookieName = "refresh_token"
+)
+
+// Generate returns a new token signed with secret for session, the value of
+// the session cookie, which may be empty.
+func Generate(secret []byte, session string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+
+	return nonce + "." + sign(secret, nonce, session), nil
+}
+
+// Valid reports whether token was generated with secret for session.
+func Valid(secret []byte, token, session string) bool {
+	nonce, sig, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(sign(secret, nonce, session)))
+}
+
+func sign(secret []byte, nonce, session string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(nonce))
+	mac.Write([]byte{0})
+	mac.Write([]byte(session))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
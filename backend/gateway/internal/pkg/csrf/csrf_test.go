@@ -0,0 +1,63 @@
+package csrf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	secret := []byte("secret")
+
+	token, err := Generate(secret, "session")
+	require.NoError(t, err)
+
+	other, err := Generate(secret, "session")
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	nonce, _, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name     string
+		token    string
+		expected bool
+	}{
+		{
+			name:     "valid token",
+			token:    token,
+			expected: true,
+		},
+		{
+			name:     "signed with another secret",
+			token:    nonce + "." + sign([]byte("other"), nonce, "session"),
+			expected: false,
+		},
+		{
+			name:     "issued for another session",
+			token:    nonce + "." + sign(secret, nonce, "attacker"),
+			expected: false,
+		},
+		{
+			name:     "issued without a session",
+			token:    nonce + "." + sign(secret, nonce, ""),
+			expected: false,
+		},
+		{
+			name:     "unsigned token",
+			token:    nonce,
+			expected: false,
+		},
+		{
+			name:     "empty token",
+			token:    "",
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Valid(secret, tt.token, "session"))
+		})
+	}
+}
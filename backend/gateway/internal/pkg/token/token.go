@@ -12,6 +12,18 @@ import (
 type TokenExtractor interface {
 	ExtractTTL(accessToken string) (time.Duration, error)
 	ExtractEmail(accessToken string) (string, error)
+	ExtractClaims(accessToken string) (Claims, error)
+}
+
+// Claims are the access token claims the gateway reads. ID, Subject and
+// IssuedAt are zero when the token does not have them.
+type Claims struct {
+	// ID is the jti claim.
+	ID        string
+	Subject   string
+	Email     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
 }
 
 type AccessTokenExtractor struct{}
@@ -69,3 +81,48 @@ func (e *AccessTokenExtractor) ExtractEmail(token string) (email string, err err
 
 	return email, nil
 }
+
+func (e *AccessTokenExtractor) ExtractClaims(token string) (Claims, error) {
+	payloadData, err := extractPayload(token)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	email, ok := payloadData["email"].(string)
+	if !ok {
+		return Claims{}, errors.New("email not found in the token")
+	}
+
+	exp, ok := payloadData["exp"].(float64)
+	if !ok {
+		return Claims{}, errors.New("exp not found in the token")
+	}
+
+	c := Claims{
+		Email:     email,
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}
+	if jti, ok := payloadData["jti"].(string); ok {
+		c.ID = jti
+	}
+	if sub, ok := payloadData["sub"].(string); ok {
+		c.Subject = sub
+	}
+	if iat, ok := payloadData["iat"].(float64); ok {
+		c.IssuedAt = time.Unix(int64(iat), 0)
+	}
+
+	return c, nil
+}
+
+// StringClaim returns the claim name of the token if it is a string.
+func StringClaim(token, name string) (string, bool) {
+	payloadData, err := extractPayload(token)
+	if err != nil {
+		return "", false
+	}
+
+	v, ok := payloadData[name].(string)
+
+	return v, ok
+}
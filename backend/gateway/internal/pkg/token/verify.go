@@ -0,0 +1,21 @@
+package token
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Verify checks the signature and expiry of an access token with the key
+// the Auth service signs it with (JWT_ACCESS_SECRET_KEY), then returns its
+// claims.
+func Verify(accessToken string, secret []byte) (Claims, error) {
+	_, err := jwt.Parse(accessToken, func(*jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS512.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Claims{}, err
+	}
+
+	e := &AccessTokenExtractor{}
+
+	return e.ExtractClaims(accessToken)
+}
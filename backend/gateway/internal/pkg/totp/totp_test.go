@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA1 test key "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		unix         int64
+		expectedCode string
+	}{
+		{name: "59", unix: 59, expectedCode: "287082"},
+		{name: "1111111109", unix: 1111111109, expectedCode: "081804"},
+		{name: "1234567890", unix: 1234567890, expectedCode: "005924"},
+		{name: "2000000000", unix: 2000000000, expectedCode: "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, code)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := Code(rfcSecret, now)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		code         string
+		at           time.Time
+		expectedOk   bool
+		expectedStep int64
+	}{
+		{name: "current step", code: code, at: now, expectedOk: true, expectedStep: Step(now)},
+		{name: "previous step within skew", code: code, at: now.Add(Period), expectedOk: true, expectedStep: Step(now)},
+		{name: "next step within skew", code: code, at: now.Add(-Period), expectedOk: true, expectedStep: Step(now)},
+		{name: "outside skew", code: code, at: now.Add(2 * Period), expectedOk: false},
+		{name: "wrong code", code: "000000", at: now, expectedOk: false},
+		{name: "wrong length", code: "12345", at: now, expectedOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, tt.at)
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedStep, step)
+		})
+	}
+}
+
+func TestURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	u, err := url.Parse(URI("Work Map", "user@email.com", secret))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Work Map:user@email.com", u.Path)
+	assert.Equal(t, secret, u.Query().Get("secret"))
+	assert.Equal(t, "Work Map", u.Query().Get("issuer"))
+}
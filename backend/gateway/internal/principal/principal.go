@@ -0,0 +1,59 @@
+// Package principal carries the authenticated caller of a request from the
+// auth middleware to the handlers.
+package principal
+
+import (
+	"context"
+	"slices"
+)
+
+const (
+	KindUser   = "user"
+	KindAPIKey = "api_key"
+)
+
+const (
+	// ScopeProfileRead allows reading the profile of the caller.
+	ScopeProfileRead = "profile:read"
+	// ScopeAccount guards account security settings such as 2FA, passkeys
+	// and API keys. It is never granted to API keys.
+	ScopeAccount = "account"
+)
+
+// UserScopes are every scope, those of the users.
+var UserScopes = []string{ScopeProfileRead, ScopeAccount}
+
+// APIKeyScopes are the scopes an API key can be issued with.
+var APIKeyScopes = []string{ScopeProfileRead}
+
+type Principal struct {
+	Kind string
+	// UserID is the subject of the access token of a KindUser principal,
+	// empty for the sessions saved without one.
+	UserID string
+	Email  string
+	// APIKeyID identifies the key a KindAPIKey principal authenticated with.
+	APIKeyID string
+	// Scopes limits what an API key may do. Users have every scope.
+	Scopes []string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	if p.Kind == KindUser {
+		return true
+	}
+
+	return slices.Contains(p.Scopes, scope)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+
+	return p, ok
+}
@@ -0,0 +1,50 @@
+package principal
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		scope     string
+		expected  bool
+	}{
+		{
+			name:      "user has every scope",
+			principal: Principal{Kind: KindUser},
+			scope:     ScopeAccount,
+			expected:  true,
+		},
+		{
+			name:      "api key with scope",
+			principal: Principal{Kind: KindAPIKey, Scopes: []string{ScopeProfileRead}},
+			scope:     ScopeProfileRead,
+			expected:  true,
+		},
+		{
+			name:      "api key without scope",
+			principal: Principal{Kind: KindAPIKey, Scopes: []string{ScopeProfileRead}},
+			scope:     ScopeAccount,
+			expected:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.principal.HasScope(tt.scope))
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	p := &Principal{Kind: KindUser, Email: "user@email.com"}
+	got, ok := FromContext(NewContext(context.Background(), p))
+	assert.True(t, ok)
+	assert.Equal(t, p, got)
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis"
+	"sort"
+	"time"
+)
+
+// APIKey is the metadata of an issued API key. The key itself is never
+// stored, it is looked up by its hash.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type APIKeyStore interface {
+	APIKeyGetter
+	SaveAPIKey(hash string, k APIKey) error
+	ListAPIKeys(email string) ([]APIKey, error)
+	DeleteAPIKey(email, id string) (bool, error)
+}
+
+type APIKeyGetter interface {
+	// GetAPIKey returns ErrNotFound for keys that were never saved, were
+	// deleted or have expired.
+	GetAPIKey(hash string) (APIKey, error)
+}
+
+// SaveAPIKey stores the key until it expires and adds it to the owner's
+// index, which maps key IDs to hashes for listing and revocation.
+func (r *RedisStore) SaveAPIKey(hash string, k APIKey) error {
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(r.prefix+"api_key:"+hash, data, time.Until(k.ExpiresAt))
+		pipe.HSet(r.prefix+"api_keys:"+k.Email, k.ID, hash)
+		return nil
+	})
+
+	return err
+}
+
+func (r *RedisStore) GetAPIKey(hash string) (APIKey, error) {
+	data, err := r.client.Get(r.prefix + "api_key:" + hash).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return APIKey{}, ErrNotFound
+		}
+
+		return APIKey{}, err
+	}
+
+	var k APIKey
+	if err = json.Unmarshal(data, &k); err != nil {
+		return APIKey{}, err
+	}
+
+	return k, nil
+}
+
+// ListAPIKeys returns the keys of email that have not expired, oldest first,
+// and drops expired keys from the index.
+func (r *RedisStore) ListAPIKeys(email string) ([]APIKey, error) {
+	index, err := r.client.HGetAll(r.prefix + "api_keys:" + email).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]APIKey, 0, len(index))
+	for id, hash := range index {
+		k, err := r.GetAPIKey(hash)
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+
+			r.client.HDel(r.prefix+"api_keys:"+email, id)
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// DeleteAPIKey revokes the key of email with the given ID and reports
+// whether there was one.
+func (r *RedisStore) DeleteAPIKey(email, id string) (bool, error) {
+	hash, err := r.client.HGet(r.prefix+"api_keys:"+email, id).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(r.prefix + "api_key:" + hash)
+		pipe.HDel(r.prefix+"api_keys:"+email, id)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
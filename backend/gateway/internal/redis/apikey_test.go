@@ -0,0 +1,72 @@
+package store
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	store := &RedisStore{client: rdb}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := APIKey{
+		ID:        "first",
+		Email:     "user@email.com",
+		Name:      "CI",
+		Scopes:    []string{"profile:read"},
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	second := first
+	second.ID = "second"
+	second.CreatedAt = now.Add(time.Second)
+	second.ExpiresAt = now.Add(2 * time.Minute)
+
+	require.NoError(t, store.SaveAPIKey("hash1", first))
+	require.NoError(t, store.SaveAPIKey("hash2", second))
+
+	got, err := store.GetAPIKey("hash1")
+	assert.NoError(t, err)
+	assert.Equal(t, first, got)
+
+	keys, err := store.ListAPIKeys("user@email.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []APIKey{first, second}, keys)
+
+	// The second key expires.
+	s.FastForward(3 * time.Minute)
+
+	_, err = store.GetAPIKey("hash2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	keys, err = store.ListAPIKeys("user@email.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []APIKey{first}, keys)
+
+	ids, err := s.HKeys("api_keys:user@email.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first"}, ids)
+
+	deleted, err := store.DeleteAPIKey("user@email.com", "first")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	_, err = store.GetAPIKey("hash1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	deleted, err = store.DeleteAPIKey("user@email.com", "first")
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TokenBackendConfig holds the settings of every token store backend, each
+// backend reads its own. TokenKeys derive the keys of every backend.
+type TokenBackendConfig struct {
+	TokenKeys TokenKeyConfig
+	// Redis is the connection shared with the other stores.
+	Redis           *RedisStore
+	MemoryShards    int
+	MemorySweep     time.Duration
+	PostgresDSN     string
+	PostgresTimeout time.Duration
+}
+
+// TokenBackend opens the backend of a TokenStore.
+type TokenBackend func(cfg *TokenBackendConfig) (SessionBackend, error)
+
+var (
+	tokenBackendsMu sync.RWMutex
+	tokenBackends   = map[string]TokenBackend{
+		"redis": func(cfg *TokenBackendConfig) (SessionBackend, error) {
+			return cfg.Redis, nil
+		},
+	}
+)
+
+// RegisterTokenBackend makes a token store backend selectable by name. It
+// panics if the name is already taken, like database/sql.Register.
+func RegisterTokenBackend(name string, b TokenBackend) {
+	tokenBackendsMu.Lock()
+	defer tokenBackendsMu.Unlock()
+
+	if _, ok := tokenBackends[name]; ok {
+		panic("store: token backend registered twice: " + name)
+	}
+	tokenBackends[name] = b
+}
+
+// TokenBackends returns the names of the registered backends, sorted.
+func TokenBackends() []string {
+	tokenBackendsMu.RLock()
+	defer tokenBackendsMu.RUnlock()
+
+	names := make([]string, 0, len(tokenBackends))
+	for name := range tokenBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// OpenTokenStore opens the token store of the backend registered as name,
+// keyed by cfg.TokenKeys.
+func OpenTokenStore(name string, cfg *TokenBackendConfig) (TokenStore, error) {
+	tokenBackendsMu.RLock()
+	open, ok := tokenBackends[name]
+	tokenBackendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown token store backend %q", name)
+	}
+
+	b, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTokenStore(b, cfg.TokenKeys), nil
+}
@@ -0,0 +1,102 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	store "workmap/gateway/internal/redis"
+	"workmap/gateway/internal/redis/storetest"
+)
+
+func TestTokenStoreConformance(t *testing.T) {
+	storetest.TestTokenStore(t, func(t *testing.T) (store.SessionBackend, func(time.Duration)) {
+		s := miniredis.RunT(t)
+		host, port, err := net.SplitHostPort(s.Addr())
+		require.NoError(t, err)
+
+		r, err := store.NewRedis(&store.RedisConfig{Host: host, Port: port})
+		require.NoError(t, err)
+
+		return &r, s.FastForward
+	})
+}
+
+// The sentinel and cluster runs need a deployment the suite may write to, for
+// example TEST_REDIS_SENTINEL_ADDRS=localhost:26379 with
+// TEST_REDIS_SENTINEL_MASTER=mymaster, or
+// TEST_REDIS_CLUSTER_ADDRS=localhost:7000,localhost:7001,localhost:7002.
+// Every store writes under a key prefix of its own, and the expiration tests
+// are skipped since the server's clock cannot be moved.
+func TestTokenStoreConformance_Sentinel(t *testing.T) {
+	addrs := os.Getenv("TEST_REDIS_SENTINEL_ADDRS")
+	if addrs == "" {
+		t.Skip("TEST_REDIS_SENTINEL_ADDRS is not set")
+	}
+
+	testRemoteRedis(t, store.RedisConfig{
+		Mode:       store.RedisModeSentinel,
+		Addrs:      strings.Split(addrs, ","),
+		MasterName: os.Getenv("TEST_REDIS_SENTINEL_MASTER"),
+		Password:   os.Getenv("TEST_REDIS_PASSWORD"),
+	})
+}
+
+func TestTokenStoreConformance_Cluster(t *testing.T) {
+	addrs := os.Getenv("TEST_REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("TEST_REDIS_CLUSTER_ADDRS is not set")
+	}
+
+	testRemoteRedis(t, store.RedisConfig{
+		Mode:     store.RedisModeCluster,
+		Addrs:    strings.Split(addrs, ","),
+		Password: os.Getenv("TEST_REDIS_PASSWORD"),
+	})
+}
+
+func testRemoteRedis(t *testing.T, cfg store.RedisConfig) {
+	open := func(t *testing.T) store.RedisStore {
+		cfg := cfg
+		cfg.KeyPrefix = fmt.Sprintf("storetest:%d:", time.Now().UnixNano())
+
+		r, err := store.NewRedis(&cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { r.Close() })
+
+		return r
+	}
+
+	storetest.TestTokenStore(t, func(t *testing.T) (store.SessionBackend, func(time.Duration)) {
+		r := open(t)
+
+		return &r, nil
+	})
+
+	// The 2FA challenge and its attempts are deleted together but hash to
+	// different slots.
+	t.Run("mfa challenge", func(t *testing.T) {
+		r := open(t)
+
+		require.NoError(t, r.SaveMFAChallenge("challenge", store.MFAChallenge{Email: "user@email.com"}, time.Minute))
+		_, err := r.FailMFAChallenge("challenge", time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, r.DeleteMFAChallenge("challenge"))
+		_, err = r.GetMFAChallenge("challenge")
+		assert.EqualError(t, err, "2fa challenge not found")
+		attempts, err := r.FailMFAChallenge("challenge", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), attempts)
+	})
+}
+
+func TestNewRedis_UnknownMode(t *testing.T) {
+	_, err := store.NewRedis(&store.RedisConfig{Mode: "replicated"})
+	require.EqualError(t, err, `unknown redis mode "replicated"`)
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"strings"
+)
+
+const tokenInvalidationChannel = "access_token:invalidate"
+
+// TokenInvalidator tells every gateway to forget the cached tokens with the
+// given cache keys.
+type TokenInvalidator interface {
+	PublishTokenInvalidation(ctx context.Context, keys []string) error
+	SubscribeTokenInvalidations(ctx context.Context, fn func(keys []string)) error
+}
+
+// PublishTokenInvalidation sends keys to the subscribers on every gateway,
+// including this one. The keys must not contain commas.
+func (r *RedisStore) PublishTokenInvalidation(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return r.do(ctx, func() error {
+		return r.client.Publish(r.prefix+tokenInvalidationChannel, strings.Join(keys, ",")).Err()
+	})
+}
+
+// SubscribeTokenInvalidations calls fn with the keys of every invalidation
+// until ctx is done. It returns once subscribed, or with the error of the
+// first attempt while the client keeps resubscribing in the background.
+func (r *RedisStore) SubscribeTokenInvalidations(ctx context.Context, fn func(keys []string)) error {
+	ps := r.client.Subscribe(r.prefix + tokenInvalidationChannel)
+	err := r.do(ctx, func() error {
+		_, err := ps.Receive()
+		return err
+	})
+
+	go func() {
+		defer ps.Close()
+
+		ch := ps.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				fn(strings.Split(msg.Payload, ","))
+			}
+		}
+	}()
+
+	return err
+}
@@ -0,0 +1,11 @@
+package store
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var redisUp = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "gateway_redis_up",
+	Help: "Whether Redis answered the last ping.",
+})
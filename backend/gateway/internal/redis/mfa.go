@@ -0,0 +1,137 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+// MFAChallenge holds the tokens of a login that is waiting for its second factor.
+type MFAChallenge struct {
+	Email        string `json:"email"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// MFAStore keeps the short-lived 2FA state. Enabled secrets and recovery codes
+// are persisted by the Auth service.
+type MFAStore interface {
+	SavePendingMFASecret(email, secret string, ttl time.Duration) error
+	GetPendingMFASecret(email string) (string, error)
+	GetLegacyMFA(email string) (secret string, recoveryCodeHashes []string, err error)
+	DeleteLegacyMFA(email string) error
+	UseMFAStep(email string, step int64, ttl time.Duration) (bool, error)
+	SaveMFAChallenge(challenge string, c MFAChallenge, ttl time.Duration) error
+	GetMFAChallenge(challenge string) (MFAChallenge, error)
+	FailMFAChallenge(challenge string, ttl time.Duration) (int64, error)
+	DeleteMFAChallenge(challenge string) error
+}
+
+func (r *RedisStore) SavePendingMFASecret(email, secret string, ttl time.Duration) error {
+	return r.client.Set(r.prefix+"mfa_pending:"+email, secret, ttl).Err()
+}
+
+func (r *RedisStore) GetPendingMFASecret(email string) (string, error) {
+	secret, err := r.client.Get(r.prefix + "mfa_pending:" + email).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", errors.New("2fa setup not found")
+		}
+
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// GetLegacyMFA returns a secret and recovery codes enabled before they were
+// moved to the Auth service, or an empty secret when there are none.
+func (r *RedisStore) GetLegacyMFA(email string) (string, []string, error) {
+	secret, err := r.client.Get(r.prefix + "mfa:" + email).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil, nil
+		}
+
+		return "", nil, err
+	}
+
+	codes, err := r.client.SMembers(r.prefix + "mfa_recovery:" + email).Result()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, codes, nil
+}
+
+func (r *RedisStore) DeleteLegacyMFA(email string) error {
+	_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(r.prefix + "mfa:" + email)
+		pipe.Del(r.prefix + "mfa_recovery:" + email)
+		return nil
+	})
+
+	return err
+}
+
+// UseMFAStep records that the TOTP step has been used and reports false if it
+// already was, so a code cannot be replayed while it is still valid.
+func (r *RedisStore) UseMFAStep(email string, step int64, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(r.prefix+fmt.Sprintf("mfa_step:%s:%d", email, step), 1, ttl).Result()
+}
+
+func (r *RedisStore) SaveMFAChallenge(challenge string, c MFAChallenge, ttl time.Duration) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(r.prefix+"mfa_challenge:"+challenge, data, ttl).Err()
+}
+
+func (r *RedisStore) GetMFAChallenge(challenge string) (MFAChallenge, error) {
+	data, err := r.client.Get(r.prefix + "mfa_challenge:" + challenge).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return MFAChallenge{}, errors.New("2fa challenge not found")
+		}
+
+		return MFAChallenge{}, err
+	}
+
+	var c MFAChallenge
+	if err = json.Unmarshal(data, &c); err != nil {
+		return MFAChallenge{}, err
+	}
+
+	return c, nil
+}
+
+// FailMFAChallenge counts a failed attempt and returns the attempts so far.
+func (r *RedisStore) FailMFAChallenge(challenge string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(r.prefix + "mfa_challenge_attempts:" + challenge)
+		pipe.Expire(r.prefix+"mfa_challenge_attempts:"+challenge, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+// DeleteMFAChallenge deletes the keys one by one, as they may live in
+// different cluster slots.
+func (r *RedisStore) DeleteMFAChallenge(challenge string) error {
+	_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(r.prefix + "mfa_challenge:" + challenge)
+		pipe.Del(r.prefix + "mfa_challenge_attempts:" + challenge)
+		return nil
+	})
+
+	return err
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMFA(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	store := &RedisStore{client: rdb}
+
+	_, err = store.GetPendingMFASecret("user@email.com")
+	assert.Equal(t, errors.New("2fa setup not found"), err)
+
+	require.NoError(t, store.SavePendingMFASecret("user@email.com", "SECRET", time.Minute))
+	pending, err := store.GetPendingMFASecret("user@email.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "SECRET", pending)
+
+	fresh, err := store.UseMFAStep("user@email.com", 100, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = store.UseMFAStep("user@email.com", 100, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, fresh)
+}
+
+func TestLegacyMFA(t *testing.T) {
+	s := miniredis.RunT(t)
+	store := &RedisStore{client: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+
+	secret, codes, err := store.GetLegacyMFA("user@email.com")
+	assert.NoError(t, err)
+	assert.Empty(t, secret)
+	assert.Empty(t, codes)
+
+	require.NoError(t, s.Set("mfa:user@email.com", "SECRET"))
+	_, err = s.SAdd("mfa_recovery:user@email.com", "hash1", "hash2")
+	require.NoError(t, err)
+
+	secret, codes, err = store.GetLegacyMFA("user@email.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "SECRET", secret)
+	assert.ElementsMatch(t, []string{"hash1", "hash2"}, codes)
+
+	require.NoError(t, store.DeleteLegacyMFA("user@email.com"))
+	assert.Empty(t, s.Keys())
+}
+
+func TestMFAChallenge(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	store := &RedisStore{client: rdb}
+
+	saved := MFAChallenge{
+		Email:        "user@email.com",
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+	}
+	require.NoError(t, store.SaveMFAChallenge("challenge", saved, time.Minute))
+
+	got, err := store.GetMFAChallenge("challenge")
+	assert.NoError(t, err)
+	assert.Equal(t, saved, got)
+
+	for i := int64(1); i <= 3; i++ {
+		attempts, err := store.FailMFAChallenge("challenge", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, i, attempts)
+	}
+	assert.Equal(t, time.Minute, s.TTL("mfa_challenge_attempts:challenge"))
+
+	require.NoError(t, store.DeleteMFAChallenge("challenge"))
+	assert.False(t, s.Exists("mfa_challenge_attempts:challenge"))
+
+	_, err = store.GetMFAChallenge("challenge")
+	assert.Equal(t, errors.New("2fa challenge not found"), err)
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+type OAuthStateStore interface {
+	SaveOAuthState(state string, s OAuthState, ttl time.Duration) error
+	TakeOAuthState(state string) (OAuthState, error)
+}
+
+func (r *RedisStore) SaveOAuthState(state string, s OAuthState, ttl time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	res := r.client.Set(r.prefix+"oauth_state:"+state, data, ttl)
+	if res.Err() != nil {
+		return res.Err()
+	}
+
+	return nil
+}
+
+// TakeOAuthState returns the state saved by SaveOAuthState and deletes it,
+// so every state value can be redeemed only once.
+func (r *RedisStore) TakeOAuthState(state string) (OAuthState, error) {
+	var get *redis.StringCmd
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(r.prefix + "oauth_state:" + state)
+		pipe.Del(r.prefix + "oauth_state:" + state)
+		return nil
+	})
+	if err != nil {
+		if err == redis.Nil {
+			return OAuthState{}, errors.New("oauth state not found")
+		}
+
+		return OAuthState{}, err
+	}
+
+	var s OAuthState
+	if err = json.Unmarshal([]byte(get.Val()), &s); err != nil {
+		return OAuthState{}, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestOAuthState(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	store := &RedisStore{client: rdb}
+
+	saved := OAuthState{
+		Provider:     "google",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+	}
+
+	err = store.SaveOAuthState("state", saved, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, s.TTL("oauth_state:state"))
+
+	got, err := store.TakeOAuthState("state")
+	assert.NoError(t, err)
+	assert.Equal(t, saved, got)
+
+	_, err = store.TakeOAuthState("state")
+	assert.Equal(t, errors.New("oauth state not found"), err)
+
+	_, err = store.TakeOAuthState("unknown")
+	assert.Equal(t, errors.New("oauth state not found"), err)
+}
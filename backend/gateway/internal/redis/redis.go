@@ -1,38 +1,176 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/go-redis/redis"
+	"sync/atomic"
+	"time"
+	"workmap/gateway/internal/tenant"
 )
 
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
+// ErrUnavailable is returned without trying Redis while Watch finds it down.
+var ErrUnavailable = errors.New("redis unavailable")
+
 type RedisConfig struct {
+	// Mode is one of the RedisMode constants, standalone if empty.
+	Mode     string
 	Host     string
 	Port     string
 	Password string
+	// Addrs are the sentinel addresses in sentinel mode and the seed nodes
+	// in cluster mode.
+	Addrs      []string
+	MasterName string
+	// DB is the database index, always 0 in cluster mode.
+	DB int
+	// KeyPrefix is prepended to every key and channel, so that several
+	// environments can share a Redis.
+	KeyPrefix string
+	// AllowUnavailable returns the store even if Redis cannot be reached, to
+	// be reconnected by Watch.
+	AllowUnavailable bool
+}
+
+// HealthChecker reports whether a dependency is reachable.
+type HealthChecker interface {
+	Up() bool
 }
 
 type RedisStore struct {
-	client *redis.Client
+	client redis.UniversalClient
+	prefix string
+	// down is shared by the copies of the store, nil in tests.
+	down *atomic.Bool
 }
 
 func NewRedis(cfg *RedisConfig) (RedisStore, error) {
-	addr := fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
-	var client = redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: cfg.Password,
-		DB:       0,
-	})
-	if client == nil {
-		return RedisStore{}, errors.New("cannot run redis")
+	var client redis.UniversalClient
+	switch cfg.Mode {
+	case RedisModeStandalone, "":
+		client = redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		})
+	case RedisModeSentinel:
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    cfg.MasterName,
+			SentinelAddrs: cfg.Addrs,
+			Password:      cfg.Password,
+			DB:            cfg.DB,
+		})
+	case RedisModeCluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    cfg.Addrs,
+			Password: cfg.Password,
+		})
+	default:
+		return RedisStore{}, fmt.Errorf("unknown redis mode %q", cfg.Mode)
 	}
 
+	down := &atomic.Bool{}
 	err := client.Ping().Err()
 	if err != nil {
-		return RedisStore{}, errors.New("cannot ping to redis")
+		if !cfg.AllowUnavailable {
+			return RedisStore{}, errors.New("cannot ping to redis")
+		}
+		down.Store(true)
 	}
+	redisUp.Set(boolToFloat(err == nil))
 
 	return RedisStore{
 		client: client,
+		prefix: cfg.KeyPrefix,
+		down:   down,
 	}, nil
 }
+
+// namespace prefixes the keys of the tenant of ctx.
+func (r *RedisStore) namespace(ctx context.Context) string {
+	if t, _ := tenant.FromContext(ctx); t != "" {
+		return r.prefix + "tenant:" + t + ":"
+	}
+
+	return r.prefix
+}
+
+// Close closes the connection shared by the copies of the store.
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
+
+// Up reports whether Redis answered the last ping.
+func (r *RedisStore) Up() bool {
+	return r.down == nil || !r.down.Load()
+}
+
+// Watch pings Redis every interval until ctx is done, calling onChange when
+// it goes down or comes back. The client reconnects by itself, the pings
+// only find out when.
+func (r *RedisStore) Watch(ctx context.Context, every time.Duration, onChange func(up bool, err error)) {
+	down := r.down
+	if down == nil {
+		// A store built without NewRedis still reports the changes.
+		down = &atomic.Bool{}
+	}
+
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := r.client.Ping().Err()
+			up := err == nil
+			redisUp.Set(boolToFloat(up))
+			if down.Swap(!up) == up {
+				onChange(up, err)
+			}
+		}
+	}()
+}
+
+// do runs fn, a call to a client without context support, and stops waiting
+// for it once ctx is done. fn itself finishes in the background, bounded by
+// the client's own timeouts. While Redis is down it fails at once.
+func (r *RedisStore) do(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !r.Up() {
+		return ErrUnavailable
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
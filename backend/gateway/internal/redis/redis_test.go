@@ -0,0 +1,60 @@
+package store_test
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+	store "workmap/gateway/internal/redis"
+)
+
+func TestNewRedis_Unavailable(t *testing.T) {
+	s := miniredis.RunT(t)
+	host, port, err := net.SplitHostPort(s.Addr())
+	require.NoError(t, err)
+	s.Close()
+
+	_, err = store.NewRedis(&store.RedisConfig{Host: host, Port: port})
+	require.EqualError(t, err, "cannot ping to redis")
+
+	r, err := store.NewRedis(&store.RedisConfig{Host: host, Port: port, AllowUnavailable: true})
+	require.NoError(t, err)
+	assert.False(t, r.Up())
+
+	_, err = r.GetSessions(context.Background(), []string{"token"})
+	assert.ErrorIs(t, err, store.ErrUnavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var changes atomic.Int32
+	r.Watch(ctx, 10*time.Millisecond, func(up bool, err error) {
+		assert.True(t, up)
+		assert.NoError(t, err)
+		changes.Add(1)
+	})
+
+	require.NoError(t, s.Restart())
+	assert.Eventually(t, func() bool { return changes.Load() == 1 }, time.Second, 10*time.Millisecond)
+	assert.True(t, r.Up())
+
+	sessions, err := r.GetSessions(context.Background(), []string{"token"})
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestNewRedis_DB(t *testing.T) {
+	s := miniredis.RunT(t)
+	host, port, err := net.SplitHostPort(s.Addr())
+	require.NoError(t, err)
+
+	r, err := store.NewRedis(&store.RedisConfig{Host: host, Port: port, DB: 2, KeyPrefix: "staging:"})
+	require.NoError(t, err)
+	require.NoError(t, r.SaveOAuthState("state", store.OAuthState{}, time.Minute))
+
+	assert.Empty(t, s.Keys())
+	assert.Equal(t, []string{"staging:oauth_state:state"}, s.DB(2).Keys())
+}
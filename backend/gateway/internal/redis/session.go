@@ -0,0 +1,62 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+	"workmap/gateway/internal/pkg/token"
+)
+
+// ErrNotFound is returned by the stores for tokens and API keys that were
+// never saved, were deleted or have expired.
+var ErrNotFound = errors.New("not found")
+
+// ErrUnsupported is returned for operations the configured backend lacks.
+var ErrUnsupported = errors.New("not supported by the token store")
+
+// Session is what a token store keeps about a live access token.
+type Session struct {
+	// ID identifies the session without revealing its token, see SessionID.
+	ID        string    `json:"id,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Email     string    `json:"email"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Client    Client    `json:"client"`
+	// RefreshTokenHash is the SHA-256 of the refresh token the access token
+	// was issued or refreshed with, so that revoking the session can revoke
+	// its refresh token too. It is never shown to the user.
+	RefreshTokenHash string `json:"refresh_token_hash,omitempty"`
+}
+
+// Client is the client an access token was issued to.
+type Client struct {
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// NewSession reads the session of accessToken from its claims.
+func NewSession(accessToken string, client Client) (Session, error) {
+	e := &token.AccessTokenExtractor{}
+	c, err := e.ExtractClaims(accessToken)
+	if err != nil {
+		return Session{}, err
+	}
+
+	return Session{
+		ID:        SessionID(accessToken),
+		UserID:    c.Subject,
+		Email:     c.Email,
+		IssuedAt:  c.IssuedAt,
+		ExpiresAt: c.ExpiresAt,
+		Client:    client,
+	}, nil
+}
+
+// SessionID is the SHA-256 of accessToken, hex encoded.
+func SessionID(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"sort"
+)
+
+// SessionStore lists and revokes the sessions of a user in the tenant of
+// the context.
+type SessionStore interface {
+	// ListSessions returns the live sessions of email, oldest first.
+	ListSessions(ctx context.Context, email string) ([]Session, error)
+	// RevokeSession deletes the session of email with the given ID and
+	// reports whether there was one.
+	RevokeSession(ctx context.Context, email, id string) (bool, error)
+}
+
+// sessionIndex maps the session IDs of email to their token keys.
+func (r *RedisStore) sessionIndex(ctx context.Context, email string) string {
+	return r.namespace(ctx) + "sessions:" + email
+}
+
+// ListSessions drops the sessions that expired or were logged out from the
+// index.
+func (r *RedisStore) ListSessions(ctx context.Context, email string) ([]Session, error) {
+	index := r.sessionIndex(ctx, email)
+
+	var ids map[string]string
+	err := r.do(ctx, func() error {
+		var err error
+		ids, err = r.client.HGetAll(index).Result()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make(map[string]*redis.StringCmd, len(ids))
+	err = r.do(ctx, func() error {
+		_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+			for id, key := range ids {
+				cmds[id] = pipe.Get(key)
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(cmds))
+	var gone []string
+	for id, cmd := range cmds {
+		if cmd.Err() != nil {
+			gone = append(gone, id)
+			continue
+		}
+		s := decodeSession(cmd.Val())
+		s.ID = id
+		sessions = append(sessions, s)
+	}
+	if len(gone) > 0 {
+		r.client.HDel(index, gone...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].IssuedAt.Before(sessions[j].IssuedAt)
+	})
+
+	return sessions, nil
+}
+
+func (r *RedisStore) RevokeSession(ctx context.Context, email, id string) (bool, error) {
+	index := r.sessionIndex(ctx, email)
+
+	var key string
+	err := r.do(ctx, func() error {
+		var err error
+		key, err = r.client.HGet(index, id).Result()
+		return err
+	})
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	var deleted int64
+	err = r.do(ctx, func() error {
+		var del *redis.IntCmd
+		_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+			del = pipe.Del(key)
+			pipe.HDel(index, id)
+			return nil
+		})
+		deleted = del.Val()
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted > 0, nil
+}
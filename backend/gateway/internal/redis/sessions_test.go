@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"workmap/gateway/internal/tenant"
+)
+
+func TestNamespace(t *testing.T) {
+	s := miniredis.RunT(t)
+	r := &RedisStore{
+		client: redis.NewClient(&redis.Options{Addr: s.Addr()}),
+		prefix: "staging:",
+	}
+	store := NewTokenStore(r, TokenKeyConfig{Pepper: []byte("pepper")})
+	live := Session{Email: "user39@email.com", ExpiresAt: time.Now().Add(time.Hour)}
+
+	acme := tenant.NewContext(context.Background(), "acme")
+	require.NoError(t, store.SaveAccessToken(acme, "token", live))
+	require.NoError(t, r.SavePendingMFASecret("user39@email.com", "secret", time.Minute))
+
+	keys := s.Keys()
+	require.Len(t, keys, 2)
+	assert.Equal(t, "staging:mfa_pending:user39@email.com", keys[0])
+	assert.Regexp(t, `^staging:tenant:acme:access_token:hmac:[0-9a-f]{64}$`, keys[1])
+
+	_, err := store.GetAccessToken(acme, "token")
+	assert.NoError(t, err)
+	_, err = store.GetAccessToken(context.Background(), "token")
+	assert.ErrorIs(t, err, ErrNotFound, "the default tenant does not see acme")
+	_, err = store.GetAccessToken(tenant.NewContext(context.Background(), "globex"), "token")
+	assert.ErrorIs(t, err, ErrNotFound, "other tenants do not see acme")
+}
+
+func TestSessions(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), "acme")
+	now := time.Now()
+
+	s := miniredis.RunT(t)
+	r := &RedisStore{client: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+	store := NewTokenStore(r, TokenKeyConfig{Pepper: []byte("pepper")})
+
+	save := func(t *testing.T, token string, issued time.Time) Session {
+		session := Session{
+			ID:        SessionID(token),
+			Email:     "user39@email.com",
+			IssuedAt:  issued,
+			ExpiresAt: now.Add(time.Hour),
+		}
+		require.NoError(t, store.SaveAccessToken(ctx, token, session))
+
+		return session
+	}
+	older := save(t, "older", now.Add(-time.Minute))
+	newer := save(t, "newer", now)
+	gone := save(t, "gone", now)
+	require.NoError(t, store.DeleteAccessToken(ctx, "gone"))
+
+	t.Run("lists live sessions oldest first", func(t *testing.T) {
+		sessions, err := r.ListSessions(ctx, "user39@email.com")
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		assert.Equal(t, older.ID, sessions[0].ID)
+		assert.Equal(t, newer.ID, sessions[1].ID)
+
+		ids, err := s.HKeys("tenant:acme:sessions:user39@email.com")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{older.ID, newer.ID}, ids, "%s was dropped from the index", gone.ID)
+	})
+
+	t.Run("is scoped to the tenant", func(t *testing.T) {
+		sessions, err := r.ListSessions(context.Background(), "user39@email.com")
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+
+		revoked, err := r.RevokeSession(context.Background(), "user39@email.com", older.ID)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+
+	t.Run("revokes a session", func(t *testing.T) {
+		revoked, err := r.RevokeSession(ctx, "user39@email.com", older.ID)
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		_, err = store.GetAccessToken(ctx, "older")
+		assert.ErrorIs(t, err, ErrNotFound)
+		_, err = store.GetAccessToken(ctx, "newer")
+		assert.NoError(t, err)
+
+		revoked, err = r.RevokeSession(ctx, "user39@email.com", older.ID)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	})
+}
@@ -0,0 +1,275 @@
+// Package storetest is the conformance suite every token store backend has
+// to pass.
+package storetest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	"workmap/gateway/internal/redis"
+)
+
+// Factory returns an empty backend and a function that moves the backend's
+// clock forward, so that expiration can be tested without waiting.
+// fastForward is nil for a backend whose clock cannot be moved, which skips
+// those tests.
+type Factory func(t *testing.T) (b store.SessionBackend, fastForward func(d time.Duration))
+
+// StoreFactory is Factory for a token store layered on another, like a cache.
+type StoreFactory func(t *testing.T) (s store.TokenStore, fastForward func(d time.Duration))
+
+// header starts every token of Token, so that a stored token can be found.
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS512","typ":"JWT"}`))
+
+// Token returns an unsigned access token for email expiring at exp, with a
+// jti of its own. Stores only read the claims, the signature is checked by
+// the Auth service.
+func Token(email string, exp time.Time) string {
+	jti := make([]byte, 8)
+	rand.Read(jti)
+
+	enc := base64.RawURLEncoding
+	payload, _ := json.Marshal(map[string]any{
+		"jti":   hex.EncodeToString(jti),
+		"sub":   "id-" + email,
+		"email": email,
+		"iat":   time.Now().Unix(),
+		"exp":   exp.Unix(),
+	})
+
+	return header + "." + enc.EncodeToString(payload) + "." + enc.EncodeToString([]byte("signature"))
+}
+
+// Session returns a token for email expiring at exp and its session.
+func Session(t *testing.T, email string, exp time.Time) (string, store.Session) {
+	at := Token(email, exp)
+	s, err := store.NewSession(at, store.Client{IP: "203.0.113.7", UserAgent: "storetest"})
+	require.NoError(t, err)
+
+	return at, s
+}
+
+// TestTokenStore runs the suite against the token stores of the backends
+// returned by newBackend, with every TokenKeyConfig mode.
+func TestTokenStore(t *testing.T, newBackend Factory) {
+	ctx := context.Background()
+
+	for _, keys := range []store.TokenKeyConfig{
+		{Mode: store.TokenKeyHMAC, Pepper: []byte("pepper")},
+		{Mode: store.TokenKeyJTI},
+	} {
+		t.Run(keys.Mode, func(t *testing.T) {
+			t.Run("raw token is never stored", func(t *testing.T) {
+				b, _ := newBackend(t)
+				saved := &savedBackend{SessionBackend: b}
+				at, session := Session(t, "user@email.com", time.Now().Add(time.Hour))
+
+				require.NoError(t, store.NewTokenStore(saved, keys).SaveAccessToken(ctx, at, session))
+
+				require.NotEmpty(t, saved.keys)
+				assert.NotContains(t, saved.keys[0], header)
+				assert.NotContains(t, saved.data[0], header)
+				got, err := b.GetSessions(ctx, []string{at})
+				require.NoError(t, err)
+				assert.Empty(t, got)
+			})
+
+			TestTokenStoreLayer(t, func(t *testing.T) (store.TokenStore, func(time.Duration)) {
+				b, fastForward := newBackend(t)
+
+				return store.NewTokenStore(b, keys), fastForward
+			})
+		})
+	}
+}
+
+// savedBackend records what the token store saves in its backend.
+type savedBackend struct {
+	store.SessionBackend
+	keys []string
+	data []string
+}
+
+func (b *savedBackend) SaveSession(ctx context.Context, key string, s store.Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	b.keys = append(b.keys, key)
+	b.data = append(b.data, string(data))
+
+	return b.SessionBackend.SaveSession(ctx, key, s)
+}
+
+// TestTokenStoreLayer runs the suite, but for the checks of the backend,
+// against the stores returned by newStore.
+func TestTokenStoreLayer(t *testing.T, newStore StoreFactory) {
+	ctx := context.Background()
+
+	t.Run("save and get", func(t *testing.T) {
+		s, _ := newStore(t)
+		at, session := Session(t, "user@email.com", time.Now().Add(time.Hour))
+		session.RefreshTokenHash = store.SessionID("refresh-token")
+
+		require.NoError(t, s.SaveAccessToken(ctx, at, session))
+
+		got, err := s.GetAccessToken(ctx, at)
+		require.NoError(t, err)
+		assertSession(t, session, got)
+	})
+
+	t.Run("save twice", func(t *testing.T) {
+		s, _ := newStore(t)
+		at, session := Session(t, "user@email.com", time.Now().Add(time.Hour))
+
+		require.NoError(t, s.SaveAccessToken(ctx, at, session))
+		session.Client.IP = "198.51.100.1"
+		require.NoError(t, s.SaveAccessToken(ctx, at, session))
+
+		got, err := s.GetAccessToken(ctx, at)
+		require.NoError(t, err)
+		assertSession(t, session, got)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		s, _ := newStore(t)
+
+		_, err := s.GetAccessToken(ctx, Token("user@email.com", time.Now().Add(time.Hour)))
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		s, _ := newStore(t)
+		at, session := Session(t, "user@email.com", time.Now().Add(time.Hour))
+		other, otherSession := Session(t, "other@email.com", time.Now().Add(time.Hour))
+
+		require.NoError(t, s.SaveAccessToken(ctx, at, session))
+		require.NoError(t, s.SaveAccessToken(ctx, other, otherSession))
+		require.NoError(t, s.DeleteAccessToken(ctx, at))
+
+		_, err := s.GetAccessToken(ctx, at)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		_, err = s.GetAccessToken(ctx, other)
+		assert.NoError(t, err)
+	})
+
+	t.Run("delete unknown token", func(t *testing.T) {
+		s, _ := newStore(t)
+
+		assert.NoError(t, s.DeleteAccessToken(ctx, Token("user@email.com", time.Now().Add(time.Hour))))
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		s, _ := newStore(t)
+		sessions := make(map[string]store.Session)
+		var tokens []string
+		for i := 0; i < 3; i++ {
+			at, session := Session(t, fmt.Sprintf("user%d@email.com", i), time.Now().Add(time.Hour))
+			require.NoError(t, s.SaveAccessToken(ctx, at, session))
+			sessions[at] = session
+			tokens = append(tokens, at)
+		}
+		unknown := Token("unknown@email.com", time.Now().Add(time.Hour))
+
+		got, err := s.GetAccessTokens(ctx, []string{tokens[0], tokens[1], tokens[2], unknown})
+		require.NoError(t, err)
+		require.Len(t, got, len(tokens))
+		for at, session := range sessions {
+			assertSession(t, session, got[at])
+		}
+
+		require.NoError(t, s.DeleteAccessTokens(ctx, []string{tokens[0], tokens[1], unknown}))
+		got, err = s.GetAccessTokens(ctx, tokens)
+		require.NoError(t, err)
+		assert.Len(t, got, 1)
+
+		got, err = s.GetAccessTokens(ctx, nil)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+		assert.NoError(t, s.DeleteAccessTokens(ctx, nil))
+	})
+
+	t.Run("expires with the token", func(t *testing.T) {
+		s, fastForward := newStore(t)
+		if fastForward == nil {
+			t.Skip("the store's clock cannot be moved")
+		}
+		at, session := Session(t, "user@email.com", time.Now().Add(time.Minute))
+
+		require.NoError(t, s.SaveAccessToken(ctx, at, session))
+		fastForward(30 * time.Second)
+		_, err := s.GetAccessToken(ctx, at)
+		assert.NoError(t, err)
+
+		fastForward(time.Minute)
+		_, err = s.GetAccessToken(ctx, at)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		got, err := s.GetAccessTokens(ctx, []string{at})
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+
+	t.Run("expired token is not stored", func(t *testing.T) {
+		s, _ := newStore(t)
+		at, session := Session(t, "user@email.com", time.Now().Add(-time.Minute))
+
+		require.NoError(t, s.SaveAccessToken(ctx, at, session))
+		_, err := s.GetAccessToken(ctx, at)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		s, _ := newStore(t)
+		at, session := Session(t, "user@email.com", time.Now().Add(time.Hour))
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		assert.ErrorIs(t, s.SaveAccessToken(cancelled, at, session), context.Canceled)
+		_, err := s.GetAccessToken(cancelled, at)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, s.DeleteAccessToken(cancelled, at), context.Canceled)
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		s, _ := newStore(t)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				at, session := Session(t, fmt.Sprintf("user%d@email.com", i), time.Now().Add(time.Hour))
+
+				assert.NoError(t, s.SaveAccessToken(ctx, at, session))
+				_, err := s.GetAccessToken(ctx, at)
+				assert.NoError(t, err)
+				assert.NoError(t, s.DeleteAccessToken(ctx, at))
+				_, err = s.GetAccessToken(ctx, at)
+				assert.ErrorIs(t, err, store.ErrNotFound)
+			}(i)
+		}
+		wg.Wait()
+	})
+}
+
+// assertSession compares times with Equal, as stores may drop the location
+// and the monotonic clock reading.
+func assertSession(t *testing.T, expected, actual store.Session) {
+	t.Helper()
+
+	assert.True(t, expected.IssuedAt.Equal(actual.IssuedAt), "issued at %v, got %v", expected.IssuedAt, actual.IssuedAt)
+	assert.True(t, expected.ExpiresAt.Equal(actual.ExpiresAt), "expires at %v, got %v", expected.ExpiresAt, actual.ExpiresAt)
+
+	expected.IssuedAt, actual.IssuedAt = time.Time{}, time.Time{}
+	expected.ExpiresAt, actual.ExpiresAt = time.Time{}, time.Time{}
+	assert.Equal(t, expected, actual)
+}
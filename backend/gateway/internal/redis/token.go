@@ -1,11 +1,14 @@
 package store
 
 import (
-	"errors"
+	"context"
+	"encoding/json"
 	"github.com/go-redis/redis"
-	"workmap/gateway/internal/pkg/token"
+	"time"
 )
 
+// TokenStore keeps the sessions of the access tokens. The backends are
+// SessionBackends, made TokenStores by NewTokenStore.
 type TokenStore interface {
 	TokenGetter
 	TokenSetter
@@ -13,55 +16,113 @@ type TokenStore interface {
 }
 
 type TokenGetter interface {
-	GetAccessToken(accessToken string) error
+	// GetAccessToken returns ErrNotFound unless accessToken is live.
+	GetAccessToken(ctx context.Context, accessToken string) (Session, error)
+	// GetAccessTokens returns the sessions of the live tokens, leaving the
+	// others out of the map.
+	GetAccessTokens(ctx context.Context, accessTokens []string) (map[string]Session, error)
 }
 
 type TokenSetter interface {
-	SaveAccessToken(accessToken string) error
+	// SaveAccessToken keeps s until s.ExpiresAt. An expired session is not saved.
+	SaveAccessToken(ctx context.Context, accessToken string, s Session) error
 }
 
 type TokenDeleter interface {
-	DeleteAccessToken(accessToken string) error
+	DeleteAccessToken(ctx context.Context, accessToken string) error
+	DeleteAccessTokens(ctx context.Context, accessTokens []string) error
 }
 
-func (r *RedisStore) GetAccessToken(accessToken string) error {
-	res := r.client.Get("access_token:" + accessToken)
-	if res.Err() != nil {
-		if res.Err() == redis.Nil {
-			return errors.New("unauthorized")
-		}
+// sessionKey returns the Redis key of the session at key in the tenant of
+// ctx. A key that is a raw token, see TokenKeyConfig.Migrate, gives the key
+// the gateway saved tokens at before deriving them.
+func (r *RedisStore) sessionKey(ctx context.Context, key string) string {
+	return r.namespace(ctx) + "access_token:" + key
+}
+
+// GetSessions pipelines one GET per key rather than an MGET, which a cluster
+// rejects for keys in different slots.
+func (r *RedisStore) GetSessions(ctx context.Context, keys []string) (map[string]Session, error) {
+	sessions := make(map[string]Session)
+	if len(keys) == 0 {
+		return sessions, nil
+	}
+
+	cmds := make([]*redis.StringCmd, len(keys))
+	err := r.do(ctx, func() error {
+		_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.Get(r.sessionKey(ctx, key))
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
 
-		return res.Err()
+	for i, cmd := range cmds {
+		if cmd.Err() != nil {
+			continue
+		}
+		sessions[keys[i]] = decodeSession(cmd.Val())
 	}
 
-	return nil
+	return sessions, nil
 }
 
-func (r *RedisStore) SaveAccessToken(accessToken string) error {
-	extractor := token.AccessTokenExtractor{}
-	ttl, err := extractor.ExtractTTL(accessToken)
-	if err != nil {
-		return err
+func (r *RedisStore) SaveSession(ctx context.Context, key string, s Session) error {
+	ttl := time.Until(s.ExpiresAt)
+	if ttl <= 0 {
+		// An expired token would otherwise be stored without expiration.
+		return nil
 	}
 
-	email, err := extractor.ExtractEmail(accessToken)
+	data, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
 
-	res := r.client.Set("access_token:"+accessToken, email, ttl)
-	if res.Err() != nil {
-		return res.Err()
+	key = r.sessionKey(ctx, key)
+	return r.do(ctx, func() error {
+		_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set(key, data, ttl)
+			if s.ID != "" {
+				// Access tokens share one lifetime, so the latest expires last.
+				index := r.sessionIndex(ctx, s.Email)
+				pipe.HSet(index, s.ID, key)
+				pipe.Expire(index, ttl)
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+func (r *RedisStore) DeleteSessions(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
 	}
 
-	return nil
+	return r.do(ctx, func() error {
+		_, err := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Del(r.sessionKey(ctx, key))
+			}
+			return nil
+		})
+		return err
+	})
 }
 
-func (r *RedisStore) DeleteAccessToken(accessToken string) error {
-	res := r.client.Del("access_token:" + accessToken)
-	if res.Err() != nil {
-		return res.Err()
+// decodeSession also reads the values written before sessions were
+// recorded, which hold only the email.
+func decodeSession(val string) Session {
+	var s Session
+	if err := json.Unmarshal([]byte(val), &s); err != nil {
+		return Session{Email: val}
 	}
 
-	return nil
+	return s
 }
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"workmap/gateway/internal/pkg/token"
+)
+
+const (
+	TokenKeyHMAC = "hmac"
+	TokenKeyJTI  = "jti"
+)
+
+// TokenKeyConfig derives the keys the token stores save the sessions of
+// the access tokens at, so that reading a store is not enough to replay them.
+type TokenKeyConfig struct {
+	// Mode is one of the TokenKey constants, hmac if empty. The jti mode
+	// requires the Auth service to issue tokens with a jti claim. As the
+	// claim is read without checking the token's signature, a session saved
+	// at a jti key also holds the token's hash, see savedFor.
+	Mode string
+	// Pepper is the HMAC key, the same on every gateway.
+	Pepper []byte
+	// Migrate also reads and deletes the sessions saved at the raw token,
+	// before the keys were derived. It can be turned off once every such
+	// token has expired.
+	Migrate bool
+}
+
+// keys returns the keys accessToken may be stored at, the derived key first.
+// A token without a jti claim has no derived key in jti mode.
+func (c *TokenKeyConfig) keys(accessToken string) []string {
+	var keys []string
+	if key, err := c.key(accessToken); err == nil {
+		keys = append(keys, key)
+	}
+	if c.Migrate {
+		keys = append(keys, accessToken)
+	}
+
+	return keys
+}
+
+// key derives the key accessToken is saved at.
+func (c *TokenKeyConfig) key(accessToken string) (string, error) {
+	if c.Mode == TokenKeyJTI {
+		e := &token.AccessTokenExtractor{}
+		claims, err := e.ExtractClaims(accessToken)
+		if err != nil {
+			return "", err
+		}
+		if claims.ID == "" {
+			return "", errors.New("jti not found in the token")
+		}
+
+		return "jti:" + claims.ID, nil
+	}
+
+	mac := hmac.New(sha256.New, c.Pepper)
+	mac.Write([]byte(accessToken))
+
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// savedFor reports whether s, found at key, was saved for accessToken. Keys
+// derived from the whole token cannot be reached by another token, but any
+// token can claim a jti, so the session ID, the token's hash, must match too.
+func (c *TokenKeyConfig) savedFor(key, accessToken string, s Session) bool {
+	if !isJTIKey(key) {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(s.ID), []byte(SessionID(accessToken))) == 1
+}
+
+func isJTIKey(key string) bool {
+	return strings.HasPrefix(key, "jti:")
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"io"
+)
+
+// SessionBackend keeps the sessions of a token store at the keys
+// TokenKeyConfig derives from the access tokens, so that no backend is given
+// a token it could store. See NewTokenStore.
+type SessionBackend interface {
+	// GetSessions returns the live sessions saved at keys, leaving the
+	// others out of the map.
+	GetSessions(ctx context.Context, keys []string) (map[string]Session, error)
+	// SaveSession keeps s at key until s.ExpiresAt. An expired session is not saved.
+	SaveSession(ctx context.Context, key string, s Session) error
+	DeleteSessions(ctx context.Context, keys []string) error
+}
+
+// NewTokenStore returns the TokenStore of the sessions in b. It is also a
+// SessionStore if b is one, and an io.Closer closing b if b is one.
+func NewTokenStore(b SessionBackend, keys TokenKeyConfig) TokenStore {
+	s := &tokenStore{backend: b, keys: keys}
+	if ss, ok := b.(SessionStore); ok {
+		return &sessionTokenStore{tokenStore: s, SessionStore: ss}
+	}
+
+	return s
+}
+
+type tokenStore struct {
+	backend SessionBackend
+	keys    TokenKeyConfig
+}
+
+type sessionTokenStore struct {
+	*tokenStore
+	SessionStore
+}
+
+func (s *tokenStore) GetAccessToken(ctx context.Context, accessToken string) (Session, error) {
+	sessions, err := s.GetAccessTokens(ctx, []string{accessToken})
+	if err != nil {
+		return Session{}, err
+	}
+
+	session, ok := sessions[accessToken]
+	if !ok {
+		return Session{}, ErrNotFound
+	}
+
+	return session, nil
+}
+
+// GetAccessTokens looks every key a token may be stored at up in one call,
+// and takes the first session saved for the token.
+func (s *tokenStore) GetAccessTokens(ctx context.Context, accessTokens []string) (map[string]Session, error) {
+	sessions := make(map[string]Session)
+
+	tokenKeys := make([][]string, len(accessTokens))
+	var keys []string
+	for i, at := range accessTokens {
+		tokenKeys[i] = s.keys.keys(at)
+		keys = append(keys, tokenKeys[i]...)
+	}
+	if len(keys) == 0 {
+		return sessions, nil
+	}
+
+	saved, err := s.backend.GetSessions(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, at := range accessTokens {
+		for _, key := range tokenKeys[i] {
+			session, ok := saved[key]
+			if !ok || !s.keys.savedFor(key, at, session) {
+				continue
+			}
+
+			sessions[at] = session
+			break
+		}
+	}
+
+	return sessions, nil
+}
+
+func (s *tokenStore) SaveAccessToken(ctx context.Context, accessToken string, session Session) error {
+	key, err := s.keys.key(accessToken)
+	if err != nil {
+		return err
+	}
+	if s.keys.Mode == TokenKeyJTI {
+		session.ID = SessionID(accessToken)
+	}
+
+	return s.backend.SaveSession(ctx, key, session)
+}
+
+func (s *tokenStore) DeleteAccessToken(ctx context.Context, accessToken string) error {
+	return s.DeleteAccessTokens(ctx, []string{accessToken})
+}
+
+func (s *tokenStore) DeleteAccessTokens(ctx context.Context, accessTokens []string) error {
+	// A jti key is only deleted for the token it was saved for, or a forged
+	// token carrying a live jti would log its owner out.
+	var saved map[string]Session
+	if s.keys.Mode == TokenKeyJTI {
+		var err error
+		if saved, err = s.GetAccessTokens(ctx, accessTokens); err != nil {
+			return err
+		}
+	}
+
+	var keys []string
+	for _, at := range accessTokens {
+		for _, key := range s.keys.keys(at) {
+			if _, ok := saved[at]; saved != nil && !ok && isJTIKey(key) {
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return s.backend.DeleteSessions(ctx, keys)
+}
+
+func (s *tokenStore) Close() error {
+	if c, ok := s.backend.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
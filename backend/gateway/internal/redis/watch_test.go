@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatch_WithoutSharedState(t *testing.T) {
+	s := miniredis.RunT(t)
+	store := &RedisStore{client: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var downs atomic.Int32
+	store.Watch(ctx, 10*time.Millisecond, func(up bool, err error) {
+		if !up {
+			downs.Add(1)
+		}
+	})
+
+	s.Close()
+	assert.Eventually(t, func() bool { return downs.Load() == 1 }, time.Second, 10*time.Millisecond)
+	assert.True(t, store.Up(), "the store has no state to mark down")
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"time"
+)
+
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+// WebAuthnSession is a passkey ceremony waiting for the authenticator's response.
+type WebAuthnSession struct {
+	Ceremony string               `json:"ceremony"`
+	Email    string               `json:"email"`
+	Data     webauthn.SessionData `json:"data"`
+}
+
+type WebAuthnSessionStore interface {
+	SaveWebAuthnSession(id string, s WebAuthnSession, ttl time.Duration) error
+	TakeWebAuthnSession(id string) (WebAuthnSession, error)
+}
+
+func (r *RedisStore) SaveWebAuthnSession(id string, s WebAuthnSession, ttl time.Duration) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(r.prefix+"webauthn_session:"+id, data, ttl).Err()
+}
+
+// TakeWebAuthnSession returns the session saved by SaveWebAuthnSession and
+// deletes it, so every challenge can be answered only once.
+func (r *RedisStore) TakeWebAuthnSession(id string) (WebAuthnSession, error) {
+	var get *redis.StringCmd
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		get = pipe.Get(r.prefix + "webauthn_session:" + id)
+		pipe.Del(r.prefix + "webauthn_session:" + id)
+		return nil
+	})
+	if err != nil {
+		if err == redis.Nil {
+			return WebAuthnSession{}, errors.New("webauthn session not found")
+		}
+
+		return WebAuthnSession{}, err
+	}
+
+	var s WebAuthnSession
+	if err = json.Unmarshal([]byte(get.Val()), &s); err != nil {
+		return WebAuthnSession{}, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWebAuthnSession(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+	defer s.Close()
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	store := &RedisStore{client: rdb}
+
+	saved := WebAuthnSession{
+		Ceremony: CeremonyLogin,
+		Email:    "user@email.com",
+		Data: webauthn.SessionData{
+			Challenge:            "challenge",
+			RelyingPartyID:       "localhost",
+			UserID:               []byte("user-handle"),
+			AllowedCredentialIDs: [][]byte{[]byte("credential")},
+			Expires:              time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	err = store.SaveWebAuthnSession("session", saved, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, s.TTL("webauthn_session:session"))
+
+	got, err := store.TakeWebAuthnSession("session")
+	assert.NoError(t, err)
+	assert.Equal(t, saved, got)
+
+	_, err = store.TakeWebAuthnSession("session")
+	assert.Equal(t, errors.New("webauthn session not found"), err)
+}
@@ -0,0 +1,43 @@
+// Package requestid carries the ID of a request, which ties its logs, its
+// responses and its crash reports together.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// Header carries the ID of a request, from the client or a proxy, and of
+// its response.
+const Header = "X-Request-ID"
+
+// validID keeps the IDs sent by clients safe to log and echo.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Valid reports whether id can be used as a request ID.
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// New returns a random ID.
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the ID of the request of ctx, "" if it has none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}
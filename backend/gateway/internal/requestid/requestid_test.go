@@ -0,0 +1,35 @@
+package requestid
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected bool
+	}{
+		{name: "generated", id: New(), expected: true},
+		{name: "uuid", id: "0b7c4d1e-6f0a-4f55-9d4a-2f6f4a1c9e2b", expected: true},
+		{name: "empty", id: "", expected: false},
+		{name: "too long", id: strings.Repeat("a", 129), expected: false},
+		{name: "header injection", id: "abc\r\nSet-Cookie: x", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Valid(tt.id))
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+
+	id := New()
+	assert.Equal(t, id, FromContext(NewContext(context.Background(), id)))
+	assert.NotEqual(t, id, New())
+}
@@ -0,0 +1,62 @@
+// Package route describes the route a request matched to the middlewares and
+// handlers behind the router.
+package route
+
+import (
+	"context"
+)
+
+// Auth policies of a route, applied by the router.
+const (
+	// AuthNone lets anyone call the route.
+	AuthNone = ""
+	// AuthToken requires an access token or an API key with the scope of the
+	// route, see middlewares.CheckAuth.
+	AuthToken = "token"
+	// AuthCookie requires a CSRF token along the refresh token cookie, see
+	// middlewares.CheckCSRF.
+	AuthCookie = "cookie"
+	// AuthClient requires the credentials of an OAuth client, see
+	// middlewares.CheckClient.
+	AuthClient = "client"
+	// AuthOptional authenticates the callers sending an access token or an
+	// API key like AuthToken, and lets the others through anonymously, see
+	// middlewares.CheckAuthOptional.
+	AuthOptional = "optional"
+)
+
+type Route struct {
+	// Name identifies the route in logs and metrics, like "user.login".
+	Name string
+	// Pattern is without the version prefix, like "POST /user/login".
+	Pattern string
+	// Version is the version serving the route, like "v1", empty for the
+	// unversioned routes.
+	Version string
+	// Served is the pattern the route is registered at, like
+	// "POST /v1/user/login", or Pattern for the first version's aliases.
+	Served string
+	Auth   string
+	// Scope is required by AuthToken routes, and by AuthClient routes if set.
+	Scope string
+	// RateLimit is the class of limit the route shares, like "auth", empty
+	// for none. See routes.Limits.
+	RateLimit string
+	// Handler and Middlewares name the functions serving the route, like
+	// "handlers.(*Handler).UserLogin". Middlewares are those of the router
+	// and the groups, outermost first.
+	Handler     string
+	Middlewares []string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, r *Route) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+func FromContext(ctx context.Context) (*Route, bool) {
+	r, ok := ctx.Value(contextKey{}).(*Route)
+
+	return r, ok
+}
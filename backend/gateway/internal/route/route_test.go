@@ -0,0 +1,17 @@
+package route
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	r := &Route{Name: "user.login", Pattern: "POST /user/login"}
+	got, ok := FromContext(NewContext(context.Background(), r))
+	assert.True(t, ok)
+	assert.Equal(t, r, got)
+}
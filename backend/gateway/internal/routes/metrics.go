@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var deprecatedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_deprecated_requests_total",
+	Help: "Requests to deprecated routes, by route as served.",
+}, []string{"route"})
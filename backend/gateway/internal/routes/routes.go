@@ -1,41 +1,440 @@
 package routes
 
 import (
+	"fmt"
 	"go.uber.org/zap"
 	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 	"workmap/gateway/internal/handlers"
 	"workmap/gateway/internal/middlewares"
+	"workmap/gateway/internal/principal"
+	"workmap/gateway/internal/route"
+	"workmap/gateway/internal/rpcproxy"
 )
 
 type Config struct {
 	Logger     *zap.Logger
 	Handler    *handlers.Handler
 	Middleware *middlewares.Middleware
+	// RPC serves procedures of the upstream gRPC services, none if nil.
+	RPC    *rpcproxy.Proxy
+	Limits Limits
+	// Deprecations are keyed by a version like "v1", Unversioned, or a route
+	// as served like "GET /v1/user/profile" or "GET /user/profile". A route
+	// is deprecated by its own key first, its version's otherwise.
+	Deprecations map[string]Deprecation
 }
 
+// Limits bound the request body and the handling time of every route, and
+// the requests of each client to the routes of a rate limit class.
+type Limits struct {
+	MaxBodyBytes int64
+	Timeout      time.Duration
+	// RouteMaxBodyBytes and RouteTimeouts override the limits of the routes
+	// by pattern, like "POST /user/login", whatever the version. Zero
+	// removes the limit.
+	RouteMaxBodyBytes map[string]int64
+	RouteTimeouts     map[string]time.Duration
+	// RateLimits are keyed by the route.Route RateLimit class, like "auth".
+	// The routes of a class without one are not limited.
+	RateLimits map[string]RateLimit
+}
+
+// RateLimit allows a client Requests per window of length Per.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Unversioned is the key of the routes of the first version served without
+// their version prefix in Config.Deprecations.
+const Unversioned = "unversioned"
+
+// Deprecation is announced on the responses of a deprecated route with the
+// Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers.
+type Deprecation struct {
+	// Date is when the route was deprecated.
+	Date time.Time
+	// Sunset is when it is expected to stop working, zero if not planned.
+	Sunset time.Time
+	// Link is a page about the deprecation, like a migration guide.
+	Link string
+}
+
+// Middleware wraps the handler of a route, like middlewares.EnableCORS.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
 type Router struct {
-	handler    *handlers.Handler
-	middleware *middlewares.Middleware
+	logger       *zap.Logger
+	handler      *handlers.Handler
+	middleware   *middlewares.Middleware
+	rpc          *rpcproxy.Proxy
+	limits       Limits
+	deprecations map[string]Deprecation
+	// rateLimiters count the requests to the routes of each class, across
+	// the versions.
+	rateLimiters map[string]*middlewares.RateLimiter
+	// middlewares wrap every route, root holds the unversioned routes and
+	// versions the others, the first one also served without its prefix.
+	middlewares []Middleware
+	root        *Version
+	versions    []*Version
+	// routes are those served, by served pattern.
+	routes map[string]*route.Route
 }
 
 func New(cfg *Config) *Router {
+	rateLimiters := make(map[string]*middlewares.RateLimiter)
+	for class, l := range cfg.Limits.RateLimits {
+		rateLimiters[class] = middlewares.NewRateLimiter(l.Requests, l.Per)
+	}
+
 	return &Router{
-		handler:    cfg.Handler,
-		middleware: cfg.Middleware,
+		logger:       cfg.Logger,
+		handler:      cfg.Handler,
+		middleware:   cfg.Middleware,
+		rpc:          cfg.RPC,
+		limits:       cfg.Limits,
+		deprecations: cfg.Deprecations,
+		rateLimiters: rateLimiters,
+		root:         &Version{},
+		routes:       make(map[string]*route.Route),
 	}
 }
 
+// Handler returns the routes behind the middlewares every request goes
+// through.
+func (r *Router) Handler() http.Handler {
+	mux := http.NewServeMux()
+	r.RegisterRoutes(mux)
+
+	patterns := make(map[string]bool)
+	served := make(map[string]bool)
+	classes := make(map[string]bool)
+	for _, rt := range r.routes {
+		patterns[rt.Pattern] = true
+		classes[rt.RateLimit] = true
+		served[rt.Served] = true
+		if rt.Version != "" {
+			served[rt.Version] = true
+		}
+	}
+	if len(r.versions) > 0 {
+		served[Unversioned] = true
+	}
+
+	for pattern := range r.limits.RouteMaxBodyBytes {
+		r.warnUnknown("route limit set for an unknown route", pattern, patterns)
+	}
+	for pattern := range r.limits.RouteTimeouts {
+		r.warnUnknown("route limit set for an unknown route", pattern, patterns)
+	}
+	for class := range r.limits.RateLimits {
+		r.warnUnknown("rate limit set for an unknown class", class, classes)
+	}
+	for key := range r.deprecations {
+		r.warnUnknown("deprecation set for an unknown route or version", key, served)
+	}
+
+	m := r.middleware
+
+	return m.RequestID(m.ResolveTenant(m.Compress(m.NegotiateFormat(mux.ServeHTTP))))
+}
+
 func (r *Router) RegisterRoutes(mux *http.ServeMux) {
 	h, m := r.handler, r.middleware
 
-	mux.HandleFunc("OPTIONS /", m.EnableCORS(preflight))
+	r.Use(m.Instrument, m.Recover)
+
+	ops := r.Group("")
+	cors := ops.With(m.EnableCORS)
+	cors.Handle(route.Route{Name: "preflight", Pattern: "OPTIONS /"}, preflight)
+	ops.Handle(route.Route{Name: "readyz", Pattern: "GET /readyz"}, h.Readyz)
+	cors.Handle(route.Route{Name: "openapi", Pattern: "GET /openapi.json"}, h.OpenAPI)
+	ops.Handle(route.Route{Name: "docs", Pattern: "GET /docs"}, h.APIDocs)
+
+	// The procedures are served at their gRPC path, like
+	// "/auth.AuthService/Login", over Connect, gRPC-Web and gRPC. Callers
+	// with credentials are authenticated, for the identity tokens of the
+	// calls, and anonymous ones let through, as for Login.
+	if r.rpc != nil {
+		rpc := r.Group("", m.EnableCORS)
+		for _, p := range r.rpc.Procedures() {
+			name := "rpc" + strings.ReplaceAll(p.Path, "/", ".")
+			rpc.Handle(route.Route{Name: name, Pattern: "POST " + p.Path, Auth: route.AuthOptional}, p.Handler.ServeHTTP)
+		}
+	}
+
+	v1 := r.Version("v1")
+	api := v1.Group("", m.EnableCORS)
+
+	user := api.Group("/user")
+	user.Handle(route.Route{Name: "user.register", Pattern: "POST /register", RateLimit: "auth"}, h.UserRegister)
+	user.Handle(route.Route{Name: "user.login", Pattern: "POST /login", RateLimit: "auth"}, h.UserLogin) // TODO add CheckAuth middleware and (?)redirect or delegate to FrontEnd
+	user.Handle(route.Route{Name: "user.login.mfa", Pattern: "POST /login/2fa", RateLimit: "auth"}, h.UserLoginMFA)
+	user.Handle(route.Route{Name: "user.login.passkey.begin", Pattern: "POST /login/passkey/begin", RateLimit: "auth"}, h.PasskeyLoginBegin)
+	user.Handle(route.Route{Name: "user.login.passkey.finish", Pattern: "POST /login/passkey/finish", RateLimit: "auth"}, h.PasskeyLoginFinish)
+	user.Handle(route.Route{Name: "user.refreshtoken", Pattern: "POST /refreshtoken", Auth: route.AuthCookie, RateLimit: "auth"}, h.UserRefreshToken)
+	user.Handle(route.Route{Name: "user.logout", Pattern: "POST /logout", Auth: route.AuthCookie}, h.UserLogout)
+	api.Handle(route.Route{Name: "csrf", Pattern: "GET /csrf"}, h.CSRFToken)
+
+	user.Handle(route.Route{Name: "user.profile", Pattern: "GET /profile", Auth: route.AuthToken, Scope: principal.ScopeProfileRead}, h.UserProfile)
+	user.Handle(route.Route{Name: "user.mfa.setup", Pattern: "POST /2fa/setup", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.MFASetup)
+	user.Handle(route.Route{Name: "user.mfa.confirm", Pattern: "POST /2fa/confirm", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.MFAConfirm)
+	user.Handle(route.Route{Name: "user.passkey.register.begin", Pattern: "POST /passkey/register/begin", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.PasskeyRegisterBegin)
+	user.Handle(route.Route{Name: "user.passkey.register.finish", Pattern: "POST /passkey/register/finish", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.PasskeyRegisterFinish)
+
+	user.Handle(route.Route{Name: "user.apikeys.create", Pattern: "POST /api-keys", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.CreateAPIKey)
+	user.Handle(route.Route{Name: "user.apikeys.list", Pattern: "GET /api-keys", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.ListAPIKeys)
+	user.Handle(route.Route{Name: "user.apikeys.revoke", Pattern: "DELETE /api-keys/{id}", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.RevokeAPIKey)
+
+	user.Handle(route.Route{Name: "user.sessions.list", Pattern: "GET /sessions", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.ListSessions)
+	user.Handle(route.Route{Name: "user.sessions.revoke", Pattern: "DELETE /sessions/{id}", Auth: route.AuthToken, Scope: principal.ScopeAccount}, h.RevokeSession)
+
+	oauth := api.Group("/auth")
+	oauth.Handle(route.Route{Name: "oauth.start", Pattern: "GET /{provider}/start"}, h.OAuthStart)
+	oauth.Handle(route.Route{Name: "oauth.callback", Pattern: "GET /{provider}/callback"}, h.OAuthCallback)
+
+	// The resource servers call these server to server, without CORS.
+	clients := v1.Group("/oauth")
+	clients.Handle(route.Route{Name: "oauth.introspect", Pattern: "POST /introspect", Auth: route.AuthClient, RateLimit: "auth"}, h.OAuthIntrospect)
+	clients.Handle(route.Route{Name: "oauth.revoke", Pattern: "POST /revoke", Auth: route.AuthClient, Scope: middlewares.ClientScopeRevoke, RateLimit: "auth"}, h.OAuthRevoke)
+
+	// A new version starts from the routes of v1 and replaces some of them,
+	// the two being served side by side:
+	//
+	//	v2 := v1.Next("v2")
+	//	v2.Group("/user", m.EnableCORS).Handle(route.Route{Name: "user.profile", Pattern: "GET /profile", Auth: route.AuthToken, Scope: principal.ScopeProfileRead}, h.UserProfileV2)
+	r.mount(mux)
+}
+
+// Use adds middlewares wrapping every route, outside those of the groups.
+// They see the route of the request through route.FromContext.
+func (r *Router) Use(mws ...Middleware) {
+	r.middlewares = append(r.middlewares, mws...)
+}
+
+// Group starts a group of unversioned routes, see Version.Group.
+func (r *Router) Group(prefix string, mws ...Middleware) *Group {
+	return r.root.Group(prefix, mws...)
+}
+
+// Version starts a version of the API, served under /<name>.
+func (r *Router) Version(name string) *Version {
+	v := &Version{name: name, router: r}
+	r.versions = append(r.versions, v)
+
+	return v
+}
+
+// Routes returns the routes served, by served pattern.
+func (r *Router) Routes() []route.Route {
+	routes := make([]route.Route, 0, len(r.routes))
+	for _, rt := range r.routes {
+		routes = append(routes, *rt)
+	}
+	slices.SortFunc(routes, func(a, b route.Route) int {
+		return strings.Compare(a.Served, b.Served)
+	})
+
+	return routes
+}
+
+// mount registers the routes of each version under its prefix, like
+// "POST /v1/user/login", and those of the first one without it too.
+func (r *Router) mount(mux *http.ServeMux) {
+	for _, d := range r.root.routes {
+		r.serve(mux, d, "", d.route.Pattern)
+	}
+	for i, v := range r.versions {
+		for _, d := range v.routes {
+			method, path, _ := strings.Cut(d.route.Pattern, " ")
+			r.serve(mux, d, v.name, method+" /"+v.name+path)
+			if i == 0 {
+				r.serve(mux, d, Unversioned, d.route.Pattern)
+			}
+		}
+	}
+}
+
+// serve registers the declared route at served, the pattern with its version
+// prefix if any. The route is put in the request context, and versioned
+// routes have their prefix stripped, ahead of the middlewares of the router
+// and the groups, the limits of the route and its auth policy. The
+// deprecation of served, or else of version, is announced around them all.
+func (r *Router) serve(mux *http.ServeMux, d declared, version, served string) {
+	m := r.middleware
+
+	rt := d.route
+	rt.Version, rt.Served = version, served
+	if version == Unversioned {
+		rt.Version = r.versions[0].name
+	}
+	mws := slices.Concat(r.middlewares, d.middlewares)
+	rt.Handler = funcName(d.next)
+	rt.Middlewares = make([]string, len(mws))
+	for i, mw := range mws {
+		rt.Middlewares[i] = funcName(mw)
+	}
+	r.routes[served] = &rt
+
+	next := d.next
+	switch rt.Auth {
+	case route.AuthToken:
+		next = m.CheckAuth(m.RequireScope(rt.Scope, next))
+	case route.AuthCookie:
+		next = m.CheckCSRF(next)
+	case route.AuthClient:
+		next = m.CheckClient(rt.Scope, next)
+	case route.AuthOptional:
+		next = m.CheckAuthOptional(next)
+	}
+	next = r.limit(&rt, next)
+	for i := len(mws) - 1; i >= 0; i-- {
+		next = mws[i](next)
+	}
+	if version != "" && version != Unversioned {
+		next = http.StripPrefix("/"+version, next).ServeHTTP
+	}
+	next = withRoute(&rt, next)
+
+	dep, ok := r.deprecations[served]
+	if !ok && version != "" {
+		dep, ok = r.deprecations[version]
+	}
+	if ok {
+		next = deprecate(served, dep, next)
+	}
+
+	mux.HandleFunc(served, next)
+}
+
+// limit applies the limits of rt, the rate limit of its class first so that
+// the requests refused are not read nor authenticated.
+func (r *Router) limit(rt *route.Route, next http.HandlerFunc) http.HandlerFunc {
+	maxBodyBytes, ok := r.limits.RouteMaxBodyBytes[rt.Pattern]
+	if !ok {
+		maxBodyBytes = r.limits.MaxBodyBytes
+	}
+	timeout, ok := r.limits.RouteTimeouts[rt.Pattern]
+	if !ok {
+		timeout = r.limits.Timeout
+	}
+	m := r.middleware
+
+	return m.RateLimit(r.rateLimiters[rt.RateLimit], m.LimitBody(maxBodyBytes, m.Timeout(timeout, m.ValidateOpenAPI(next))))
+}
+
+func (r *Router) warnUnknown(msg, key string, known map[string]bool) {
+	if !known[key] {
+		r.logger.Warn(msg, zap.String("key", key))
+	}
+}
+
+// withRoute puts rt in the request context.
+func withRoute(rt *route.Route, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(route.NewContext(r.Context(), rt)))
+	}
+}
+
+// deprecate announces d on the responses of the route served and counts its
+// requests.
+func deprecate(served string, d Deprecation, next http.HandlerFunc) http.HandlerFunc {
+	requests := deprecatedRequests.WithLabelValues(served)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		requests.Inc()
+
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", d.Date.Unix()))
+		if !d.Sunset.IsZero() {
+			w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Link != "" {
+			w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", d.Link))
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// Version is a version of the API, served under /<name>.
+type Version struct {
+	name   string
+	router *Router
+	routes []declared
+}
+
+// declared is a route as declared in a group.
+type declared struct {
+	route       route.Route
+	next        http.HandlerFunc
+	middlewares []Middleware
+}
+
+// Next starts a version with the routes of v, which its groups may replace.
+func (v *Version) Next(name string) *Version {
+	next := v.router.Version(name)
+	next.routes = slices.Clone(v.routes)
+
+	return next
+}
+
+// Group starts a group of routes of v under prefix, like "/user", wrapped by
+// mws, outermost first.
+func (v *Version) Group(prefix string, mws ...Middleware) *Group {
+	return &Group{version: v, prefix: prefix, middlewares: mws}
+}
+
+// Group declares routes sharing a path prefix and a stack of middlewares.
+type Group struct {
+	version     *Version
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group starts a group within g, under its prefix and inside its middlewares.
+func (g *Group) Group(prefix string, mws ...Middleware) *Group {
+	return &Group{
+		version:     g.version,
+		prefix:      g.prefix + prefix,
+		middlewares: slices.Concat(g.middlewares, mws),
+	}
+}
+
+// With is g with more middlewares, for the routes needing them alone.
+func (g *Group) With(mws ...Middleware) *Group {
+	return g.Group("", mws...)
+}
+
+// Handle declares rt, its pattern relative to the group, like
+// "GET /profile", replacing the route of the same pattern in the version.
+// The router applies the auth policy and the limits of rt around next.
+func (g *Group) Handle(rt route.Route, next http.HandlerFunc) {
+	method, path, _ := strings.Cut(rt.Pattern, " ")
+	rt.Pattern = method + " " + g.prefix + path
+	d := declared{route: rt, next: next, middlewares: g.middlewares}
+
+	v := g.version
+	for i := range v.routes {
+		if v.routes[i].route.Pattern == rt.Pattern {
+			v.routes[i] = d
+			return
+		}
+	}
+	v.routes = append(v.routes, d)
+}
 
-	mux.HandleFunc("POST /user/register", m.EnableCORS(h.UserRegister))
-	mux.HandleFunc("POST /user/login", m.EnableCORS(h.UserLogin)) // TODO add CheckAuth middleware and (?)redirect or delegate to FrontEnd
-	mux.HandleFunc("POST /user/refreshtoken", m.EnableCORS(h.UserRefreshToken))
-	mux.HandleFunc("POST /user/logout", m.EnableCORS(h.UserLogout))
+// funcName names the function f, like "handlers.(*Handler).UserLogin".
+func funcName(f any) string {
+	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
 
-	mux.HandleFunc("GET /user/profile", m.EnableCORS(m.CheckAuth(h.UserProfile)))
+	return strings.TrimSuffix(name, "-fm")
 }
 
 func preflight(w http.ResponseWriter, r *http.Request) {}
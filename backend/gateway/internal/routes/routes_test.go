@@ -0,0 +1,615 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"workmap/gateway/docs"
+	"workmap/gateway/internal/gapi"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/memstore"
+	"workmap/gateway/internal/middlewares"
+	"workmap/gateway/internal/principal"
+	"workmap/gateway/internal/redis"
+	"workmap/gateway/internal/redis/storetest"
+	"workmap/gateway/internal/route"
+	"workmap/gateway/internal/rpcproxy"
+	"workmap/gateway/pkg/identity"
+)
+
+func newTestRouter(deprecations map[string]Deprecation) *Router {
+	return New(&Config{
+		Logger:       zap.NewNop(),
+		Middleware:   middlewares.New(&middlewares.Config{Logger: zap.NewNop()}),
+		Deprecations: deprecations,
+	})
+}
+
+func TestGroups(t *testing.T) {
+	tag := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Middleware", name)
+				next.ServeHTTP(w, r)
+			}
+		}
+	}
+	reply := func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := route.FromContext(r.Context())
+		require.True(t, ok)
+		w.Write([]byte(rt.Name + " " + rt.Pattern + " " + rt.Served + " " + rt.Version))
+	}
+
+	router := newTestRouter(nil)
+	router.Use(tag("global"))
+	ops := router.Group("", tag("ops"))
+	ops.Handle(route.Route{Name: "readyz", Pattern: "GET /readyz"}, reply)
+	api := router.Version("v1").Group("", tag("api"))
+	user := api.Group("/user", tag("user"))
+	user.Handle(route.Route{Name: "user.login", Pattern: "POST /login", RateLimit: "auth"}, reply)
+	user.With(tag("with")).Handle(route.Route{Name: "user.profile", Pattern: "GET /profile", Auth: route.AuthToken, Scope: principal.ScopeProfileRead}, reply)
+	mux := http.NewServeMux()
+	router.mount(mux)
+
+	tests := []struct {
+		name                string
+		method              string
+		path                string
+		expectedCode        int
+		expectedBody        string
+		expectedMiddlewares []string
+	}{
+		{
+			name:                "unversioned group",
+			method:              http.MethodGet,
+			path:                "/readyz",
+			expectedCode:        http.StatusOK,
+			expectedBody:        "readyz GET /readyz GET /readyz ",
+			expectedMiddlewares: []string{"global", "ops"},
+		},
+		{
+			name:                "nested group",
+			method:              http.MethodPost,
+			path:                "/v1/user/login",
+			expectedCode:        http.StatusOK,
+			expectedBody:        "user.login POST /user/login POST /v1/user/login v1",
+			expectedMiddlewares: []string{"global", "api", "user"},
+		},
+		{
+			name:                "alias",
+			method:              http.MethodPost,
+			path:                "/user/login",
+			expectedCode:        http.StatusOK,
+			expectedBody:        "user.login POST /user/login POST /user/login v1",
+			expectedMiddlewares: []string{"global", "api", "user"},
+		},
+		{
+			name:                "auth policy",
+			method:              http.MethodGet,
+			path:                "/v1/user/profile",
+			expectedCode:        http.StatusUnauthorized,
+			expectedMiddlewares: []string{"global", "api", "user", "with"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			assert.Equal(t, tt.expectedMiddlewares, w.Header().Values("X-Middleware"))
+		})
+	}
+
+	var served []string
+	for _, rt := range router.Routes() {
+		served = append(served, rt.Served)
+	}
+	assert.Equal(t, []string{"GET /readyz", "GET /user/profile", "GET /v1/user/profile", "POST /user/login", "POST /v1/user/login"}, served)
+	login := router.Routes()[3]
+	assert.Equal(t, "auth", login.RateLimit)
+	assert.Len(t, login.Middlewares, 3)
+}
+
+func TestRPC(t *testing.T) {
+	proxy, err := rpcproxy.New(&rpcproxy.Config{Procedures: []string{"/auth.AuthService/Login"}})
+	require.NoError(t, err)
+	router := New(&Config{Logger: zap.NewNop(), Middleware: middlewares.New(&middlewares.Config{Logger: zap.NewNop()}), RPC: proxy})
+	router.Handler()
+
+	var login *route.Route
+	for _, rt := range router.Routes() {
+		if rt.Served == "POST /auth.AuthService/Login" {
+			login = &rt
+		}
+	}
+	require.NotNil(t, login, "the procedure is not served")
+	assert.Equal(t, "rpc.auth.AuthService.Login", login.Name)
+	assert.Equal(t, route.AuthOptional, login.Auth)
+	assert.Contains(t, login.Middlewares, "middlewares.(*Middleware).EnableCORS")
+}
+
+type mockAuthServiceServer struct {
+	pb.UnimplementedAuthServiceServer
+	identities []string
+}
+
+func (s *mockAuthServiceServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.identities = md.Get(identity.MetadataKey)
+	// Big enough to be compressed.
+	return &pb.LoginReply{RefreshToken: strings.Repeat("r", 2048), AccessToken: "access"}, nil
+}
+
+// TestRPC_Handler calls a procedure through every middleware of the router,
+// with the limits, compression and OpenAPI validation on, and an identity
+// signer on the upstream connection.
+func TestRPC_Handler(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	mock := &mockAuthServiceServer{}
+	upstream := grpc.NewServer()
+	pb.RegisterAuthServiceServer(upstream, mock)
+	go upstream.Serve(lis)
+	t.Cleanup(upstream.Stop)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer := identity.NewSigner(key, time.Minute)
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+	auth, err := gapi.NewAuthService(&gapi.AuthConfig{Host: host, Port: port, Identity: signer})
+	require.NoError(t, err)
+	t.Cleanup(func() { auth.Close() })
+
+	tokens := store.NewTokenStore(memstore.New(&memstore.Config{}), store.TokenKeyConfig{})
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, tokens.SaveAccessToken(context.Background(), at, session))
+
+	proxy, err := rpcproxy.New(&rpcproxy.Config{Conn: auth.Conn(), Procedures: []string{"/auth.AuthService/Login"}})
+	require.NoError(t, err)
+	spec, err := middlewares.NewOpenAPIRouter(docs.Swagger)
+	require.NoError(t, err)
+	router := New(&Config{
+		Logger: zap.NewNop(),
+		Middleware: middlewares.New(&middlewares.Config{
+			Logger:                  zap.NewNop(),
+			Redis:                   tokens,
+			CompressionEncodings:    []string{middlewares.EncodingGzip},
+			CompressionContentTypes: []string{"application/"},
+			OpenAPI:                 spec,
+			OpenAPIRequests:         true,
+			OpenAPIResponses:        middlewares.ResponseValidationFail,
+		}),
+		RPC:    proxy,
+		Limits: Limits{MaxBodyBytes: 1 << 16, Timeout: 5 * time.Second},
+	})
+	srv := httptest.NewUnstartedServer(router.Handler())
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	msg, err := proto.Marshal(&pb.LoginRequest{Email: "test@example.com", Password: "password123"})
+	require.NoError(t, err)
+	post := func(t *testing.T, contentType string, body []byte, accessToken string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/auth.AuthService/Login", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		resp, err := srv.Client().Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+
+		return resp
+	}
+
+	t.Run("connect", func(t *testing.T) {
+		resp := post(t, "application/json", []byte(`{"email":"test@example.com","password":"password123"}`), "")
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var got struct{ AccessToken string }
+		require.NoError(t, json.Unmarshal(b, &got))
+		assert.Equal(t, "access", got.AccessToken)
+		assert.Empty(t, mock.identities, "anonymous call")
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		resp := post(t, "application/json", []byte(`{}`), at)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, mock.identities, 1)
+		id, err := identity.NewVerifier(signer.PublicKey()).Verify(mock.identities[0])
+		require.NoError(t, err)
+		assert.Equal(t, principal.KindUser, id.Kind)
+		assert.Equal(t, "id-user@email.com", id.UserID)
+		assert.Equal(t, "user@email.com", id.Email)
+
+		resp = post(t, "application/json", []byte(`{}`), "unknown")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("grpc-web", func(t *testing.T) {
+		body := append([]byte{0}, binary.BigEndian.AppendUint32(nil, uint32(len(msg)))...)
+		resp := post(t, "application/grpc-web+proto", append(body, msg...), "")
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var got pb.LoginReply
+		var trailer string
+		for len(b) >= 5 {
+			n := int(binary.BigEndian.Uint32(b[1:5]))
+			require.LessOrEqual(t, 5+n, len(b))
+			if b[0]&0x80 != 0 {
+				trailer = string(b[5 : 5+n])
+			} else {
+				require.NoError(t, proto.Unmarshal(b[5:5+n], &got))
+			}
+			b = b[5+n:]
+		}
+		assert.Equal(t, "access", got.AccessToken)
+		assert.Contains(t, strings.ToLower(trailer), "grpc-status: 0")
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		cc, err := grpc.Dial(strings.TrimPrefix(srv.URL, "https://"),
+			grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})))
+		require.NoError(t, err)
+		t.Cleanup(func() { cc.Close() })
+
+		got, err := pb.NewAuthServiceClient(cc).Login(context.Background(), &pb.LoginRequest{Email: "test@example.com", Password: "password123"})
+		require.NoError(t, err)
+		assert.Equal(t, "access", got.AccessToken)
+	})
+}
+
+func TestVersions(t *testing.T) {
+	reply := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body + " " + r.URL.Path + " " + r.PathValue("id")))
+		}
+	}
+
+	router := newTestRouter(nil)
+	v1 := router.Version("v1")
+	user := v1.Group("/user")
+	user.Handle(route.Route{Pattern: "GET /profile"}, reply("v1"))
+	user.Handle(route.Route{Pattern: "DELETE /sessions/{id}"}, reply("v1"))
+	v2 := v1.Next("v2")
+	v2.Group("/user").Handle(route.Route{Pattern: "GET /profile"}, reply("v2"))
+	mux := http.NewServeMux()
+	router.mount(mux)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "first version", method: http.MethodGet, path: "/v1/user/profile", expectedCode: http.StatusOK, expectedBody: "v1 /user/profile "},
+		{name: "alias of the first version", method: http.MethodGet, path: "/user/profile", expectedCode: http.StatusOK, expectedBody: "v1 /user/profile "},
+		{name: "handler replaced", method: http.MethodGet, path: "/v2/user/profile", expectedCode: http.StatusOK, expectedBody: "v2 /user/profile "},
+		{name: "handler kept", method: http.MethodDelete, path: "/v2/user/sessions/s1", expectedCode: http.StatusOK, expectedBody: "v1 /user/sessions/s1 s1"},
+		{name: "unknown version", method: http.MethodGet, path: "/v3/user/profile", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	reply := func(w http.ResponseWriter, r *http.Request) {}
+
+	router := New(&Config{
+		Logger:     zap.NewNop(),
+		Middleware: middlewares.New(&middlewares.Config{Logger: zap.NewNop()}),
+		Limits:     Limits{RateLimits: map[string]RateLimit{"auth": {Requests: 2, Per: time.Minute}}},
+	})
+	user := router.Version("v1").Group("/user")
+	user.Handle(route.Route{Pattern: "POST /login", RateLimit: "auth"}, reply)
+	user.Handle(route.Route{Pattern: "POST /register", RateLimit: "auth"}, reply)
+	user.Handle(route.Route{Pattern: "GET /profile"}, reply)
+	mux := http.NewServeMux()
+	router.mount(mux)
+
+	serve := func(method, path, remoteAddr string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	// The routes of a class share the limit, across the versions.
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/v1/user/login", "192.0.2.1:1234"))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/user/register", "192.0.2.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, "/user/login", "192.0.2.1:1234"))
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/user/login", "192.0.2.2:1234"), "other clients have their own count")
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/user/profile", "192.0.2.1:1234"), "routes without a class are not limited")
+	}
+}
+
+func TestDeprecations(t *testing.T) {
+	date := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	router := newTestRouter(map[string]Deprecation{
+		Unversioned:    {Date: date, Sunset: sunset, Link: "https://docs.example.com/v1"},
+		"GET /v1/csrf": {Date: date},
+	})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	api := router.Version("v1").Group("")
+	api.Handle(route.Route{Pattern: "GET /user/profile"}, ok)
+	api.Handle(route.Route{Pattern: "GET /csrf"}, ok)
+	mux := http.NewServeMux()
+	router.mount(mux)
+
+	tests := []struct {
+		path                string
+		expectedDeprecation string
+		expectedSunset      string
+		expectedLink        string
+	}{
+		{
+			path:                "/user/profile",
+			expectedDeprecation: "@1790812800",
+			expectedSunset:      "Thu, 01 Apr 2027 00:00:00 GMT",
+			expectedLink:        `<https://docs.example.com/v1>; rel="deprecation"`,
+		},
+		{
+			path: "/v1/user/profile",
+		},
+		{
+			path:                "/v1/csrf",
+			expectedDeprecation: "@1790812800",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			requests := deprecatedRequests.WithLabelValues("GET " + tt.path)
+			before := testutil.ToFloat64(requests)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.expectedDeprecation, w.Header().Get("Deprecation"))
+			assert.Equal(t, tt.expectedSunset, w.Header().Get("Sunset"))
+			assert.Equal(t, tt.expectedLink, w.Header().Get("Link"))
+
+			counted := 0.0
+			if tt.expectedDeprecation != "" {
+				counted = 1
+			}
+			assert.Equal(t, counted, testutil.ToFloat64(requests)-before)
+		})
+	}
+}
+
+// TestSpec fails when a registered route, or a status code its handler and
+// middlewares may answer, is missing from the OpenAPI description. The codes
+// are the http.Status constants the functions reachable from the route pass
+// to a call, like http.Error or writeJSON.
+func TestSpec(t *testing.T) {
+	router := newTestRouter(nil)
+	router.Handler()
+
+	handlers := statusCodes(t, "../handlers")
+	middleware := statusCodes(t, "../middlewares")
+	// The names of the functions, like "handlers.(*Handler).UserProfile",
+	// as in statusCodes, like "Handler.UserProfile".
+	method := regexp.MustCompile(`^\w+\.\(\*(\w+)\)\.(\w+)$`)
+	codesOf := func(name string) map[int]bool {
+		n := method.ReplaceAllString(name, "$1.$2")
+		if strings.HasPrefix(name, "handlers.") {
+			return handlers[n]
+		}
+		return middleware[n]
+	}
+
+	b, err := docs.OpenAPI()
+	require.NoError(t, err)
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(b, &spec))
+
+	seen := make(map[string]bool)
+	for _, rt := range router.Routes() {
+		method, path, _ := strings.Cut(rt.Pattern, " ")
+		if method == http.MethodOptions || seen[rt.Pattern] {
+			// CORS preflights are not operations.
+			continue
+		}
+		seen[rt.Pattern] = true
+
+		raw, ok := spec.Paths[path][strings.ToLower(method)]
+		if !assert.True(t, ok, "%s is missing from the spec", rt.Pattern) {
+			continue
+		}
+		var op struct {
+			Responses map[string]any `json:"responses"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &op))
+
+		// ValidateOpenAPI is left out: it only refuses what the spec itself
+		// rules out.
+		names := append([]string{rt.Handler, "middlewares.(*Middleware).LimitBody", "middlewares.(*Middleware).Timeout"}, rt.Middlewares...)
+		switch rt.Auth {
+		case route.AuthToken:
+			names = append(names, "middlewares.(*Middleware).CheckAuth", "middlewares.(*Middleware).RequireScope")
+		case route.AuthCookie:
+			names = append(names, "middlewares.(*Middleware).CheckCSRF")
+		case route.AuthClient:
+			names = append(names, "middlewares.(*Middleware).CheckClient")
+		case route.AuthOptional:
+			names = append(names, "middlewares.(*Middleware).CheckAuthOptional")
+		}
+		if rt.RateLimit != "" {
+			names = append(names, "middlewares.(*Middleware).RateLimit")
+		}
+		codes := map[int]bool{}
+		for _, name := range names {
+			merge(codes, codesOf(name))
+		}
+		for code := range codes {
+			_, ok := op.Responses[strconv.Itoa(code)]
+			assert.True(t, ok, "%s may answer %d, missing from the spec", rt.Pattern, code)
+		}
+	}
+}
+
+// statusCodes returns the status codes each function of the package in dir
+// may answer, directly or through the functions it calls. Methods are named
+// after their receiver type, like "Handler.UserProfile".
+func statusCodes(t *testing.T, dir string) map[string]map[int]bool {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	require.NoError(t, err)
+
+	constants := make(map[string]int)
+	for code := 100; code < 600; code++ {
+		if text := http.StatusText(code); text != "" {
+			constants["Status"+strings.Map(func(r rune) rune {
+				if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+					return r
+				}
+				return -1
+			}, text)] = code
+		}
+	}
+
+	direct := make(map[string]map[int]bool)
+	calls := make(map[string][]string)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		require.NoError(t, err)
+
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			name := fn.Name.Name
+			receiver, recv := "", ""
+			if fn.Recv != nil {
+				typ := fn.Recv.List[0].Type
+				if star, ok := typ.(*ast.StarExpr); ok {
+					typ = star.X
+				}
+				recv = typ.(*ast.Ident).Name
+				name = recv + "." + name
+				if len(fn.Recv.List[0].Names) > 0 {
+					receiver = fn.Recv.List[0].Names[0].Name
+				}
+			}
+
+			codes := make(map[int]bool)
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				switch fun := call.Fun.(type) {
+				case *ast.Ident:
+					calls[name] = append(calls[name], fun.Name)
+				case *ast.SelectorExpr:
+					if x, ok := fun.X.(*ast.Ident); ok && x.Name == receiver {
+						calls[name] = append(calls[name], recv+"."+fun.Sel.Name)
+					}
+				}
+				for _, arg := range call.Args {
+					sel, ok := arg.(*ast.SelectorExpr)
+					if !ok || !strings.HasPrefix(sel.Sel.Name, "Status") {
+						continue
+					}
+					if x, ok := sel.X.(*ast.Ident); !ok || x.Name != "http" {
+						continue
+					}
+					code, ok := constants[sel.Sel.Name]
+					require.True(t, ok, "unknown status constant http.%s", sel.Sel.Name)
+					codes[code] = true
+				}
+				return true
+			})
+			direct[name] = codes
+		}
+	}
+
+	all := make(map[string]map[int]bool)
+	var visit func(name string, seen map[string]bool) map[int]bool
+	visit = func(name string, seen map[string]bool) map[int]bool {
+		codes := make(map[int]bool)
+		if seen[name] {
+			return codes
+		}
+		seen[name] = true
+		merge(codes, direct[name])
+		for _, callee := range calls[name] {
+			merge(codes, visit(callee, seen))
+		}
+		return codes
+	}
+	for name := range direct {
+		all[name] = visit(name, map[string]bool{})
+	}
+
+	return all
+}
+
+func merge(dst, src map[int]bool) {
+	for code := range src {
+		dst[code] = true
+	}
+}
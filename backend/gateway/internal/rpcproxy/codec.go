@@ -0,0 +1,83 @@
+package rpcproxy
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"slices"
+)
+
+// frame is a message in the protobuf binary format.
+type frame struct {
+	b []byte
+}
+
+// binaryCodec passes the frames on as they are, for both Connect and gRPC.
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string {
+	return "proto"
+}
+
+func (binaryCodec) Marshal(v any) ([]byte, error) {
+	f, ok := v.(*frame)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+
+	return f.b, nil
+}
+
+func (binaryCodec) Unmarshal(data []byte, v any) error {
+	f, ok := v.(*frame)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+	// The caller may reuse data.
+	f.b = slices.Clone(data)
+
+	return nil
+}
+
+// jsonCodec converts the requests of a procedure from JSON and its
+// responses to JSON, with the descriptors of their messages.
+type jsonCodec struct {
+	in, out protoreflect.MessageDescriptor
+}
+
+func (c *jsonCodec) Name() string {
+	return "json"
+}
+
+func (c *jsonCodec) Marshal(v any) ([]byte, error) {
+	f, ok := v.(*frame)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	msg := dynamicpb.NewMessage(c.out)
+	if err := proto.Unmarshal(f.b, msg); err != nil {
+		return nil, err
+	}
+
+	return protojson.Marshal(msg)
+}
+
+func (c *jsonCodec) Unmarshal(data []byte, v any) error {
+	f, ok := v.(*frame)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+	msg := dynamicpb.NewMessage(c.in)
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, msg); err != nil {
+		return err
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	f.b = b
+
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package rpcproxy serves procedures of the upstream gRPC services to
+// browsers over the Connect and gRPC-Web protocols, and to other clients
+// over gRPC, forwarding the calls to the upstream server. Messages are
+// passed on in the protobuf binary format, decoded only to convert JSON.
+package rpcproxy
+
+import (
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"net/http"
+	"slices"
+	"strings"
+	"workmap/gateway/internal/requestid"
+)
+
+// BrowserSafe are the procedures that may be served. Each needs a secret of
+// the user, a password or a refresh token. The others take the email they
+// act for on trust, like GetMfa returning the TOTP secret or PasskeyLogin
+// signing in without an assertion, and are only for the gateway's handlers.
+var BrowserSafe = []string{
+	"/auth.AuthService/Register",
+	"/auth.AuthService/Login",
+	"/auth.AuthService/Logout",
+	"/auth.AuthService/RefreshToken",
+}
+
+type Config struct {
+	// Conn is the connection to the upstream server.
+	Conn grpc.ClientConnInterface
+	// Procedures are served by path, like "/auth.AuthService/Login", and
+	// must be BrowserSafe. Their descriptors are those the generated code
+	// registers in Files, the global registry if nil. Only unary procedures
+	// are supported.
+	Procedures []string
+	Files      *protoregistry.Files
+}
+
+// Procedure is the handler of a procedure, to serve at POST Path.
+type Procedure struct {
+	Path    string
+	Handler http.Handler
+}
+
+type Proxy struct {
+	conn       grpc.ClientConnInterface
+	procedures []Procedure
+}
+
+func New(cfg *Config) (*Proxy, error) {
+	files := cfg.Files
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+
+	p := &Proxy{conn: cfg.Conn}
+	for _, path := range cfg.Procedures {
+		method, err := findMethod(files, path)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(BrowserSafe, path) {
+			return nil, fmt.Errorf("procedure %q is not browser-safe", path)
+		}
+		handler := connect.NewUnaryHandler(path, p.call(path),
+			connect.WithCodec(binaryCodec{}),
+			connect.WithCodec(&jsonCodec{in: method.Input(), out: method.Output()}),
+		)
+		p.procedures = append(p.procedures, Procedure{Path: path, Handler: handler})
+	}
+
+	return p, nil
+}
+
+// Procedures returns the procedures served, in the order configured.
+func (p *Proxy) Procedures() []Procedure {
+	return p.procedures
+}
+
+// findMethod finds the unary method of path, like "/auth.AuthService/Login".
+func findMethod(files *protoregistry.Files, path string) (protoreflect.MethodDescriptor, error) {
+	service, name, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !ok || !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("procedure %q is not a /package.Service/Method path", path)
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("procedure %q: %w", path, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("procedure %q: %s is not a service", path, service)
+	}
+	method := sd.Methods().ByName(protoreflect.Name(name))
+	if method == nil {
+		return nil, fmt.Errorf("procedure %q: %s has no method %s", path, service, name)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("procedure %q: streaming is not supported", path)
+	}
+
+	return method, nil
+}
+
+// call forwards the calls to path upstream with the Authorization header
+// and the request ID, and passes back the metadata of the response.
+func (p *Proxy) call(path string) func(context.Context, *connect.Request[frame]) (*connect.Response[frame], error) {
+	return func(ctx context.Context, req *connect.Request[frame]) (*connect.Response[frame], error) {
+		md := metadata.MD{}
+		if v := req.Header().Values("Authorization"); len(v) > 0 {
+			md.Set("authorization", v...)
+		}
+		if id := requestid.FromContext(ctx); id != "" {
+			md.Set(strings.ToLower(requestid.Header), id)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+
+		var header, trailer metadata.MD
+		out := &frame{}
+		err := p.conn.Invoke(ctx, path, req.Msg, out,
+			grpc.ForceCodec(binaryCodec{}), grpc.Header(&header), grpc.Trailer(&trailer))
+		if err != nil {
+			return nil, connectError(err)
+		}
+
+		res := connect.NewResponse(out)
+		copyMetadata(res.Header(), header)
+		copyMetadata(res.Trailer(), trailer)
+
+		return res, nil
+	}
+}
+
+// connectError keeps the code, message and details of the upstream error.
+func connectError(err error) error {
+	st := status.Convert(err)
+	ce := connect.NewError(connect.Code(st.Code()), errors.New(st.Message()))
+	for _, d := range st.Proto().GetDetails() {
+		if detail, err := connect.NewErrorDetail(d); err == nil {
+			ce.AddDetail(detail)
+		}
+	}
+
+	return ce
+}
+
+// copyMetadata copies the application metadata of md to h, leaving out the
+// keys reserved by gRPC.
+func copyMetadata(h http.Header, md metadata.MD) {
+	for k, vs := range md {
+		if strings.HasPrefix(k, "grpc-") || strings.HasPrefix(k, ":") || k == "content-type" {
+			continue
+		}
+		for _, v := range vs {
+			if strings.HasSuffix(k, "-bin") {
+				v = connect.EncodeBinaryHeader([]byte(v))
+			}
+			h.Add(k, v)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package rpcproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	pb "workmap/gateway/internal/gapi/proto_gen"
+	"workmap/gateway/internal/requestid"
+)
+
+type mockAuthServiceServer struct {
+	pb.UnimplementedAuthServiceServer
+	md metadata.MD
+}
+
+func (s *mockAuthServiceServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginReply, error) {
+	s.md, _ = metadata.FromIncomingContext(ctx)
+	if req.Password != "password123" {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("x-upstream", "auth"))
+
+	return &pb.LoginReply{RefreshToken: "refresh", AccessToken: "access"}, nil
+}
+
+// startProxy serves Login through a proxy to a mock Auth service.
+func startProxy(t *testing.T) (*httptest.Server, *mockAuthServiceServer) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	mock := &mockAuthServiceServer{}
+	server := grpc.NewServer()
+	pb.RegisterAuthServiceServer(server, mock)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	proxy, err := New(&Config{Conn: conn, Procedures: []string{"/auth.AuthService/Login"}})
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	for _, p := range proxy.Procedures() {
+		mux.Handle("POST "+p.Path, p.Handler)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), "r1")))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, mock
+}
+
+func post(t *testing.T, srv *httptest.Server, contentType string, body []byte) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/auth.AuthService/Login", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestProxy_Connect(t *testing.T) {
+	srv, mock := startProxy(t)
+
+	tests := []struct {
+		name         string
+		contentType  string
+		body         []byte
+		expectedCode int
+		expected     *pb.LoginReply
+		expectedBody string
+	}{
+		{
+			name:         "json",
+			contentType:  "application/json",
+			body:         []byte(`{"email":"test@example.com","password":"password123"}`),
+			expectedCode: http.StatusOK,
+			expectedBody: `{"refreshToken":"refresh","accessToken":"access"}`,
+		},
+		{
+			name:         "binary",
+			contentType:  "application/proto",
+			body:         mustMarshal(t, &pb.LoginRequest{Email: "test@example.com", Password: "password123"}),
+			expectedCode: http.StatusOK,
+			expected:     &pb.LoginReply{RefreshToken: "refresh", AccessToken: "access"},
+		},
+		{
+			name:         "upstream error",
+			contentType:  "application/json",
+			body:         []byte(`{"email":"test@example.com","password":"wrong"}`),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"code":"unauthenticated","message":"invalid credentials"}`,
+		},
+		{
+			name:         "invalid json",
+			contentType:  "application/json",
+			body:         []byte(`{"email":1}`),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := post(t, srv, tt.contentType, tt.body)
+			b, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedCode, resp.StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, string(b))
+			}
+			if tt.expected != nil {
+				got := &pb.LoginReply{}
+				require.NoError(t, proto.Unmarshal(b, got))
+				assert.True(t, proto.Equal(tt.expected, got))
+			}
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, "auth", resp.Header.Get("X-Upstream"))
+				assert.Equal(t, []string{"Bearer token"}, mock.md.Get("authorization"))
+				assert.Equal(t, []string{"r1"}, mock.md.Get("x-request-id"))
+			}
+		})
+	}
+}
+
+func TestProxy_GRPCWeb(t *testing.T) {
+	srv, _ := startProxy(t)
+
+	msg := mustMarshal(t, &pb.LoginRequest{Email: "test@example.com", Password: "password123"})
+	body := append([]byte{0}, binary.BigEndian.AppendUint32(nil, uint32(len(msg)))...)
+	resp := post(t, srv, "application/grpc-web+proto", append(body, msg...))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got pb.LoginReply
+	var trailer string
+	for {
+		head := make([]byte, 5)
+		_, err := io.ReadFull(resp.Body, head)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data := make([]byte, binary.BigEndian.Uint32(head[1:]))
+		_, err = io.ReadFull(resp.Body, data)
+		require.NoError(t, err)
+		if head[0]&0x80 != 0 {
+			trailer = string(data)
+		} else {
+			require.NoError(t, proto.Unmarshal(data, &got))
+		}
+	}
+
+	assert.Equal(t, "access", got.AccessToken)
+	assert.Contains(t, strings.ToLower(trailer), "grpc-status: 0")
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name          string
+		procedure     string
+		expectedError string
+	}{
+		{name: "not a path", procedure: "auth.AuthService.Login", expectedError: "is not a /package.Service/Method path"},
+		{name: "unknown service", procedure: "/auth.Unknown/Login", expectedError: "not found"},
+		{name: "unknown method", procedure: "/auth.AuthService/Unknown", expectedError: "has no method Unknown"},
+		{name: "not a service", procedure: "/auth.LoginRequest/Login", expectedError: "is not a service"},
+		{name: "not browser-safe", procedure: "/auth.AuthService/GetMfa", expectedError: `procedure "/auth.AuthService/GetMfa" is not browser-safe`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&Config{Procedures: []string{tt.procedure}})
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, m proto.Message) []byte {
+	b, err := proto.Marshal(m)
+	require.NoError(t, err)
+
+	return b
+}
@@ -2,9 +2,15 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
 	"net/http"
+	"sync/atomic"
+	"time"
 	"workmap/gateway/internal/routes"
 )
 
@@ -12,43 +18,105 @@ type Config struct {
 	Port   string
 	Logger *zap.Logger
 	Router *routes.Router
+	// The timeouts and MaxHeaderBytes are those of http.Server, zero for
+	// none and for its default respectively.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// H2C serves HTTP/2 without TLS, to clients and proxies connecting with
+	// prior knowledge, along HTTP/1.1. It must only be reachable from a
+	// trusted hop: an HTTP/1.1 upgrade to h2c passing through a proxy that
+	// does not understand it lets the client smuggle requests past the
+	// proxy's rules.
+	H2C bool
 }
 
 type Server struct {
 	httpServer *http.Server
 	logger     *zap.Logger
+	handler    atomic.Pointer[http.Handler]
+	errc       chan error
 }
 
 func New(cfg *Config) *Server {
-	mux := http.NewServeMux()
-
-	cfg.Router.RegisterRoutes(mux)
+	s := &Server{
+		logger: cfg.Logger,
+		errc:   make(chan error, 1),
+	}
+	s.SetRouter(cfg.Router)
 
 	addr := fmt.Sprintf(":%s", cfg.Port)
-	srvr := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
-
-	return &Server{
-		httpServer: srvr,
-		logger:     cfg.Logger,
+	if cfg.H2C {
+		// ConfigureServer lets Shutdown send GOAWAY on the HTTP/2
+		// connections, which h2c takes over from the server.
+		h2s := &http2.Server{IdleTimeout: cfg.IdleTimeout}
+		if err := http2.ConfigureServer(s.httpServer, h2s); err != nil {
+			s.logger.Error("failed to configure http/2, serving http/1.1 only", zap.Error(err))
+		} else {
+			s.httpServer.Handler = h2c.NewHandler(s, h2s)
+		}
 	}
+
+	return s
+}
+
+// SetRouter replaces the route table. Requests already dispatched finish on
+// the previous one.
+func (s *Server) SetRouter(r *routes.Router) {
+	h := r.Handler()
+
+	s.handler.Store(&h)
 }
 
-func (s *Server) Run() {
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.handler.Load()).ServeHTTP(w, r)
+}
+
+// Start listens on the port and serves in the background. Errors of the
+// listener after that are sent to Err.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil {
-			s.logger.Fatal("failed to listen server", zap.String("address", s.httpServer.Addr), zap.Error(err))
+		if err := s.httpServer.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			s.errc <- err
 		}
 	}()
 	s.logger.Info("server is ready to handle requests", zap.String("address", s.httpServer.Addr))
+
+	return nil
+}
+
+// Err receives the error the server stopped serving with, if not stopped by
+// Stop.
+func (s *Server) Err() <-chan error {
+	return s.errc
 }
 
-func (s *Server) ShutDown(ctx context.Context) {
+// Stop stops accepting connections and waits for the requests in flight
+// until ctx is done, then closes their connections.
+func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Debug("Shutting down gracefully, press Ctrl+C again to force")
 	if err := s.httpServer.Shutdown(ctx); err != nil {
-		s.logger.Fatal("Server forced to shutdown: %v", zap.Error(err))
+		s.logger.Warn("requests still in flight at the shutdown timeout, closing their connections", zap.Error(err))
+		s.httpServer.Close()
+		return err
 	}
 	s.logger.Debug("Server stopped")
+
+	return nil
 }
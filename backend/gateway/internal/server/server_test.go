@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+	"workmap/gateway/internal/middlewares"
+	"workmap/gateway/internal/routes"
+)
+
+// newServer returns a server on a free port serving h.
+func newServer(t *testing.T, h http.HandlerFunc) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	s := &Server{
+		httpServer: &http.Server{Addr: addr, Handler: h},
+		logger:     zap.NewNop(),
+		errc:       make(chan error, 1),
+	}
+
+	return s
+}
+
+func TestServer(t *testing.T) {
+	t.Run("waits for the requests in flight", func(t *testing.T) {
+		release := make(chan struct{})
+		s := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.WriteHeader(http.StatusNoContent)
+		})
+		require.NoError(t, s.Start(context.Background()))
+
+		res := make(chan int, 1)
+		go func() {
+			resp, err := http.Get("http://" + s.httpServer.Addr)
+			if err != nil {
+				res <- 0
+				return
+			}
+			resp.Body.Close()
+			res <- resp.StatusCode
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		stopped := make(chan error, 1)
+		go func() { stopped <- s.Stop(context.Background()) }()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+
+		assert.NoError(t, <-stopped)
+		assert.Equal(t, http.StatusNoContent, <-res)
+		assert.Empty(t, s.Err(), "a stopped server is not a failure")
+	})
+
+	t.Run("closes the connections at the timeout", func(t *testing.T) {
+		s := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		})
+		require.NoError(t, s.Start(context.Background()))
+
+		go func() {
+			resp, err := http.Get("http://" + s.httpServer.Addr)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, s.Stop(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("serves http/2 without tls", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		_, port, _ := net.SplitHostPort(ln.Addr().String())
+		require.NoError(t, ln.Close())
+
+		router := routes.New(&routes.Config{Logger: zap.NewNop(), Middleware: middlewares.New(&middlewares.Config{Logger: zap.NewNop()})})
+		s := New(&Config{Port: port, Logger: zap.NewNop(), Router: router, H2C: true})
+		require.NoError(t, s.Start(context.Background()))
+		defer s.Stop(context.Background())
+
+		client := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}}
+		resp, err := client.Get("http://127.0.0.1:" + port + "/unknown")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, 2, resp.ProtoMajor)
+	})
+
+	t.Run("fails to start on a taken port", func(t *testing.T) {
+		s := newServer(t, http.NotFound)
+		require.NoError(t, s.Start(context.Background()))
+		defer s.Stop(context.Background())
+
+		other := &Server{
+			httpServer: &http.Server{Addr: s.httpServer.Addr},
+			logger:     zap.NewNop(),
+			errc:       make(chan error, 1),
+		}
+		assert.Error(t, other.Start(context.Background()))
+	})
+}
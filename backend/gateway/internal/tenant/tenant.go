@@ -0,0 +1,93 @@
+// Package tenant carries the tenant a request belongs to, which scopes the
+// access tokens and sessions it can see in the token store.
+package tenant
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"workmap/gateway/internal/pkg/token"
+)
+
+// validName keeps tenant names usable in Redis keys and pub/sub messages.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Valid reports whether name can be used as a tenant.
+func Valid(name string) bool {
+	return validName.MatchString(name)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// FromContext returns the tenant of ctx, "" for the default one, and whether
+// one was resolved.
+func FromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(contextKey{}).(string)
+
+	return name, ok
+}
+
+type Config struct {
+	// Hosts maps request hosts, without the port, to tenants.
+	Hosts map[string]string
+	// Claim is the access token claim naming the tenant, none if empty.
+	Claim string
+}
+
+// Resolver finds the tenant of a request by its host first, then by the
+// claim of its access token. A nil Resolver finds none.
+type Resolver struct {
+	hosts map[string]string
+	claim string
+}
+
+func NewResolver(cfg *Config) *Resolver {
+	return &Resolver{
+		hosts: cfg.Hosts,
+		claim: cfg.Claim,
+	}
+}
+
+// Resolve returns the tenant of r and whether there is one. The access token
+// claim is not verified here: a token is only found in the token store under
+// the tenant it was saved for.
+func (t *Resolver) Resolve(r *http.Request) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if name, ok := t.hosts[strings.ToLower(host)]; ok {
+		return name, true
+	}
+
+	at := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if at == "" {
+		return "", false
+	}
+
+	return t.FromToken(at)
+}
+
+// FromToken returns the tenant claimed by accessToken, if it is valid.
+func (t *Resolver) FromToken(accessToken string) (string, bool) {
+	if t == nil || t.claim == "" {
+		return "", false
+	}
+
+	name, ok := token.StringClaim(accessToken, t.claim)
+	if !ok || !Valid(name) {
+		return "", false
+	}
+
+	return name, true
+}
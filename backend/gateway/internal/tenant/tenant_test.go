@@ -0,0 +1,85 @@
+package tenant
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// claimToken returns an unsigned access token with the given payload.
+func claimToken(payload string) string {
+	enc := base64.RawURLEncoding
+
+	return enc.EncodeToString([]byte(`{"alg":"HS512"}`)) + "." + enc.EncodeToString([]byte(payload)) + ".c2ln"
+}
+
+func TestResolve(t *testing.T) {
+	resolver := NewResolver(&Config{
+		Hosts: map[string]string{"acme.work-map.test": "acme"},
+		Claim: "tenant",
+	})
+
+	tests := []struct {
+		name           string
+		resolver       *Resolver
+		host           string
+		token          string
+		expectedTenant string
+		expectedOK     bool
+	}{
+		{
+			name:           "by host",
+			resolver:       resolver,
+			host:           "ACME.work-map.test:8080",
+			token:          claimToken(`{"tenant":"globex"}`),
+			expectedTenant: "acme",
+			expectedOK:     true,
+		},
+		{
+			name:           "by claim",
+			resolver:       resolver,
+			host:           "work-map.test",
+			token:          claimToken(`{"tenant":"globex"}`),
+			expectedTenant: "globex",
+			expectedOK:     true,
+		},
+		{
+			name:     "invalid claim",
+			resolver: resolver,
+			host:     "work-map.test",
+			token:    claimToken(`{"tenant":"Globex:admin"}`),
+		},
+		{
+			name:     "no claim",
+			resolver: resolver,
+			host:     "work-map.test",
+			token:    claimToken(`{"email":"user@email.com"}`),
+		},
+		{
+			name:     "no token",
+			resolver: resolver,
+			host:     "work-map.test",
+		},
+		{
+			name:  "no resolver",
+			host:  "acme.work-map.test",
+			token: claimToken(`{"tenant":"globex"}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			req.Host = tt.host
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			name, ok := tt.resolver.Resolve(req)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedTenant, name)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package tokencache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_token_cache_lookups_total",
+		Help: "Access token lookups by result: hit, negative_hit or miss.",
+	}, []string{"result"})
+
+	evictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_token_cache_evictions_total",
+		Help: "Cached lookups evicted because the cache was full.",
+	})
+
+	invalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gateway_token_cache_invalidations_total",
+		Help: "Token invalidations received from the gateways.",
+	})
+
+	entries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gateway_token_cache_entries",
+		Help: "Cached access token lookups.",
+	})
+)
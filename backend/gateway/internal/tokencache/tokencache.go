@@ -0,0 +1,325 @@
+// Package tokencache keeps the recent lookups of a token store in memory, so
+// that most authenticated requests skip the round-trip to the store.
+package tokencache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+	"workmap/gateway/internal/redis"
+	"workmap/gateway/internal/tenant"
+)
+
+const (
+	defaultSize        = 10000
+	defaultMaxAge      = 30 * time.Second
+	defaultNegativeAge = 5 * time.Second
+)
+
+type Config struct {
+	Store  store.TokenStore
+	Logger *zap.Logger
+	// Invalidator spreads the deletions to the caches of the other gateways,
+	// nil for a single gateway.
+	Invalidator store.TokenInvalidator
+	// Size bounds the number of cached lookups, 10000 if zero.
+	Size int
+	// MaxAge bounds how long a live token is cached, 30 seconds if zero. It is
+	// also the longest a deletion missed by this gateway goes unnoticed.
+	MaxAge time.Duration
+	// NegativeAge is how long an unknown token is cached, 5 seconds if zero.
+	NegativeAge time.Duration
+}
+
+// Cache is a TokenStore caching the lookups of the store it wraps. Saves and
+// deletions go to the store and drop the cached lookups of their tokens.
+type Cache struct {
+	store       store.TokenStore
+	logger      *zap.Logger
+	invalidator store.TokenInvalidator
+	size        int
+	maxAge      time.Duration
+	negativeAge time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	// gen is incremented by every invalidation, so that a lookup racing with
+	// one is not cached.
+	gen uint64
+}
+
+type entry struct {
+	key     string
+	session store.Session
+	found   bool
+	expires time.Time
+}
+
+func New(cfg *Config) *Cache {
+	c := &Cache{
+		store:       cfg.Store,
+		logger:      cfg.Logger,
+		invalidator: cfg.Invalidator,
+		size:        cfg.Size,
+		maxAge:      cfg.MaxAge,
+		negativeAge: cfg.NegativeAge,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element),
+		lru:         list.New(),
+	}
+	if c.size <= 0 {
+		c.size = defaultSize
+	}
+	if c.maxAge <= 0 {
+		c.maxAge = defaultMaxAge
+	}
+	if c.negativeAge <= 0 {
+		c.negativeAge = defaultNegativeAge
+	}
+
+	return c
+}
+
+// Subscribe listens to the invalidations published by the other gateways
+// until ctx is done.
+func (c *Cache) Subscribe(ctx context.Context) error {
+	if c.invalidator == nil {
+		return nil
+	}
+
+	return c.invalidator.SubscribeTokenInvalidations(ctx, func(keys []string) {
+		c.invalidate(keys)
+		invalidations.Add(float64(len(keys)))
+	})
+}
+
+func (c *Cache) GetAccessToken(ctx context.Context, accessToken string) (store.Session, error) {
+	key := cacheKey(ctx, store.SessionID(accessToken))
+	if e, ok := c.get(key); ok {
+		if !e.found {
+			return store.Session{}, store.ErrNotFound
+		}
+
+		return e.session, nil
+	}
+
+	gen := c.generation()
+	s, err := c.store.GetAccessToken(ctx, accessToken)
+	if errors.Is(err, store.ErrNotFound) {
+		c.add(gen, &entry{key: key, expires: c.now().Add(c.negativeAge)})
+	}
+	if err != nil {
+		return store.Session{}, err
+	}
+	c.add(gen, c.positive(key, s))
+
+	return s, nil
+}
+
+func (c *Cache) GetAccessTokens(ctx context.Context, accessTokens []string) (map[string]store.Session, error) {
+	sessions := make(map[string]store.Session)
+	var missed []string
+	for _, at := range accessTokens {
+		e, ok := c.get(cacheKey(ctx, store.SessionID(at)))
+		switch {
+		case !ok:
+			missed = append(missed, at)
+		case e.found:
+			sessions[at] = e.session
+		}
+	}
+	if len(missed) == 0 {
+		return sessions, nil
+	}
+
+	gen := c.generation()
+	found, err := c.store.GetAccessTokens(ctx, missed)
+	if err != nil {
+		return nil, err
+	}
+	for _, at := range missed {
+		s, ok := found[at]
+		if !ok {
+			c.add(gen, &entry{key: cacheKey(ctx, store.SessionID(at)), expires: c.now().Add(c.negativeAge)})
+			continue
+		}
+		c.add(gen, c.positive(cacheKey(ctx, store.SessionID(at)), s))
+		sessions[at] = s
+	}
+
+	return sessions, nil
+}
+
+// SaveAccessToken drops the negative lookup of accessToken, if any, on this
+// gateway only: the token was just issued, the other gateways cannot have
+// looked it up yet.
+func (c *Cache) SaveAccessToken(ctx context.Context, accessToken string, s store.Session) error {
+	if err := c.store.SaveAccessToken(ctx, accessToken, s); err != nil {
+		return err
+	}
+	c.invalidate([]string{cacheKey(ctx, store.SessionID(accessToken))})
+
+	return nil
+}
+
+func (c *Cache) DeleteAccessToken(ctx context.Context, accessToken string) error {
+	return c.DeleteAccessTokens(ctx, []string{accessToken})
+}
+
+// DeleteAccessTokens deletes the tokens from the store and then from the
+// caches of every gateway.
+func (c *Cache) DeleteAccessTokens(ctx context.Context, accessTokens []string) error {
+	if err := c.store.DeleteAccessTokens(ctx, accessTokens); err != nil {
+		return err
+	}
+
+	keys := make([]string, len(accessTokens))
+	for i, at := range accessTokens {
+		keys[i] = cacheKey(ctx, store.SessionID(at))
+	}
+	c.forget(ctx, keys)
+
+	return nil
+}
+
+// forget drops keys from the caches of every gateway. A failed publication is
+// only logged, the other gateways forget the keys within MaxAge.
+func (c *Cache) forget(ctx context.Context, keys []string) {
+	c.invalidate(keys)
+
+	if c.invalidator != nil {
+		if err := c.invalidator.PublishTokenInvalidation(ctx, keys); err != nil {
+			c.logger.Warn("failed to publish token invalidation", zap.Error(err))
+		}
+	}
+}
+
+// ListSessions lists the sessions of email in the store, which must be a
+// store.SessionStore.
+func (c *Cache) ListSessions(ctx context.Context, email string) ([]store.Session, error) {
+	ss, ok := c.store.(store.SessionStore)
+	if !ok {
+		return nil, store.ErrUnsupported
+	}
+
+	return ss.ListSessions(ctx, email)
+}
+
+// RevokeSession revokes the session in the store, which must be a
+// store.SessionStore, then in the caches of every gateway.
+func (c *Cache) RevokeSession(ctx context.Context, email, id string) (bool, error) {
+	ss, ok := c.store.(store.SessionStore)
+	if !ok {
+		return false, store.ErrUnsupported
+	}
+
+	revoked, err := ss.RevokeSession(ctx, email, id)
+	if err != nil || !revoked {
+		return revoked, err
+	}
+	c.forget(ctx, []string{cacheKey(ctx, id)})
+
+	return true, nil
+}
+
+// positive caches s until MaxAge or its expiry, whichever comes first.
+func (c *Cache) positive(key string, s store.Session) *entry {
+	expires := c.now().Add(c.maxAge)
+	if s.ExpiresAt.Before(expires) {
+		expires = s.ExpiresAt
+	}
+
+	return &entry{key: key, session: s, found: true, expires: expires}
+}
+
+func (c *Cache) get(key string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		lookups.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		lookups.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	if e.found {
+		lookups.WithLabelValues("hit").Inc()
+	} else {
+		lookups.WithLabelValues("negative_hit").Inc()
+	}
+
+	return e, true
+}
+
+func (c *Cache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// add caches e unless an invalidation happened since gen, evicting the least
+// recently used lookup when full.
+func (c *Cache) add(gen uint64, e *entry) {
+	if !c.now().Before(e.expires) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		evictions.Inc()
+	}
+	entries.Set(float64(c.lru.Len()))
+}
+
+func (c *Cache) invalidate(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, key := range keys {
+		if el, ok := c.entries[key]; ok {
+			c.remove(el)
+		}
+	}
+	entries.Set(float64(c.lru.Len()))
+}
+
+// remove must be called with mu held.
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
+
+// cacheKey scopes the session ID to the tenant of ctx. It is also what is
+// published to the other gateways, so that tokens never go through the
+// pub/sub channel.
+func cacheKey(ctx context.Context, id string) string {
+	t, _ := tenant.FromContext(ctx)
+
+	return t + ":" + id
+}
@@ -0,0 +1,277 @@
+package tokencache
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net"
+	"sync"
+	"testing"
+	"time"
+	"workmap/gateway/internal/memstore"
+	store "workmap/gateway/internal/redis"
+	"workmap/gateway/internal/redis/storetest"
+	"workmap/gateway/internal/tenant"
+)
+
+// countingStore counts the lookups reaching the store behind the cache.
+type countingStore struct {
+	store.TokenStore
+
+	mu   sync.Mutex
+	gets int
+	err  error
+}
+
+func (s *countingStore) GetAccessToken(ctx context.Context, accessToken string) (store.Session, error) {
+	s.mu.Lock()
+	s.gets++
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return store.Session{}, err
+	}
+
+	return s.TokenStore.GetAccessToken(ctx, accessToken)
+}
+
+func (s *countingStore) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.gets
+}
+
+func newCountingStore(t *testing.T) *countingStore {
+	m := memstore.New(&memstore.Config{})
+	t.Cleanup(func() { m.Close() })
+
+	return &countingStore{TokenStore: store.NewTokenStore(m, store.TokenKeyConfig{})}
+}
+
+func TestConformance(t *testing.T) {
+	storetest.TestTokenStoreLayer(t, func(t *testing.T) (store.TokenStore, func(time.Duration)) {
+		s := miniredis.RunT(t)
+		host, port, err := net.SplitHostPort(s.Addr())
+		require.NoError(t, err)
+		r, err := store.NewRedis(&store.RedisConfig{Host: host, Port: port})
+		require.NoError(t, err)
+
+		c := New(&Config{Store: store.NewTokenStore(&r, store.TokenKeyConfig{}), Logger: zap.NewNop(), Invalidator: &r})
+		now := time.Now()
+		var mu sync.Mutex
+		c.now = func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}
+
+		return c, func(d time.Duration) {
+			mu.Lock()
+			now = now.Add(d)
+			mu.Unlock()
+			s.FastForward(d)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("caches live tokens", func(t *testing.T) {
+		s := newCountingStore(t)
+		c := New(&Config{Store: s, Logger: zap.NewNop()})
+		at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+		require.NoError(t, c.SaveAccessToken(ctx, at, session))
+
+		hits := testutil.ToFloat64(lookups.WithLabelValues("hit"))
+		for i := 0; i < 3; i++ {
+			got, err := c.GetAccessToken(ctx, at)
+			require.NoError(t, err)
+			assert.Equal(t, session.Email, got.Email)
+		}
+		assert.Equal(t, 1, s.calls())
+		assert.Equal(t, hits+2, testutil.ToFloat64(lookups.WithLabelValues("hit")))
+	})
+
+	t.Run("caches unknown tokens", func(t *testing.T) {
+		s := newCountingStore(t)
+		c := New(&Config{Store: s, Logger: zap.NewNop()})
+		at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+
+		for i := 0; i < 2; i++ {
+			_, err := c.GetAccessToken(ctx, at)
+			assert.ErrorIs(t, err, store.ErrNotFound)
+		}
+		assert.Equal(t, 1, s.calls())
+
+		require.NoError(t, c.SaveAccessToken(ctx, at, session))
+		_, err := c.GetAccessToken(ctx, at)
+		assert.NoError(t, err, "saving drops the negative lookup")
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		s := newCountingStore(t)
+		c := New(&Config{Store: s, Logger: zap.NewNop(), MaxAge: time.Minute, NegativeAge: time.Second})
+		now := time.Now()
+		c.now = func() time.Time { return now }
+
+		short, shortSession := storetest.Session(t, "short@email.com", now.Add(10*time.Second))
+		long, longSession := storetest.Session(t, "long@email.com", now.Add(time.Hour))
+		require.NoError(t, c.SaveAccessToken(ctx, short, shortSession))
+		require.NoError(t, c.SaveAccessToken(ctx, long, longSession))
+		_, err := c.GetAccessToken(ctx, short)
+		require.NoError(t, err)
+		_, err = c.GetAccessToken(ctx, long)
+		require.NoError(t, err)
+
+		now = now.Add(20 * time.Second)
+		_, err = c.GetAccessToken(ctx, long)
+		require.NoError(t, err)
+		assert.Equal(t, 2, s.calls(), "cached within the max age")
+		_, _ = c.GetAccessToken(ctx, short)
+		assert.Equal(t, 3, s.calls(), "not cached past the token expiry")
+
+		now = now.Add(time.Minute)
+		_, err = c.GetAccessToken(ctx, long)
+		require.NoError(t, err)
+		assert.Equal(t, 4, s.calls(), "not cached past the max age")
+	})
+
+	t.Run("is bounded", func(t *testing.T) {
+		s := newCountingStore(t)
+		c := New(&Config{Store: s, Logger: zap.NewNop(), Size: 2})
+		var tokens []string
+		for _, email := range []string{"a@email.com", "b@email.com", "c@email.com"} {
+			at, session := storetest.Session(t, email, time.Now().Add(time.Hour))
+			require.NoError(t, c.SaveAccessToken(ctx, at, session))
+			_, err := c.GetAccessToken(ctx, at)
+			require.NoError(t, err)
+			tokens = append(tokens, at)
+		}
+
+		assert.Equal(t, 2, c.lru.Len())
+		_, err := c.GetAccessToken(ctx, tokens[0])
+		require.NoError(t, err)
+		assert.Equal(t, 4, s.calls(), "the least recently used lookup was evicted")
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		s := newCountingStore(t)
+		s.err = errors.New("connection refused")
+		c := New(&Config{Store: s, Logger: zap.NewNop()})
+
+		for i := 0; i < 2; i++ {
+			_, err := c.GetAccessToken(ctx, "token")
+			assert.EqualError(t, err, "connection refused")
+		}
+		assert.Equal(t, 2, s.calls())
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		s := newCountingStore(t)
+		c := New(&Config{Store: s, Logger: zap.NewNop()})
+		at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+		unknown := storetest.Token("unknown@email.com", time.Now().Add(time.Hour))
+		require.NoError(t, c.SaveAccessToken(ctx, at, session))
+
+		got, err := c.GetAccessTokens(ctx, []string{at, unknown})
+		require.NoError(t, err)
+		assert.Len(t, got, 1)
+
+		_, err = c.GetAccessToken(ctx, at)
+		require.NoError(t, err)
+		_, err = c.GetAccessToken(ctx, unknown)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		assert.Equal(t, 0, s.calls(), "both lookups were cached by the batch")
+	})
+
+	t.Run("is scoped to the tenant", func(t *testing.T) {
+		s := newCountingStore(t)
+		c := New(&Config{Store: s, Logger: zap.NewNop()})
+		at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+		require.NoError(t, c.SaveAccessToken(ctx, at, session))
+
+		_, err := c.GetAccessToken(ctx, at)
+		require.NoError(t, err)
+		_, _ = c.GetAccessToken(tenant.NewContext(ctx, "acme"), at)
+		assert.Equal(t, 2, s.calls(), "the lookup of the default tenant is not reused")
+	})
+
+	t.Run("needs a session store", func(t *testing.T) {
+		c := New(&Config{Store: newCountingStore(t), Logger: zap.NewNop()})
+
+		_, err := c.ListSessions(ctx, "user@email.com")
+		assert.ErrorIs(t, err, store.ErrUnsupported)
+		_, err = c.RevokeSession(ctx, "user@email.com", "id")
+		assert.ErrorIs(t, err, store.ErrUnsupported)
+	})
+}
+
+func TestCache_InvalidatesOtherGateways(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mr := miniredis.RunT(t)
+	host, port, err := net.SplitHostPort(mr.Addr())
+	require.NoError(t, err)
+	r, err := store.NewRedis(&store.RedisConfig{Host: host, Port: port})
+	require.NoError(t, err)
+
+	s := newCountingStore(t)
+	a := New(&Config{Store: s, Logger: zap.NewNop(), Invalidator: &r})
+	b := New(&Config{Store: s, Logger: zap.NewNop(), Invalidator: &r})
+	require.NoError(t, a.Subscribe(ctx))
+	require.NoError(t, b.Subscribe(ctx))
+
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, a.SaveAccessToken(ctx, at, session))
+	_, err = b.GetAccessToken(ctx, at)
+	require.NoError(t, err)
+
+	require.NoError(t, a.DeleteAccessToken(ctx, at))
+
+	assert.Eventually(t, func() bool {
+		_, err := b.GetAccessToken(ctx, at)
+		return errors.Is(err, store.ErrNotFound)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCache_RevokeSession(t *testing.T) {
+	ctx, cancel := context.WithCancel(tenant.NewContext(context.Background(), "acme"))
+	defer cancel()
+
+	mr := miniredis.RunT(t)
+	host, port, err := net.SplitHostPort(mr.Addr())
+	require.NoError(t, err)
+	r, err := store.NewRedis(&store.RedisConfig{Host: host, Port: port})
+	require.NoError(t, err)
+
+	tokens := store.NewTokenStore(&r, store.TokenKeyConfig{})
+	a := New(&Config{Store: tokens, Logger: zap.NewNop(), Invalidator: &r})
+	b := New(&Config{Store: tokens, Logger: zap.NewNop(), Invalidator: &r})
+	require.NoError(t, a.Subscribe(ctx))
+	require.NoError(t, b.Subscribe(ctx))
+
+	at, session := storetest.Session(t, "user@email.com", time.Now().Add(time.Hour))
+	require.NoError(t, a.SaveAccessToken(ctx, at, session))
+	_, err = b.GetAccessToken(ctx, at)
+	require.NoError(t, err)
+
+	sessions, err := a.ListSessions(ctx, "user@email.com")
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	revoked, err := a.RevokeSession(ctx, "user@email.com", sessions[0].ID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	assert.Eventually(t, func() bool {
+		_, err := b.GetAccessToken(ctx, at)
+		return errors.Is(err, store.ErrNotFound)
+	}, time.Second, 10*time.Millisecond)
+}
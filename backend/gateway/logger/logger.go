@@ -5,8 +5,13 @@ import (
 	"log"
 )
 
-func New() *zap.Logger {
-	logger, err := zap.NewDevelopment()
+// New returns a development logger whose level follows level, so that it can
+// be changed while the gateway is running.
+func New(level zap.AtomicLevel) *zap.Logger {
+	cfg := zap.NewDevelopmentConfig()
+	cfg.Level = level
+
+	logger, err := cfg.Build()
 	if err != nil {
 		log.Fatal("failed to init zap logger")
 	}
@@ -0,0 +1,239 @@
+// Package identity mints and verifies the identity tokens the gateway sends
+// along the calls it makes upstream for an authenticated caller, so that the
+// upstream services trust the authentication of the gateway instead of each
+// verifying the access tokens again.
+//
+// The tokens are short-lived JWTs signed with Ed25519. Only the gateway holds
+// the private key, the services verify with the public key:
+//
+//	key, err := identity.ParsePublicKey(os.Getenv("IDENTITY_PUBLIC_KEY"))
+//	v := identity.NewVerifier(key)
+//	grpc.NewServer(grpc.UnaryInterceptor(v.UnaryServerInterceptor()))
+//
+// and read the caller with identity.FromContext.
+package identity
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// Header carries the token on HTTP requests.
+	Header = "X-Identity-Token"
+	// MetadataKey carries the token in the metadata of gRPC calls.
+	MetadataKey = "x-identity-token"
+	// Issuer and Audience are the iss and aud claims of every token.
+	Issuer   = "workmap-gateway"
+	Audience = "workmap-internal"
+)
+
+// Kinds of caller, as in the principals of the gateway.
+const (
+	KindUser   = "user"
+	KindAPIKey = "api_key"
+)
+
+// leeway tolerates the clock skew between the gateway and the services.
+const leeway = 5 * time.Second
+
+// Identity is the caller the gateway authenticated.
+type Identity struct {
+	Kind string
+	// UserID is empty for API keys and for the sessions predating it.
+	UserID string
+	Email  string
+	// APIKeyID is the key a KindAPIKey caller authenticated with.
+	APIKeyID string
+	// Scopes limit what an API key may do. Users have every scope.
+	Scopes    []string
+	Tenant    string
+	RequestID string
+	ExpiresAt time.Time
+}
+
+type claims struct {
+	jwt.RegisteredClaims
+	Kind      string `json:"kind"`
+	Email     string `json:"email"`
+	APIKeyID  string `json:"api_key_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Signer mints the tokens, valid for ttl.
+type Signer struct {
+	key ed25519.PrivateKey
+	ttl time.Duration
+}
+
+func NewSigner(key ed25519.PrivateKey, ttl time.Duration) *Signer {
+	return &Signer{key: key, ttl: ttl}
+}
+
+// PublicKey verifies the tokens of the signer.
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign mints a token for id, ignoring its ExpiresAt.
+func (s *Signer) Sign(id *Identity) (string, error) {
+	now := time.Now()
+	c := claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   id.UserID,
+			Audience:  jwt.ClaimStrings{Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.ttl)),
+		},
+		Kind:      id.Kind,
+		Email:     id.Email,
+		APIKeyID:  id.APIKeyID,
+		Scope:     strings.Join(id.Scopes, " "),
+		Tenant:    id.Tenant,
+		RequestID: id.RequestID,
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodEdDSA, c).SignedString(s.key)
+}
+
+// Verifier checks the tokens against the public keys of the gateway, more
+// than one while the signing key rotates.
+type Verifier struct {
+	keys []ed25519.PublicKey
+}
+
+func NewVerifier(keys ...ed25519.PublicKey) *Verifier {
+	return &Verifier{keys: keys}
+}
+
+// Verify checks the signature, issuer, audience and expiry of token and
+// returns the identity it carries.
+func (v *Verifier) Verify(token string) (*Identity, error) {
+	var err error
+	for _, key := range v.keys {
+		c := &claims{}
+		_, err = jwt.ParseWithClaims(token, c, func(*jwt.Token) (interface{}, error) {
+			return key, nil
+		},
+			jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+			jwt.WithIssuer(Issuer),
+			jwt.WithAudience(Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(leeway),
+		)
+		if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id := &Identity{
+			Kind:      c.Kind,
+			UserID:    c.Subject,
+			Email:     c.Email,
+			APIKeyID:  c.APIKeyID,
+			Tenant:    c.Tenant,
+			RequestID: c.RequestID,
+			ExpiresAt: c.ExpiresAt.Time,
+		}
+		if c.Scope != "" {
+			id.Scopes = strings.Split(c.Scope, " ")
+		}
+
+		return id, nil
+	}
+	if err == nil {
+		err = errors.New("no verification key")
+	}
+
+	return nil, err
+}
+
+// Middleware answers 401 to the requests without a valid token in Header,
+// and passes the identity of the others to next in the request context.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := v.Verify(r.Header.Get(Header))
+		if err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// UnaryServerInterceptor refuses the calls without a valid token in
+// MetadataKey, and passes the identity of the others to the handlers in the
+// context.
+func (v *Verifier) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var token string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(MetadataKey); len(values) > 0 {
+				token = values[0]
+			}
+		}
+
+		id, err := v.Verify(token)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid identity token")
+		}
+
+		return handler(NewContext(ctx, id), req)
+	}
+}
+
+// ParsePrivateKey decodes the standard base64 of an Ed25519 seed, 32 bytes.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ed25519 seed is %d bytes, not %d", len(seed), ed25519.SeedSize)
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParsePublicKey decodes the standard base64 of an Ed25519 public key, as
+// logged by the gateway.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public key is %d bytes, not %d", len(key), ed25519.PublicKeySize)
+	}
+
+	return key, nil
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(*Identity)
+
+	return id, ok
+}
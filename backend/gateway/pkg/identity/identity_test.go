@@ -0,0 +1,150 @@
+package identity
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newSigner(t *testing.T, ttl time.Duration) *Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return NewSigner(key, ttl)
+}
+
+func TestVerify(t *testing.T) {
+	signer := newSigner(t, time.Minute)
+	next := newSigner(t, time.Minute)
+	id := &Identity{
+		Kind:      KindAPIKey,
+		UserID:    "42",
+		Email:     "user@email.com",
+		APIKeyID:  "k1",
+		Scopes:    []string{"profile:read", "other"},
+		Tenant:    "acme",
+		RequestID: "req-1",
+	}
+
+	valid, err := signer.Sign(id)
+	require.NoError(t, err)
+	expired, err := newSigner(t, -time.Minute).Sign(id)
+	require.NoError(t, err)
+	rotated, err := next.Sign(id)
+	require.NoError(t, err)
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": Issuer, "aud": Audience, "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte(signer.PublicKey()))
+	require.NoError(t, err)
+	otherAudience, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"iss": Issuer, "aud": "elsewhere", "exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(signer.key)
+	require.NoError(t, err)
+
+	v := NewVerifier(signer.PublicKey(), next.PublicKey())
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr bool
+	}{
+		{name: "valid", token: valid},
+		{name: "signed by the next key", token: rotated},
+		{name: "expired", token: expired, expectedErr: true},
+		{name: "unknown key", token: rotated[:len(rotated)-4] + "AAAA", expectedErr: true},
+		{name: "hmac with the public key", token: hmac, expectedErr: true},
+		{name: "other audience", token: otherAudience, expectedErr: true},
+		{name: "empty", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token)
+
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), got.ExpiresAt, 2*time.Second)
+			got.ExpiresAt = time.Time{}
+			assert.Equal(t, id, got)
+		})
+	}
+
+	t.Run("no key", func(t *testing.T) {
+		_, err := NewVerifier().Verify(valid)
+		assert.Error(t, err)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	signer := newSigner(t, time.Minute)
+	token, err := signer.Sign(&Identity{Kind: KindUser, UserID: "42", Email: "user@email.com"})
+	require.NoError(t, err)
+	h := NewVerifier(signer.PublicKey()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := FromContext(r.Context())
+		require.True(t, ok)
+		w.Write([]byte(id.Email))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, token)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "user@email.com", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	signer := newSigner(t, time.Minute)
+	token, err := signer.Sign(&Identity{Kind: KindUser, UserID: "42", Email: "user@email.com"})
+	require.NoError(t, err)
+	interceptor := NewVerifier(signer.PublicKey()).UnaryServerInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) {
+		id, ok := FromContext(ctx)
+		require.True(t, ok)
+		return id.UserID, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/billing.Billing/Charge"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, token))
+	res, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "42", res)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestParseKeys(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	key, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(seed))
+	require.NoError(t, err)
+	assert.Equal(t, ed25519.NewKeyFromSeed(seed), key)
+
+	public, err := ParsePublicKey(base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+	assert.Equal(t, key.Public(), public)
+
+	_, err = ParsePrivateKey(base64.StdEncoding.EncodeToString(key))
+	assert.Error(t, err, "a private key rather than a seed")
+	_, err = ParsePublicKey("not base64")
+	assert.Error(t, err)
+}